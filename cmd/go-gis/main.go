@@ -5,6 +5,7 @@ import (
 	_ "embed"
 	"go-gis/internal/cmd"
 	"go-gis/internal/config"
+	"os"
 
 	"go-gis/internal/config/consts"
 	xlog "go-gis/internal/util/utillog"
@@ -23,11 +24,15 @@ var (
 
 func main() {
 
-	xlog.Info("build info: [name: %v] [version: %v] [date: %v] [short-commit: %v]", consts.AppName, Version, cmp.Or(Date, date), ShortCommit)
-
 	config.AppVersion, config.AppCommit, config.AppDate, config.ShortCommit = Version, Commit, Date, ShortCommit
 
 	config.ReadFlags()
+
+	if len(config.CmdLine.Args) > 0 {
+		xlog.SetOutput(os.Stderr) // stdout is for subcommand output
+	}
+
+	xlog.Info("build info: [name: %v] [version: %v] [date: %v] [short-commit: %v]", consts.AppName, Version, cmp.Or(Date, date), ShortCommit)
 	//
 	x := cmd.Command{}
 
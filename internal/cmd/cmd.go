@@ -35,9 +35,14 @@ func (x *Command) Stop() {
 
 func (x *Command) Exec() {
 
+	if args := config.CmdLine.Args; len(args) > 0 {
+		x.execSubcommandAndExit(args)
+		return
+	}
+
 	defer xlog.Sync()
 
-	x.AppService = service.MustNewAppServiceProd()
+	x.AppService = service.MustNewAppServiceServer()
 
 	x.WebDriver = echo.New()
 	x.WebDriver.Logger.SetLevel(elog.INFO) // has "file":"cmd.go","line":"85"
@@ -55,6 +60,32 @@ func (x *Command) Exec() {
 
 	time.Sleep(400 * time.Millisecond)
 }
+
+// execSubcommandAndExit run subcommand instead of server, exit code 1 on error
+func (x *Command) execSubcommandAndExit(args []string) {
+
+	offline := isOfflineSubcommand(args)
+
+	switch {
+	case offline: // no app services
+	case isMigrateSubcommand(args):
+		x.AppService = service.MustNewAppServiceWithoutMigration()
+	default:
+		x.AppService = service.MustNewAppServiceProd()
+	}
+
+	err := x.execSubcommand(args)
+
+	if !offline {
+		_ = x.AppService.Repository().Close()
+	}
+
+	if err != nil {
+		xlog.Error("%v", err)
+		os.Exit(1)
+	}
+}
+
 func applyServer(s *http.Server, c *config.AppConfig) {
 
 	s.ReadTimeout = time.Duration(c.HTTPServer.ReadTimeout) * time.Second
@@ -0,0 +1,563 @@
+package cmd
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go-gis/internal/geo/country"
+	"go-gis/internal/geo/geojson"
+	"go-gis/internal/geo/geonames"
+	"go-gis/internal/geo/shp"
+	"go-gis/internal/repository"
+	"go-gis/internal/service"
+	xlog "go-gis/internal/util/utillog"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// subcommand usage
+const usage = `usage:
+  go-gis [flags] import geojson <file|-> --layer <name> [--batch 500] [--rejects <file>]
+  go-gis [flags] import shapefile <file.shp|file.zip> --layer <name> [--srid <epsg>] [--batch 500] [--rejects <file>]
+  go-gis [flags] import postcodes <file.txt|file.zip|-> [--batch 500] [--rejects <file>]
+  go-gis [flags] export geojson --layer <name> [--out <file>]
+  go-gis [flags] boundaries build --countries <file> [--subdivisions <file>] [--tolerance 0.01] --out <file.geojson.gz>
+  go-gis [flags] migrate up [--tenant <name>]
+  go-gis [flags] migrate down [--steps 1] [--tenant <name>]
+  go-gis [flags] migrate status [--tenant <name>]`
+
+// offlineSubcommands run without app services and database
+var offlineSubcommands = map[string]bool{
+	"boundaries build": true,
+}
+
+// isOfflineSubcommand subcommand does not need app services
+func isOfflineSubcommand(args []string) bool {
+	return len(args) >= 2 && offlineSubcommands[args[0]+" "+args[1]]
+}
+
+// isMigrateSubcommand subcommand runs schema migrations itself
+func isMigrateSubcommand(args []string) bool {
+	return len(args) >= 1 && args[0] == "migrate"
+}
+
+// execSubcommand run subcommand like "import geojson file.json --layer x"
+func (x *Command) execSubcommand(args []string) error {
+
+	if len(args) < 2 {
+		return fmt.Errorf("%v", usage)
+	}
+
+	name := args[0] + " " + args[1]
+	args = args[2:]
+
+	switch name {
+	case "import geojson":
+		return x.importGeoJSON(args)
+	case "import shapefile":
+		return x.importShapefile(args)
+	case "import postcodes":
+		return x.importPostcodes(args)
+	case "export geojson":
+		return x.exportGeoJSON(args)
+	case "boundaries build":
+		return x.buildBoundaries(args)
+	case "migrate up":
+		return x.migrateUp(args)
+	case "migrate down":
+		return x.migrateDown(args)
+	case "migrate status":
+		return x.migrateStatus(args)
+	}
+
+	return fmt.Errorf("unknown subcommand: %v\n%v", name, usage)
+}
+
+// parseArgs parse flags mixed with positional args
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+
+	positional := []string{}
+
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func (x *Command) importGeoJSON(args []string) error {
+
+	fs := flag.NewFlagSet("import geojson", flag.ContinueOnError)
+	layer := fs.String("layer", "", "layer name")
+	batch := fs.Int("batch", 500, "features per insert")
+	rejects := fs.String("rejects", "", "file for rejected features, json lines")
+
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(files) != 1 || *layer == "" {
+		return fmt.Errorf("file and --layer are required\n%v", usage)
+	}
+
+	in, closeIn, err := openInput(files[0])
+	if err != nil {
+		return err
+	}
+	defer closeIn()
+
+	opts, closeRejects, err := importOptions(*batch, *rejects)
+	if err != nil {
+		return err
+	}
+	defer closeRejects()
+
+	res, err := x.AppService.Feature().Import(*layer, geojson.NewReader(bufio.NewReader(in)), opts)
+
+	xlog.Info("import done: [total: %v] [imported: %v] [rejected: %v]", res.Total, res.Imported, res.Rejected)
+
+	return err
+}
+
+func (x *Command) importShapefile(args []string) error {
+
+	fs := flag.NewFlagSet("import shapefile", flag.ContinueOnError)
+	layer := fs.String("layer", "", "layer name")
+	srid := fs.Int("srid", 0, "EPSG code of coordinates, default from .prj")
+	batch := fs.Int("batch", 500, "features per insert")
+	rejects := fs.String("rejects", "", "file for rejected features, json lines")
+
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(files) != 1 || *layer == "" {
+		return fmt.Errorf("file and --layer are required\n%v", usage)
+	}
+
+	reader, err := shp.Open(files[0])
+	if err != nil {
+		return err
+	}
+	defer func() { _ = reader.Close() }()
+
+	if *srid == 0 {
+		if *srid, err = reader.CRS(); err != nil {
+			return fmt.Errorf("%v, use --srid", err)
+		}
+	}
+
+	opts, closeRejects, err := importOptions(*batch, *rejects)
+	if err != nil {
+		return err
+	}
+	defer closeRejects()
+
+	opts.SRID = *srid
+
+	xlog.Info("import shapefile: [fields: %v] [srid: %v]", reader.Fields(), opts.SRID)
+
+	res, err := x.AppService.Feature().Import(*layer, reader, opts)
+
+	xlog.Info("import done: [total: %v] [imported: %v] [rejected: %v]", res.Total, res.Imported, res.Rejected)
+
+	return err
+}
+
+func (x *Command) importPostcodes(args []string) error {
+
+	fs := flag.NewFlagSet("import postcodes", flag.ContinueOnError)
+	batch := fs.Int("batch", 500, "rows per insert")
+	rejects := fs.String("rejects", "", "file for rejected rows, json lines")
+
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(files) != 1 {
+		return fmt.Errorf("file is required\n%v", usage)
+	}
+
+	srv := x.AppService.Postcode()
+	if srv == nil {
+		return fmt.Errorf("postcodes are disabled, set postcodes.enabled")
+	}
+
+	var reader *geonames.Reader
+
+	if files[0] == "-" {
+		reader = geonames.NewReader(bufio.NewReader(os.Stdin))
+	} else {
+		if reader, err = geonames.Open(files[0]); err != nil {
+			return err
+		}
+		defer func() { _ = reader.Close() }()
+	}
+
+	opts, closeRejects, err := importOptions(*batch, *rejects)
+	if err != nil {
+		return err
+	}
+	defer closeRejects()
+
+	res, err := srv.Import(reader, opts)
+
+	xlog.Info("import done: [total: %v] [imported: %v] [rejected: %v]", res.Total, res.Imported, res.Rejected)
+
+	return err
+}
+
+func (x *Command) exportGeoJSON(args []string) error {
+
+	fs := flag.NewFlagSet("export geojson", flag.ContinueOnError)
+	layer := fs.String("layer", "", "layer name")
+	out := fs.String("out", "", "output file, default stdout")
+
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	if *layer == "" {
+		return fmt.Errorf("--layer is required\n%v", usage)
+	}
+
+	var w io.Writer = os.Stdout
+
+	if *out != "" {
+		f, err := os.Create(filepath.Clean(*out))
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		w = f
+	}
+
+	bw := bufio.NewWriter(w)
+	writer := geojson.NewWriter(bw)
+
+	count, err := x.AppService.Feature().Export(*layer, writer, 0)
+	if err != nil {
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	xlog.Info("export done: [layer: %v] [features: %v]", *layer, count)
+
+	return nil
+}
+
+func (x *Command) buildBoundaries(args []string) error {
+
+	fs := flag.NewFlagSet("boundaries build", flag.ContinueOnError)
+	countries := fs.String("countries", "", "Natural Earth admin 0 countries, .shp, .zip or .geojson")
+	subdivisions := fs.String("subdivisions", "", "Natural Earth admin 1 states and provinces, .shp, .zip or .geojson")
+	tolerance := fs.Float64("tolerance", 0.01, "simplify tolerance, degrees")
+	out := fs.String("out", "", "output file, .geojson.gz")
+
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	if *countries == "" || *out == "" {
+		return fmt.Errorf("--countries and --out are required\n%v", usage)
+	}
+
+	features := []*geojson.Feature{}
+
+	for _, src := range []struct {
+		file  string
+		level int
+	}{{*countries, country.LevelCountry}, {*subdivisions, country.LevelSubdivision}} {
+
+		if src.file == "" {
+			continue
+		}
+
+		count, err := readBoundaries(src.file, func(f *geojson.Feature) {
+			if b, ok := country.FromNaturalEarth(f, src.level, *tolerance); ok {
+				features = append(features, b)
+			}
+		})
+		if err != nil {
+			return err
+		}
+
+		xlog.Info("boundaries read: [file: %v] [features: %v]", src.file, count)
+	}
+
+	f, err := os.Create(filepath.Clean(*out))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	if err := country.Write(f, features); err != nil {
+		return err
+	}
+
+	xlog.Info("boundaries done: [out: %v] [boundaries: %v]", *out, len(features))
+
+	return nil
+}
+
+// readBoundaries features of shapefile or GeoJSON, unsupported records are skipped
+func readBoundaries(name string, fn func(f *geojson.Feature)) (int, error) {
+
+	var r service.FeatureReader
+
+	ext := strings.ToLower(filepath.Ext(name))
+
+	if ext == ".geojson" || ext == ".json" {
+		in, closeIn, err := openInput(name)
+		if err != nil {
+			return 0, err
+		}
+		defer closeIn()
+		r = geojson.NewReader(bufio.NewReader(in))
+	} else {
+		reader, err := shp.Open(name)
+		if err != nil {
+			return 0, err
+		}
+		defer func() { _ = reader.Close() }()
+		r = reader
+	}
+
+	count := 0
+
+	for {
+		f, err := r.Next()
+		if err == io.EOF {
+			return count, nil
+		}
+
+		fe := &geojson.FeatureError{}
+		if errors.As(err, &fe) {
+			xlog.Warn("boundaries skip: [file: %v] [index: %v] %v", name, fe.Index, fe.Err)
+			continue
+		}
+
+		if err != nil {
+			return count, err
+		}
+
+		count++
+		fn(f)
+	}
+}
+
+// openInput file or stdin for "-"
+func openInput(name string) (io.Reader, func(), error) {
+
+	if name == "-" {
+		return os.Stdin, func() {}, nil
+	}
+
+	f, err := os.Open(filepath.Clean(name))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return f, func() { _ = f.Close() }, nil
+}
+
+// importOptions log progress, write rejects to file or log
+func importOptions(batch int, rejectsFile string) (service.ImportOptions, func(), error) {
+
+	opts := service.ImportOptions{
+		BatchSize: batch,
+		OnProgress: func(res service.ImportResult) {
+			xlog.Info("import progress: [total: %v] [imported: %v] [rejected: %v]", res.Total, res.Imported, res.Rejected)
+		},
+		OnReject: func(rej service.ImportReject) {
+			xlog.Warn("import reject: [index: %v] [id: %v] %v", rej.Index, rej.ID, rej.Reason)
+		},
+	}
+
+	if rejectsFile == "" {
+		return opts, func() {}, nil
+	}
+
+	f, err := os.Create(filepath.Clean(rejectsFile))
+	if err != nil {
+		return opts, nil, err
+	}
+
+	enc := json.NewEncoder(f)
+	opts.OnReject = func(rej service.ImportReject) {
+		rej.Reason = strings.TrimSpace(rej.Reason)
+		_ = enc.Encode(rej)
+	}
+
+	return opts, func() { _ = f.Close() }, nil
+}
+
+func (x *Command) migrateUp(args []string) error {
+
+	fs := flag.NewFlagSet("migrate up", flag.ContinueOnError)
+	tenant := fs.String("tenant", "", "tenant schema, default and all tenants if empty")
+
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(rest) != 0 {
+		return fmt.Errorf("unexpected args: %v\n%v", rest, usage)
+	}
+
+	repositories, err := x.migrateRepositories(*tenant, true)
+	if err != nil {
+		return err
+	}
+
+	for _, rep := range repositories {
+
+		applied, err := service.MigrateUp(rep)
+
+		xlog.Info("migrate up done: [schema: %v] [applied: %v]", rep.Schema(), len(applied))
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (x *Command) migrateDown(args []string) error {
+
+	fs := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+	steps := fs.Int("steps", 1, "migrations to revert")
+	tenant := fs.String("tenant", "", "tenant schema, default if empty")
+
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(rest) != 0 || *steps <= 0 {
+		return fmt.Errorf("--steps must be positive\n%v", usage)
+	}
+
+	repositories, err := x.migrateRepositories(*tenant, false)
+	if err != nil {
+		return err
+	}
+
+	migrator, err := service.NewMigrator(repositories[0])
+	if err != nil {
+		return err
+	}
+
+	reverted, err := migrator.Down(*steps)
+	for _, v := range reverted {
+		xlog.Info("migration reverted: %v_%v", v.Version, v.Name)
+	}
+
+	xlog.Info("migrate down done: [schema: %v] [reverted: %v]", repositories[0].Schema(), len(reverted))
+
+	return err
+}
+
+func (x *Command) migrateStatus(args []string) error {
+
+	fs := flag.NewFlagSet("migrate status", flag.ContinueOnError)
+	tenant := fs.String("tenant", "", "tenant schema, default and all tenants if empty")
+
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(rest) != 0 {
+		return fmt.Errorf("unexpected args: %v\n%v", rest, usage)
+	}
+
+	repositories, err := x.migrateRepositories(*tenant, true)
+	if err != nil {
+		return err
+	}
+
+	for _, rep := range repositories {
+
+		migrator, err := service.NewMigrator(rep)
+		if err != nil {
+			return err
+		}
+
+		res, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+
+		if len(repositories) > 1 {
+			fmt.Printf("# schema %v\n", cmp.Or(rep.Schema(), "default"))
+		}
+
+		for _, v := range res {
+			state := "pending"
+			switch {
+			case v.Missing:
+				state = "applied " + v.AppliedAt.Format(time.RFC3339) + ", no file"
+			case v.AppliedAt != nil:
+				state = "applied " + v.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%v\t%v\n", v.Version, v.Name, state)
+		}
+	}
+
+	return nil
+}
+
+// migrateRepositories repository of tenant, or default and with all tenants
+func (x *Command) migrateRepositories(tenant string, all bool) ([]repository.AppRepository, error) {
+
+	if tenant != "" {
+		v, err := x.AppService.Tenant(tenant)
+		if err != nil {
+			return nil, err
+		}
+		return []repository.AppRepository{v.Repository()}, nil
+	}
+
+	res := []repository.AppRepository{x.AppService.Repository()}
+
+	if !all {
+		return res, nil
+	}
+
+	for _, name := range service.TenantNames(x.AppService.Config()) {
+		v, err := x.AppService.Tenant(name)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, v.Repository())
+	}
+
+	return res, nil
+}
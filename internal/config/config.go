@@ -2,6 +2,7 @@
 package config
 
 import (
+	"cmp"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -11,6 +12,7 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
@@ -49,6 +51,8 @@ type CmdLineConfig struct {
 	ListenSys string
 
 	DumpConfig bool
+
+	Args []string // subcommand and its args, empty = start server
 }
 
 const (
@@ -84,6 +88,8 @@ func ReadFlags() {
 
 	flag.Parse() // dont use from init()
 
+	CmdLine.Args = flag.Args()
+
 	dumpVersionAndExitIf()
 
 }
@@ -147,6 +153,25 @@ func (x *envReader) String(p *string, name string, cmdValue *string) {
 
 }
 
+// Strings comma separated list, replaces config list
+func (x *envReader) Strings(p *[]string, name string, cmdValue *string) {
+
+	value := ""
+	x.String(&value, name, cmdValue)
+
+	if value == "" {
+		return
+	}
+
+	res := []string{}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	*p = res
+}
+
 func (x *envReader) Bool(p *bool, name string, cmdValue *bool) {
 
 	envName := strings.ToUpper(x.prefix + name) // *nix case-sensitive
@@ -217,7 +242,8 @@ func (x *envReader) Int(p *int, name string, cmdValue *int) {
 }
 
 type Database struct {
-	Dialect   string `json:"dialect"`
+	Dialect   string `json:"dialect"` // postgres or sqlite
+	File      string `json:"file"`    // sqlite database file, empty = in memory
 	Host      string `json:"host"`
 	Port      string `json:"port"`
 	Name      string `json:"name"`
@@ -228,7 +254,58 @@ type Database struct {
 	MaxIdle   int    `json:"max_idle"`
 	IdleTime  int    `json:"idle_time"`
 	Migration bool   `json:"migration"`
-	SSL       bool   `json:"ssl"`
+	SSL       bool   `json:"ssl"` // sslmode require if SSLMode is empty
+
+	QueryTimeout int `json:"query_timeout"` // seconds, postgres statement_timeout, 0 = none
+	TxRetries    int `json:"tx_retries"`    // retries of transactions on serialization failure or deadlock
+
+	LogLevel  string `json:"log_level"`  // sql log: silent, error, warn (errors and slow queries) or info (all queries)
+	SlowQuery int    `json:"slow_query"` // ms, queries above are logged as slow, 0 = none
+	LogParams bool   `json:"log_params"` // parameter values in logged sql, placeholders by default
+
+	ConnectWait int  `json:"connect_wait"` // seconds of connect retries on start, 0 = until connected
+	Degraded    bool `json:"degraded"`     // server starts without database, db routes are 503 until connected
+
+	Replicas     []string `json:"replicas"`      // read replicas "host", "host:port" or dsn, same user, name and ssl
+	ReplicaLag   int      `json:"replica_lag"`   // seconds, replicas behind are excluded from reads
+	ReplicaCheck int      `json:"replica_check"` // seconds between health checks of replicas
+
+	DSN         string `json:"dsn"`           // postgres url or key=value string, used as is instead of host, port, name, user, password and ssl
+	SSLMode     string `json:"ssl_mode"`      // disable, allow, prefer, require, verify-ca or verify-full
+	SSLRootCert string `json:"ssl_root_cert"` // CA file of server certificate, verify-ca and verify-full
+	SSLCert     string `json:"ssl_cert"`      // client certificate file, with SSLKey
+	SSLKey      string `json:"ssl_key"`       // client key file
+	CertDir     string `json:"cert_dir"`      // dir of relative ssl files, default http server cert dir
+}
+
+// LogLevels of sql log
+var LogLevels = []string{"silent", "error", "warn", "info"}
+
+// SSLModes of postgres sslmode
+var SSLModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// PostgresSSLMode sslmode of connection
+func (x *Database) PostgresSSLMode() string {
+
+	if x.SSLMode != "" {
+		return x.SSLMode
+	}
+
+	if x.SSL {
+		return "require"
+	}
+
+	return "disable"
+}
+
+// SSLFile path of ssl file, relative to CertDir, empty if name is empty
+func (x *Database) SSLFile(name string) string {
+
+	if name == "" || filepath.IsAbs(name) || x.CertDir == "" {
+		return name
+	}
+
+	return filepath.Join(x.CertDir, name)
 }
 
 // type AppConfigLog struct {
@@ -236,10 +313,11 @@ type Database struct {
 // }
 
 type AppConfigMapsGateway struct {
-	Enabled bool   `json:"enabled"`
-	APIKey  string `json:"api_key"`
-	URL     string `json:"url"`
-	Stdout  bool   `json:"stdout"`
+	Enabled   bool   `json:"enabled"`
+	APIKey    string `json:"api_key"`
+	URL       string `json:"url"`
+	SearchURL string `json:"search_url"` // forward geocoding, template with {address} {lang} {api_key}
+	Stdout    bool   `json:"stdout"`
 }
 
 type AppConfigVault struct {
@@ -278,6 +356,201 @@ type AppConfig struct {
 	HTTPServer AppConfigHTTPServer `json:"http_server"`
 
 	Configs AppConfigConfigs `json:"configs"`
+
+	Features AppConfigFeatures `json:"features"`
+	Layers   []AppConfigLayer  `json:"layers"`
+
+	Tiles AppConfigTiles `json:"tiles"`
+
+	TileProxy AppConfigTileProxy `json:"tile_proxy"`
+
+	StaticMap AppConfigStaticMap `json:"static_map"`
+
+	Tracks AppConfigTracks `json:"tracks"`
+
+	Projections []AppConfigProjection `json:"projections"` // custom CRS in addition to built-in
+
+	Geometry AppConfigGeometry `json:"geometry"`
+
+	Postcodes AppConfigPostcodes `json:"postcodes"`
+
+	Country AppConfigCountry `json:"country"`
+
+	Clusters AppConfigClusters `json:"clusters"`
+
+	Hex AppConfigHex `json:"hex"`
+
+	Tenants AppConfigTenants `json:"tenants"`
+}
+
+// AppConfigLayer spatial table exposed as layer, geometry in SRID 4326
+type AppConfigLayer struct {
+	Name     string   `json:"name"`
+	Table    string   `json:"table"`    // "table" or "schema.table"
+	Geometry string   `json:"geometry"` // geometry column, default "geom"
+	ID       string   `json:"id"`       // unique sortable column, default "id", used as paging cursor
+	Columns  []string `json:"columns"`  // columns returned as properties and allowed in filter
+
+	UpdatedAt string `json:"updated_at"` // timestamp column set on insert and update, clusters reload changed rows only
+
+	MinZoom     int      `json:"min_zoom"`     // vector tiles zoom range
+	MaxZoom     int      `json:"max_zoom"`     // 0 = no limit
+	TileColumns []string `json:"tile_columns"` // vector tile attributes, default Columns
+}
+
+// GeometryColumn geometry column name
+func (x *AppConfigLayer) GeometryColumn() string { return cmp.Or(x.Geometry, "geom") }
+
+// IDColumn id column name
+func (x *AppConfigLayer) IDColumn() string { return cmp.Or(x.ID, "id") }
+
+// HasZoom zoom is in layer tiles zoom range
+func (x *AppConfigLayer) HasZoom(z int) bool {
+	return z >= x.MinZoom && (x.MaxZoom == 0 || z <= x.MaxZoom)
+}
+
+// VectorTileColumns vector tile attributes
+func (x *AppConfigLayer) VectorTileColumns() []string {
+	if x.TileColumns != nil {
+		return x.TileColumns
+	}
+	return x.Columns
+}
+
+// HasColumn column is in Columns
+func (x *AppConfigLayer) HasColumn(name string) bool { return slices.Contains(x.Columns, name) }
+
+type AppConfigTiles struct {
+	Extent int `json:"extent"`  // vector tile extent
+	Buffer int `json:"buffer"`  // vector tile buffer in extent units
+	MaxAge int `json:"max_age"` // Cache-Control max-age, seconds
+}
+
+type AppConfigTileProxy struct {
+	Enabled    bool              `json:"enabled"`
+	Sources    map[string]string `json:"sources"`    // name: url template with {z} {x} {y} {s}
+	Subdomains []string          `json:"subdomains"` // values of {s}
+
+	CacheDir     string `json:"cache_dir"`      // default in os temp dir
+	CacheMaxSize int    `json:"cache_max_size"` // MB
+	TTL          int    `json:"ttl"`            // seconds, tile is fresh
+	StaleTTL     int    `json:"stale_ttl"`      // seconds after TTL, serve stale and revalidate in background
+	Timeout      int    `json:"timeout"`        // seconds, upstream request
+	MaxAge       int    `json:"max_age"`        // Cache-Control max-age, seconds
+}
+
+// AppConfigStaticMap static map images, uses tile proxy sources
+type AppConfigStaticMap struct {
+	Source    string `json:"source"` // default tile proxy source
+	MaxWidth  int    `json:"max_width"`
+	MaxHeight int    `json:"max_height"`
+	MaxScale  int    `json:"max_scale"`
+	MaxZoom   int    `json:"max_zoom"` // max zoom of auto-fit
+	Padding   int    `json:"padding"`  // auto-fit padding, px
+	MaxAge    int    `json:"max_age"`  // Cache-Control max-age, seconds
+}
+
+// AppConfigTracks GPX/KML upload and download
+type AppConfigTracks struct {
+	Layer         string `json:"layer"`           // layer of sys import and export, empty disables both
+	MaxUploadSize int    `json:"max_upload_size"` // MB
+	MaxExport     int    `json:"max_export"`      // features of export, ordered by id
+
+	Process AppConfigTrackProcess `json:"process"` // default processing of stored tracks
+}
+
+// AppConfigTrackProcess track cleanup, zero value disables the step
+type AppConfigTrackProcess struct {
+	MaxSpeed  float64 `json:"max_speed"` // m/s, outlier removal
+	Smooth    bool    `json:"smooth"`    // Kalman filter
+	Accuracy  float64 `json:"accuracy"`  // m, smoothing measurement noise
+	Noise     float64 `json:"noise"`     // m/s, smoothing process noise
+	Tolerance float64 `json:"tolerance"` // m, Douglas-Peucker
+}
+
+// AppConfigProjection CRS defined by parameters, angles in degrees
+type AppConfigProjection struct {
+	Code      int       `json:"code"` // EPSG or private code
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`      // longlat, tmerc, lcc
+	Ellipsoid string    `json:"ellipsoid"` // WGS84, GRS80, Airy1830, Intl1924, Clarke66, Bessel41
+	Lat0      float64   `json:"lat_0"`
+	Lon0      float64   `json:"lon_0"`
+	Lat1      float64   `json:"lat_1"` // lcc standard parallels
+	Lat2      float64   `json:"lat_2"`
+	K0        float64   `json:"k_0"` // scale factor, default 1
+	X0        float64   `json:"x_0"` // false easting
+	Y0        float64   `json:"y_0"` // false northing
+	ToWGS84   []float64 `json:"towgs84"`
+}
+
+// AppConfigGeometry geometry operations api
+type AppConfigGeometry struct {
+	Engine            string  `json:"engine"`              // auto (postgis, go on db error), postgis, go
+	MaxVertices       int     `json:"max_vertices"`        // per request
+	MaxBufferDistance float64 `json:"max_buffer_distance"` // meters
+}
+
+// AppConfigPostcodes postal code table of GeoNames dumps
+type AppConfigPostcodes struct {
+	Enabled     bool    `json:"enabled"`
+	Table       string  `json:"table"`        // "table" or "schema.table"
+	MaxDistance float64 `json:"max_distance"` // meters, nearest postcode
+	Geocode     bool    `json:"geocode"`      // fill in missing postcode of geocode results
+}
+
+// AppConfigCountry country and subdivision lookup
+type AppConfigCountry struct {
+	File string `json:"file"` // boundaries of "boundaries build", default embedded
+}
+
+// AppConfigClusters point clustering of layers in memory
+type AppConfigClusters struct {
+	Layers  []string `json:"layers"`   // point layers, empty disables clustering
+	Radius  int      `json:"radius"`   // cluster size, pixels of 256 tile
+	MaxZoom int      `json:"max_zoom"` // points are not clustered above
+	Refresh int      `json:"refresh"`  // seconds between reloads of layer
+}
+
+// AppConfigHex hexagonal grid api
+type AppConfigHex struct {
+	MaxCells int `json:"max_cells"` // per request, k-ring and aggregate
+	MaxRows  int `json:"max_rows"`  // points read by aggregate per request
+}
+
+// AppConfigTenants postgres schema per tenant, selected per request by api key or header
+type AppConfigTenants struct {
+	Enabled  bool              `json:"enabled"`
+	Header   string            `json:"header"`   // tenant name header, empty = api keys only
+	APIKeys  map[string]string `json:"api_keys"` // api key to tenant name, query api-key or header X-API-Key
+	Schemas  map[string]string `json:"schemas"`  // tenant name to schema, migrated on start
+	Required bool              `json:"required"` // reject requests without tenant, else default schema
+
+	// TrustHeader header alone selects tenant though api keys are configured,
+	// header is set by trusted proxy only, without api keys header is always used
+	TrustHeader bool `json:"trust_header"`
+}
+
+// HeaderSelects tenant header selects tenant without api key
+func (x *AppConfigTenants) HeaderSelects() bool {
+	return x.Header != "" && (len(x.APIKeys) == 0 || x.TrustHeader)
+}
+
+type AppConfigFeatures struct {
+	DefaultLimit int `json:"default_limit"`
+	MaxLimit     int `json:"max_limit"`
+}
+
+// Layer find layer by name, nil if not exists
+func (x *AppConfig) Layer(name string) *AppConfigLayer {
+
+	for i := range x.Layers {
+		if x.Layers[i].Name == name {
+			return &x.Layers[i]
+		}
+	}
+
+	return nil
 }
 
 func NewAppConfig() *AppConfig {
@@ -300,6 +573,16 @@ func NewAppConfig() *AppConfig {
 			MaxIdle:   0,
 			IdleTime:  0,
 			Migration: true,
+
+			TxRetries: 3,
+
+			LogLevel:  "warn",
+			SlowQuery: 200,
+
+			ConnectWait: 60,
+
+			ReplicaLag:   10,
+			ReplicaCheck: 5,
 		},
 
 		Redis: Database{
@@ -319,14 +602,71 @@ func NewAppConfig() *AppConfig {
 		},
 
 		OsmGateway: AppConfigMapsGateway{
-			URL: "https://nominatim.openstreetmap.org/search?q={lat_lng}&format=json&accept-language={lang}",
+			URL:       "https://nominatim.openstreetmap.org/search?q={lat_lng}&format=json&accept-language={lang}",
+			SearchURL: "https://nominatim.openstreetmap.org/search?q={address}&format=json&limit=1&accept-language={lang}",
 		},
 
 		GmapsGateway: AppConfigMapsGateway{
-			URL: "https://maps.googleapis.com/maps/api/geocode/json?latlng={lat_lng}&key={key}&language={lang}&location_type=ROOFTOP&result_type=street_address",
+			URL:       "https://maps.googleapis.com/maps/api/geocode/json?latlng={lat_lng}&key={key}&language={lang}&location_type=ROOFTOP&result_type=street_address",
+			SearchURL: "https://maps.googleapis.com/maps/api/geocode/json?address={address}&key={api_key}&language={lang}",
+		},
+
+		HTTPTransport: AppConfigHTTPTransport{
+			UserAgent: "Mozilla/5.0 (compatible; AcmeInc/1.0)",
+		},
+
+		TileProxy: AppConfigTileProxy{
+			Sources: map[string]string{
+				"osm": "https://tile.openstreetmap.org/{z}/{x}/{y}.png",
+			},
+			CacheMaxSize: 512,
+			TTL:          7 * 24 * 3600,
+			StaleTTL:     30 * 24 * 3600,
+			Timeout:      10,
+			MaxAge:       24 * 3600,
 		},
 
-		HTTPTransport: AppConfigHTTPTransport{},
+		StaticMap: AppConfigStaticMap{
+			Source:    "osm",
+			MaxWidth:  1280,
+			MaxHeight: 1280,
+			MaxScale:  2,
+			MaxZoom:   17,
+			Padding:   32,
+			MaxAge:    3600,
+		},
+
+		Tracks: AppConfigTracks{
+			MaxUploadSize: 20,
+			MaxExport:     10000,
+		},
+
+		Geometry: AppConfigGeometry{
+			Engine:            "auto",
+			MaxVertices:       100000,
+			MaxBufferDistance: 100000,
+		},
+
+		Clusters: AppConfigClusters{
+			Radius:  60,
+			MaxZoom: 16,
+			Refresh: 10,
+		},
+
+		Hex: AppConfigHex{
+			MaxCells: 10000,
+			MaxRows:  1000000,
+		},
+
+		Tenants: AppConfigTenants{
+			Header: "X-Tenant",
+		},
+
+		Postcodes: AppConfigPostcodes{
+			Table:       "postcodes",
+			MaxDistance: 20000,
+			Geocode:     true,
+		},
 
 		HTTPServer: AppConfigHTTPServer{
 			ReadTimeout:  0,
@@ -341,11 +681,25 @@ func NewAppConfig() *AppConfig {
 			CertDir: "",
 
 			SysAPIKey: "",
+
+			SysImportMaxSize:      256,
+			SysImportMaxUnzipSize: 1024,
 		},
 
 		Configs: AppConfigConfigs{
 			Dir: "",
 		},
+
+		Features: AppConfigFeatures{
+			DefaultLimit: 100,
+			MaxLimit:     1000,
+		},
+
+		Tiles: AppConfigTiles{
+			Extent: 4096,
+			Buffer: 64,
+			MaxAge: 300,
+		},
 	}
 
 	return res
@@ -394,11 +748,13 @@ func (x *AppConfig) readEnvVar() error {
 
 	// OsmGateway configuration
 	reader.String(&x.OsmGateway.URL, "osm_url", nil)
+	reader.String(&x.OsmGateway.SearchURL, "osm_search_url", nil)
 	reader.String(&x.OsmGateway.APIKey, "osm_api_key", nil)
 	reader.Bool(&x.OsmGateway.Enabled, "osm_enabled", nil)
 	reader.Bool(&x.OsmGateway.Stdout, "osm_stdout", nil)
 	// GoogleMapsGateway configuration
 	reader.String(&x.GmapsGateway.URL, "gmaps_url", nil)
+	reader.String(&x.GmapsGateway.SearchURL, "gmaps_search_url", nil)
 	reader.String(&x.GmapsGateway.APIKey, "gmaps_api_key", nil)
 	reader.Bool(&x.GmapsGateway.Enabled, "gmaps_enabled", nil)
 	reader.Bool(&x.GmapsGateway.Stdout, "gmaps_stdout", nil)
@@ -406,20 +762,86 @@ func (x *AppConfig) readEnvVar() error {
 	// Database configuration
 
 	reader.String(&x.DB.Dialect, "db_dialect", nil)
+	reader.String(&x.DB.File, "db_file", nil)
 	reader.String(&x.DB.Host, "db_host", nil)
 	reader.String(&x.DB.Port, "db_port", nil)
 	reader.String(&x.DB.Name, "db_name", nil)
+	reader.String(&x.DB.Schema, "db_schema", nil)
 	reader.String(&x.DB.User, "db_user", nil)
 	reader.String(&x.DB.Password, "db_password", nil)
 	reader.Int(&x.DB.MaxOpen, "db_max_open", nil)
 	reader.Int(&x.DB.MaxIdle, "db_max_idle", nil)
 	reader.Int(&x.DB.IdleTime, "db_idle_time", nil)
+	reader.Int(&x.DB.QueryTimeout, "db_query_timeout", nil)
+	reader.Int(&x.DB.TxRetries, "db_tx_retries", nil)
+	reader.String(&x.DB.LogLevel, "db_log_level", nil)
+	reader.Int(&x.DB.SlowQuery, "db_slow_query", nil)
+	reader.Bool(&x.DB.LogParams, "db_log_params", nil)
+	reader.Int(&x.DB.ConnectWait, "db_connect_wait", nil)
+	reader.Bool(&x.DB.Degraded, "db_degraded", nil)
+	reader.Strings(&x.DB.Replicas, "db_replicas", nil)
+	reader.Int(&x.DB.ReplicaLag, "db_replica_lag", nil)
+	reader.Int(&x.DB.ReplicaCheck, "db_replica_check", nil)
 	reader.Bool(&x.DB.Migration, "db_migration", nil)
 	reader.Bool(&x.DB.SSL, "db_ssl", nil)
+	reader.String(&x.DB.DSN, "db_dsn", nil)
+	reader.String(&x.DB.SSLMode, "db_ssl_mode", nil)
+	reader.String(&x.DB.SSLRootCert, "db_ssl_root_cert", nil)
+	reader.String(&x.DB.SSLCert, "db_ssl_cert", nil)
+	reader.String(&x.DB.SSLKey, "db_ssl_key", nil)
+	reader.String(&x.DB.CertDir, "db_cert_dir", nil)
 
 	// General configuration
 	reader.String(&x.Title, "title", nil)
 
+	// Features
+	reader.Int(&x.Features.DefaultLimit, "features_default_limit", nil)
+	reader.Int(&x.Features.MaxLimit, "features_max_limit", nil)
+
+	// Tiles
+	reader.Int(&x.Tiles.Extent, "tiles_extent", nil)
+	reader.Int(&x.Tiles.Buffer, "tiles_buffer", nil)
+	reader.Int(&x.Tiles.MaxAge, "tiles_max_age", nil)
+
+	// Tile proxy
+	reader.Bool(&x.TileProxy.Enabled, "tile_proxy_enabled", nil)
+	reader.String(&x.TileProxy.CacheDir, "tile_proxy_cache_dir", nil)
+	reader.Int(&x.TileProxy.CacheMaxSize, "tile_proxy_cache_max_size", nil)
+	reader.Int(&x.TileProxy.TTL, "tile_proxy_ttl", nil)
+	reader.Int(&x.TileProxy.StaleTTL, "tile_proxy_stale_ttl", nil)
+
+	// Tracks
+	reader.String(&x.Tracks.Layer, "tracks_layer", nil)
+	reader.Int(&x.Tracks.MaxUploadSize, "tracks_max_upload_size", nil)
+	reader.Int(&x.Tracks.MaxExport, "tracks_max_export", nil)
+
+	// Geometry
+	reader.String(&x.Geometry.Engine, "geometry_engine", nil)
+	reader.Int(&x.Geometry.MaxVertices, "geometry_max_vertices", nil)
+
+	// Postcodes
+	reader.Bool(&x.Postcodes.Enabled, "postcodes_enabled", nil)
+	reader.String(&x.Postcodes.Table, "postcodes_table", nil)
+
+	// Country
+	reader.String(&x.Country.File, "country_file", nil)
+
+	// Clusters
+	reader.Int(&x.Clusters.Radius, "clusters_radius", nil)
+	reader.Int(&x.Clusters.MaxZoom, "clusters_max_zoom", nil)
+	reader.Int(&x.Clusters.Refresh, "clusters_refresh", nil)
+
+	// Hex
+	reader.Int(&x.Hex.MaxCells, "hex_max_cells", nil)
+	reader.Int(&x.Hex.MaxRows, "hex_max_rows", nil)
+	reader.Bool(&x.Tenants.Enabled, "tenants_enabled", nil)
+	reader.String(&x.Tenants.Header, "tenants_header", nil)
+	reader.Bool(&x.Tenants.Required, "tenants_required", nil)
+	reader.Bool(&x.Tenants.TrustHeader, "tenants_trust_header", nil)
+
+	// Http transport
+	reader.String(&x.HTTPTransport.UserAgent, "http_user_agent", nil)
+
 	// Http server
 	reader.Bool(&x.HTTPServer.AccessLog, "http_access_log", nil)
 	reader.Float64(&x.HTTPServer.RateLimit, "http_rate_limit", nil)
@@ -436,6 +858,9 @@ func (x *AppConfig) readEnvVar() error {
 	reader.Int(&x.HTTPServer.ReadHeaderTimeout, "http_read_header_timeout", nil)
 	reader.String(&x.HTTPServer.ListenSys, "http_listen_sys", nil)  // =>listen_sys
 	reader.String(&x.HTTPServer.SysAPIKey, "http_sys_api_key", nil) // =>sys_api_key
+	reader.Bool(&x.HTTPServer.SysImport, "http_sys_import", nil)
+	reader.Int(&x.HTTPServer.SysImportMaxSize, "http_sys_import_max_size", nil)
+	reader.Int(&x.HTTPServer.SysImportMaxUnzipSize, "http_sys_import_max_unzip_size", nil)
 
 	reader.String(&x.HTTPServer.CertDir, "cert_dir", &CmdLine.CertDir) // short
 	reader.String(&x.Configs.Dir, "configs_dir", &CmdLine.ConfigsDir)
@@ -473,6 +898,194 @@ func (x AppConfig) validate() error {
 		return fmt.Errorf("socket Listen and ListenTLS are empty")
 	}
 
+	if x.Tiles.Extent <= 0 || x.Tiles.Buffer < 0 {
+		return fmt.Errorf("tiles extent or buffer is invalid")
+	}
+
+	if x.HTTPServer.SysImport && x.HTTPServer.SysImportMaxSize <= 0 {
+		return fmt.Errorf("sys import max size is invalid")
+	}
+
+	if x.HTTPServer.SysImport && x.HTTPServer.SysImportMaxUnzipSize <= 0 {
+		return fmt.Errorf("sys import max unzip size is invalid")
+	}
+
+	if x.TileProxy.Enabled {
+		for k := range x.TileProxy.Sources {
+			if !reColumnName.MatchString(k) {
+				return fmt.Errorf("tile proxy source has invalid name: %q", k)
+			}
+		}
+	}
+
+	if err := x.validateLayers(); err != nil {
+		return err
+	}
+
+	if x.Tracks.Layer != "" && x.Layer(x.Tracks.Layer) == nil {
+		return fmt.Errorf("tracks layer is not in layers: %q", x.Tracks.Layer)
+	}
+
+	if !slices.Contains([]string{"auto", "postgis", "go"}, x.Geometry.Engine) {
+		return fmt.Errorf("geometry engine is invalid: %q", x.Geometry.Engine)
+	}
+
+	if !slices.Contains([]string{"postgres", "sqlite"}, x.DB.Dialect) {
+		return fmt.Errorf("db dialect is invalid: %q", x.DB.Dialect)
+	}
+
+	if x.DB.QueryTimeout < 0 || x.DB.ConnectWait < 0 || x.DB.TxRetries < 0 {
+		return fmt.Errorf("db query timeout, connect wait or tx retries is invalid")
+	}
+
+	if !slices.Contains(LogLevels, x.DB.LogLevel) || x.DB.SlowQuery < 0 {
+		return fmt.Errorf("db log level or slow query is invalid: %q %v", x.DB.LogLevel, x.DB.SlowQuery)
+	}
+
+	if len(x.DB.Replicas) > 0 && (x.DB.Dialect != "postgres" || x.DB.ReplicaLag <= 0 || x.DB.ReplicaCheck <= 0) {
+		return fmt.Errorf("db replicas need postgres, positive replica lag and check")
+	}
+
+	if err := x.validateSSL(); err != nil {
+		return err
+	}
+
+	if x.Postcodes.Enabled && x.DB.Dialect != "postgres" {
+		return fmt.Errorf("postcodes need postgres with postgis")
+	}
+
+	if x.Postcodes.Enabled && !reTableName.MatchString(x.Postcodes.Table) {
+		return fmt.Errorf("postcodes table is invalid: %q", x.Postcodes.Table)
+	}
+
+	if x.Tracks.MaxUploadSize <= 0 || x.Tracks.MaxExport <= 0 {
+		return fmt.Errorf("tracks max upload size or max export is invalid")
+	}
+
+	for _, v := range x.Clusters.Layers {
+		if x.Layer(v) == nil {
+			return fmt.Errorf("clusters layer is not in layers: %q", v)
+		}
+	}
+
+	if x.Clusters.Radius <= 0 || x.Clusters.MaxZoom < 0 || x.Clusters.MaxZoom > 24 || x.Clusters.Refresh <= 0 {
+		return fmt.Errorf("clusters radius, max zoom or refresh is invalid")
+	}
+
+	if x.Hex.MaxCells <= 0 {
+		return fmt.Errorf("hex max cells is invalid")
+	}
+
+	if x.Hex.MaxRows <= 0 {
+		return fmt.Errorf("hex max rows is invalid")
+	}
+
+	if err := x.validateTenants(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateTenants schemas are used in sql as identifiers
+func (x AppConfig) validateTenants() error {
+
+	if x.DB.Schema != "" && !reColumnName.MatchString(x.DB.Schema) {
+		return fmt.Errorf("db schema is invalid: %q", x.DB.Schema)
+	}
+
+	if !x.Tenants.Enabled {
+		return nil
+	}
+
+	if x.DB.Dialect != "postgres" {
+		return fmt.Errorf("tenants need postgres")
+	}
+
+	if len(x.Tenants.Schemas) == 0 {
+		return fmt.Errorf("tenants schemas are empty")
+	}
+
+	for k, v := range x.Tenants.Schemas {
+		if k == "" || !reColumnName.MatchString(v) {
+			return fmt.Errorf("tenant %q has invalid schema: %q", k, v)
+		}
+	}
+
+	for _, v := range x.Tenants.APIKeys {
+		if _, ok := x.Tenants.Schemas[v]; !ok {
+			return fmt.Errorf("tenants api key of unknown tenant: %q", v)
+		}
+	}
+
+	return nil
+}
+
+// validateSSL mode and files of postgres connection, files are checked on start
+func (x AppConfig) validateSSL() error {
+
+	db := &x.DB
+
+	if db.Dialect != "postgres" {
+		return nil
+	}
+
+	if db.SSLMode != "" && !slices.Contains(SSLModes, db.SSLMode) {
+		return fmt.Errorf("db ssl mode is invalid: %q", db.SSLMode)
+	}
+
+	if (db.SSLCert == "") != (db.SSLKey == "") {
+		return fmt.Errorf("db ssl cert and key are required together")
+	}
+
+	for _, v := range []string{db.SSLRootCert, db.SSLCert, db.SSLKey} {
+		if v == "" {
+			continue
+		}
+		if _, err := os.Stat(db.SSLFile(v)); err != nil {
+			return fmt.Errorf("db ssl file: %v", err)
+		}
+	}
+
+	return nil
+}
+
+var (
+	reTableName  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+	reColumnName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// validateLayers names are used in sql as identifiers
+func (x AppConfig) validateLayers() error {
+
+	names := map[string]bool{}
+
+	for _, v := range x.Layers {
+
+		if v.Name == "" {
+			return fmt.Errorf("layer name is empty")
+		}
+
+		if names[v.Name] {
+			return fmt.Errorf("layer name is not unique: %v", v.Name)
+		}
+		names[v.Name] = true
+
+		if !reTableName.MatchString(v.Table) {
+			return fmt.Errorf("layer %v has invalid table: %q", v.Name, v.Table)
+		}
+
+		if v.MinZoom < 0 || v.MaxZoom < 0 || (v.MaxZoom > 0 && v.MinZoom > v.MaxZoom) {
+			return fmt.Errorf("layer %v has invalid zoom range: %v-%v", v.Name, v.MinZoom, v.MaxZoom)
+		}
+
+		for _, c := range slices.Concat([]string{v.GeometryColumn(), v.IDColumn()}, v.Columns, v.TileColumns) {
+			if !reColumnName.MatchString(c) {
+				return fmt.Errorf("layer %v has invalid column: %q", v.Name, c)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -499,6 +1112,8 @@ type AppConfigHTTPTransport struct {
 	MaxIdleConnsPerHost int `json:"max_idle_conns_per_host,omitempty"`
 	IdleConnTimeout     int `json:"idle_conn_timeout,omitempty"`
 	MaxConnsPerHost     int `json:"max_conns_per_host,omitempty"`
+
+	UserAgent string `json:"user_agent,omitempty"` // for outgoing requests to gateways and tile servers
 }
 
 type AppConfigHTTPServer struct {
@@ -521,6 +1136,10 @@ type AppConfigHTTPServer struct {
 	SysMetrics bool   `json:"sys_metrics"` //
 	SysAPIKey  string `json:"sys_api_key"`
 	ListenSys  string `json:"listen_sys"`
+
+	SysImport             bool `json:"sys_import"`                // upload of layer data to sys api
+	SysImportMaxSize      int  `json:"sys_import_max_size"`       // MB
+	SysImportMaxUnzipSize int  `json:"sys_import_max_unzip_size"` // MB, shapefile parts unpacked to memory
 }
 type AppConfigConfigs struct {
 	Dir string `json:"dir"`
@@ -565,6 +1184,10 @@ func (x *AppConfigSource) Load() error {
 
 	}
 
+	if res.DB.CertDir == "" {
+		res.DB.CertDir = res.HTTPServer.CertDir
+	}
+
 	{
 		err := res.validate()
 		if err != nil {
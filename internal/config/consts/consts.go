@@ -8,12 +8,63 @@ const (
 	DefaultTextLength  = 100
 	LocationTextLength = 30
 	LangTextLength     = 2
+	AddressTextLength  = 300
+	// MaxQueryItems max count of repeated query params
+	MaxQueryItems = 20
+)
+
+const (
+	// ContextKeyAppService echo context key of tenant services of request
+	ContextKeyAppService = "app_service"
+	// HeaderAPIKey api key of tenant, or query api-key
+	HeaderAPIKey = "X-API-Key"
 )
 
 const (
 	PathSysMetricsAPI = "/sys/api/metrics"
 
+	PathSysImportShapefileAPI = "/sys/api/import/shapefile"
+
+	PathSysImportTracksAPI = "/sys/api/import/tracks"
+
 	PathGisPingDebugAPI = "/gis/api/ping"
 
 	PathGisGeocodeAPI = "/gis/api/geocode"
+
+	PathGisGeocodeForwardAPI = "/gis/api/geocode/forward"
+
+	PathGisAddressAPI = "/gis/api/address"
+
+	PathGisPostcodesAPI = "/gis/api/postcodes"
+
+	PathGisPostcodesNearestAPI = "/gis/api/postcodes/nearest"
+
+	PathGisCountryAPI = "/gis/api/country"
+
+	PathGisFeaturesAPI = "/gis/api/features"
+
+	PathGisClustersAPI = "/gis/api/clusters"
+
+	PathGisHexCellAPI      = "/gis/api/hex/cell"
+	PathGisHexCellByIDAPI  = "/gis/api/hex/cells/:id"
+	PathGisHexRingAPI      = "/gis/api/hex/cells/:id/ring"
+	PathGisHexAggregateAPI = "/gis/api/hex/aggregate"
+
+	PathGisPrefix = "/gis/" // tenant routes
+
+	PathGisVectorTiles = "/gis/tiles/:layer/:z/:x/:y" // y with .pbf
+
+	PathGisRasterTiles = "/gis/raster/:source/:z/:x/:y" // y with .png
+
+	PathGisStaticMapAPI = "/gis/api/staticmap"
+
+	PathGisTracksAPI = "/gis/api/tracks" // GET export of tracks layer
+
+	PathGisTracksConvertAPI = "/gis/api/tracks/convert"
+
+	PathGisTracksProcessAPI = "/gis/api/tracks/process"
+
+	PathGisTransformAPI = "/gis/api/transform"
+
+	PathGisGeometryAPI = "/gis/api/geometry/:op"
 )
@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"go-gis/internal/config/consts"
+	"go-gis/internal/geo/address"
+	"go-gis/internal/service"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type addressTextDTO struct {
+	Address string `query:"address"`
+	Lang    string `query:"lang"`
+}
+
+func (x addressTextDTO) validate() bool {
+
+	if x.Address == "" || len(x.Address) > consts.AddressTextLength {
+		return false
+	}
+
+	// len(2)
+	if len(x.Lang) > consts.LangTextLength {
+		return false
+	}
+
+	return true
+}
+
+type addressPartsDTO struct {
+	address.Address
+	Formatted string `json:"formatted"`
+}
+
+// AddressController controller
+type AddressController struct {
+	appService service.AppService
+	webCtxt    echo.Context
+	Debug      bool
+}
+
+// NewAddressController new controller
+func NewAddressController(appService service.AppService, c echo.Context) *AddressController {
+
+	appConfig := appService.Config()
+	return &AddressController{
+		Debug:      appConfig.Debug,
+		appService: appService,
+		webCtxt:    c,
+	}
+}
+
+// Parse address text to normalized components
+func (x *AddressController) Parse() error {
+
+	c := x.webCtxt
+	dto := &addressTextDTO{}
+	err := c.Bind(dto)
+	if err != nil {
+		return err
+	}
+
+	if !dto.validate() {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	res := x.appService.Address().Parse(dto.Address, dto.Lang)
+
+	return c.JSON(http.StatusOK, addressPartsDTO{Address: res, Formatted: res.String()})
+}
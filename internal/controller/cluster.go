@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"errors"
+	"go-gis/internal/config/consts"
+	"go-gis/internal/geo/geojson"
+	"go-gis/internal/geo/tile"
+	"go-gis/internal/service"
+	xlog "go-gis/internal/util/utillog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type clusterQueryDTO struct {
+	Layer string `query:"layer"`
+	BBox  string `query:"bbox"` // minLng,minLat,maxLng,maxLat
+	Zoom  int    `query:"zoom"`
+}
+
+func (x clusterQueryDTO) validate() bool {
+	return x.Layer != "" && len(x.Layer) <= consts.DefaultTextLength && len(x.BBox) <= consts.DefaultTextLength &&
+		x.Zoom >= 0 && x.Zoom <= tile.MaxZoom
+}
+
+// ClusterController controller
+type ClusterController struct {
+	appService service.AppService
+	webCtxt    echo.Context
+	Debug      bool
+}
+
+// NewClusterController new controller
+func NewClusterController(appService service.AppService, c echo.Context) *ClusterController {
+
+	appConfig := appService.Config()
+	return &ClusterController{
+		Debug:      appConfig.Debug,
+		appService: appService,
+		webCtxt:    c,
+	}
+}
+
+// Clusters of layer in bbox at zoom as FeatureCollection, clusters have "cluster" and "point_count" properties,
+// single points are features of layer
+func (x *ClusterController) Clusters() error {
+
+	c := x.webCtxt
+	dto := &clusterQueryDTO{}
+	err := c.Bind(dto)
+	if err != nil {
+		return err
+	}
+
+	if !dto.validate() {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	bbox, err := geojson.ParseBBox(dto.BBox)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	srv := x.appService.Cluster()
+	if !srv.HasLayer(dto.Layer) {
+		return c.NoContent(http.StatusNotFound)
+	}
+
+	clusters, err := srv.Query(dto.Layer, bbox, dto.Zoom)
+	if errors.Is(err, service.ErrInvalidArgument) {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		xlog.Error("cluster service error: %v", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	res := geojson.NewFeatureCollection()
+	for _, v := range clusters {
+		f := geojson.NewFeature(geojson.NewPoint(v.Lng, v.Lat))
+		if v.Point != nil {
+			f.ID = v.Point.ID
+			if v.Point.Properties != nil {
+				f.Properties = v.Point.Properties
+			}
+		} else {
+			f.Properties["cluster"] = true
+			f.Properties["point_count"] = v.Count
+		}
+		res.Features = append(res.Features, f)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, geojson.MediaType)
+
+	return c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"go-gis/internal/config/consts"
+	"go-gis/internal/service"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type countryDTO struct {
+	LatLng string `query:"lat_lng"`
+}
+
+func (x countryDTO) validate() bool {
+	return x.LatLng != "" && len(x.LatLng) <= consts.LocationTextLength
+}
+
+// CountryController controller
+type CountryController struct {
+	appService service.AppService
+	webCtxt    echo.Context
+	Debug      bool
+}
+
+// NewCountryController new controller
+func NewCountryController(appService service.AppService, c echo.Context) *CountryController {
+
+	appConfig := appService.Config()
+	return &CountryController{
+		Debug:      appConfig.Debug,
+		appService: appService,
+		webCtxt:    c,
+	}
+}
+
+// Country ISO 3166 country and subdivision of lat,lng, 404 if none
+func (x *CountryController) Country() error {
+
+	c := x.webCtxt
+	dto := &countryDTO{}
+	err := c.Bind(dto)
+	if err != nil {
+		return err
+	}
+
+	if !dto.validate() {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	p, err := parseLatLng(dto.LatLng)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	res := x.appService.Country().Lookup(p[1], p[0])
+	if res.Country == "" {
+		return c.NoContent(http.StatusNotFound)
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"errors"
+	"go-gis/internal/config/consts"
+	"go-gis/internal/geo/geojson"
+	"go-gis/internal/service"
+	xlog "go-gis/internal/util/utillog"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+type featureQueryDTO struct {
+	BBox   string   `query:"bbox"`   // minLng,minLat,maxLng,maxLat
+	Layer  []string `query:"layer"`  // layer=a&layer=b or layer=a,b
+	Filter []string `query:"filter"` // filter=column:value
+	Limit  int      `query:"limit"`
+	Cursor string   `query:"cursor"`
+}
+
+func (x featureQueryDTO) validate() bool {
+
+	if len(x.BBox) > consts.DefaultTextLength {
+		return false
+	}
+
+	if len(x.Cursor) > consts.DefaultTextLength*4 {
+		return false
+	}
+
+	if x.Limit < 0 {
+		return false
+	}
+
+	return len(x.Layer) > 0 && len(x.Filter) <= consts.MaxQueryItems
+}
+
+func (x featureQueryDTO) layers() []string {
+
+	res := []string{}
+	for _, v := range x.Layer {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				res = append(res, name)
+			}
+		}
+	}
+
+	return res
+}
+
+// filter "column:value" pairs to map, ok=false if malformed
+func (x featureQueryDTO) filter() (map[string]string, bool) {
+
+	res := map[string]string{}
+	for _, v := range x.Filter {
+		k, val, found := strings.Cut(v, ":")
+		if !found || k == "" {
+			return nil, false
+		}
+		res[k] = val
+	}
+
+	return res, true
+}
+
+type featureCollectionDTO struct {
+	*geojson.FeatureCollection
+	NextCursor string `json:"next_cursor,omitempty"` // foreign member
+}
+
+// FeatureController controller
+type FeatureController struct {
+	appService service.AppService
+	webCtxt    echo.Context
+	Debug      bool
+}
+
+// NewFeatureController new controller
+func NewFeatureController(appService service.AppService, c echo.Context) *FeatureController {
+
+	appConfig := appService.Config()
+	return &FeatureController{
+		Debug:      appConfig.Debug,
+		appService: appService,
+		webCtxt:    c,
+	}
+}
+
+// Features features of layers in bbox as FeatureCollection
+func (x *FeatureController) Features() error {
+
+	c := x.webCtxt
+	dto := &featureQueryDTO{}
+	err := c.Bind(dto)
+	if err != nil {
+		return err
+	}
+
+	if !dto.validate() {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	bbox, err := geojson.ParseBBox(dto.BBox)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	filter, ok := dto.filter()
+	if !ok {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	page, err := x.appService.Feature().Query(service.FeatureQuery{
+		BBox:   bbox,
+		Layers: dto.layers(),
+		Filter: filter,
+		Limit:  dto.Limit,
+		Cursor: dto.Cursor,
+	})
+	if errors.Is(err, service.ErrInvalidArgument) {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		xlog.Error("feature service error: %v", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, geojson.MediaType)
+
+	return c.JSON(http.StatusOK, featureCollectionDTO{
+		FeatureCollection: page.Collection,
+		NextCursor:        page.NextCursor,
+	})
+}
@@ -3,6 +3,7 @@ package controller
 // Handler web req handler
 
 import (
+	"errors"
 	"go-gis/internal/config/consts"
 	"go-gis/internal/service"
 	xlog "go-gis/internal/util/utillog"
@@ -35,6 +36,11 @@ type addressDTO struct {
 	Address string `json:"address"`
 }
 
+type forwardGeocodeDTO struct {
+	LatLng  string `json:"lat_lng"` // empty if not found
+	Address string `json:"address"` // normalized address used in search
+}
+
 // GeocodeController controller
 type GeocodeController struct {
 	appService service.AppService
@@ -78,3 +84,32 @@ func (x *GeocodeController) Geocode() error {
 	return c.JSON(http.StatusOK, addressDTO{Address: addr})
 
 }
+
+// Forward address to latlng
+func (x *GeocodeController) Forward() error {
+
+	c := x.webCtxt
+	dto := &addressTextDTO{}
+	err := c.Bind(dto)
+	if err != nil {
+		return err
+	}
+
+	if !dto.validate() {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	g := x.appService.Geocode()
+
+	latLng, normalized, err := g.AddressToLocation(dto.Address, dto.Lang)
+	if errors.Is(err, service.ErrInvalidArgument) {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		xlog.Error("gocode service error: %v", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, forwardGeocodeDTO{LatLng: latLng, Address: normalized})
+
+}
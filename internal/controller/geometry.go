@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"go-gis/internal/geo/geojson"
+	"go-gis/internal/service"
+	xlog "go-gis/internal/util/utillog"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxGeometryBody max size of posted geometry request
+const maxGeometryBody = 8 * 1024 * 1024
+
+type geometryOpDTO struct {
+	Geometry   *geojson.Geometry   `json:"geometry"`
+	Geometries []*geojson.Geometry `json:"geometries"` // union, intersection
+	Distance   float64             `json:"distance"`   // buffer, meters
+	Segments   int                 `json:"segments"`   // buffer, per quarter circle
+	Tolerance  float64             `json:"tolerance"`  // simplify, degrees
+	Method     string              `json:"method"`     // simplify, dp or vw
+}
+
+type geometryOpResultDTO struct {
+	Geometry *geojson.Geometry `json:"geometry,omitempty"`
+	Value    *float64          `json:"value,omitempty"`
+	Unit     string            `json:"unit,omitempty"`
+	Engine   string            `json:"engine"`
+}
+
+// GeometryController controller
+type GeometryController struct {
+	appService service.AppService
+	webCtxt    echo.Context
+	Debug      bool
+}
+
+// NewGeometryController new controller
+func NewGeometryController(appService service.AppService, c echo.Context) *GeometryController {
+
+	appConfig := appService.Config()
+	return &GeometryController{
+		Debug:      appConfig.Debug,
+		appService: appService,
+		webCtxt:    c,
+	}
+}
+
+// Operation run operation of path on posted geometries
+func (x *GeometryController) Operation() error {
+
+	c := x.webCtxt
+
+	data, err := io.ReadAll(io.LimitReader(c.Request().Body, maxGeometryBody+1))
+	if err != nil {
+		return err
+	}
+	if len(data) > maxGeometryBody {
+		return c.NoContent(http.StatusRequestEntityTooLarge)
+	}
+
+	dto := &geometryOpDTO{}
+	if err := json.Unmarshal(data, dto); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	res, err := x.appService.Geometry().Apply(c.Param("op"), service.GeometryOpRequest{
+		Geometry:   dto.Geometry,
+		Geometries: dto.Geometries,
+		Distance:   dto.Distance,
+		Segments:   dto.Segments,
+		Tolerance:  dto.Tolerance,
+		Method:     dto.Method,
+	})
+	if errors.Is(err, service.ErrInvalidArgument) {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		xlog.Error("geometry service error: %v", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, geometryOpResultDTO{
+		Geometry: res.Geometry,
+		Value:    res.Value,
+		Unit:     res.Unit,
+		Engine:   res.Engine,
+	})
+}
@@ -0,0 +1,219 @@
+package controller
+
+import (
+	"errors"
+	"go-gis/internal/config/consts"
+	"go-gis/internal/geo/geojson"
+	"go-gis/internal/geo/hexgrid"
+	"go-gis/internal/service"
+	xlog "go-gis/internal/util/utillog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type hexCellDTO struct {
+	LatLng     string `query:"lat_lng"`
+	Resolution int    `query:"res"`
+}
+
+func (x hexCellDTO) validate() bool {
+	return x.LatLng != "" && len(x.LatLng) <= consts.LocationTextLength &&
+		x.Resolution >= 0 && x.Resolution <= hexgrid.MaxResolution
+}
+
+type hexRingDTO struct {
+	ID string `param:"id"`
+	K  int    `query:"k"`
+}
+
+func (x hexRingDTO) validate() bool {
+	return len(x.ID) <= consts.DefaultTextLength && x.K >= 0
+}
+
+type hexAggregateDTO struct {
+	Layer      string   `query:"layer"`
+	BBox       string   `query:"bbox"` // minLng,minLat,maxLng,maxLat
+	Resolution int      `query:"res"`
+	Sum        string   `query:"sum"`    // column
+	Filter     []string `query:"filter"` // filter=column:value
+}
+
+func (x hexAggregateDTO) validate() bool {
+	return x.Layer != "" && len(x.Layer) <= consts.DefaultTextLength && len(x.BBox) <= consts.DefaultTextLength &&
+		len(x.Sum) <= consts.DefaultTextLength && len(x.Filter) <= consts.MaxQueryItems &&
+		x.Resolution >= 0 && x.Resolution <= hexgrid.MaxResolution
+}
+
+// HexController controller
+type HexController struct {
+	appService service.AppService
+	webCtxt    echo.Context
+	Debug      bool
+}
+
+// NewHexController new controller
+func NewHexController(appService service.AppService, c echo.Context) *HexController {
+
+	appConfig := appService.Config()
+	return &HexController{
+		Debug:      appConfig.Debug,
+		appService: appService,
+		webCtxt:    c,
+	}
+}
+
+// Cell of lat,lng at resolution as Feature
+func (x *HexController) Cell() error {
+
+	c := x.webCtxt
+	dto := &hexCellDTO{}
+	err := c.Bind(dto)
+	if err != nil {
+		return err
+	}
+
+	if !dto.validate() {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	p, err := parseLatLng(dto.LatLng)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	return x.feature(hexgrid.FromLngLat(p[0], p[1], dto.Resolution))
+}
+
+// CellByID center, boundary, parent of cell as Feature
+func (x *HexController) CellByID() error {
+
+	c := x.webCtxt
+
+	cell, err := hexgrid.Parse(c.Param("id"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	return x.feature(cell)
+}
+
+// Ring cells within k steps of cell as FeatureCollection
+func (x *HexController) Ring() error {
+
+	c := x.webCtxt
+	dto := &hexRingDTO{K: 1}
+	err := c.Bind(dto)
+	if err != nil {
+		return err
+	}
+
+	if !dto.validate() {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	cell, err := hexgrid.Parse(dto.ID)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if hexgrid.RingSize(dto.K) > x.appService.Hex().MaxCells() {
+		return c.String(http.StatusBadRequest, "k is too large")
+	}
+
+	res := geojson.NewFeatureCollection()
+	for _, v := range cell.Ring(dto.K) {
+		if v.Valid() {
+			res.Features = append(res.Features, hexFeature(v))
+		}
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, geojson.MediaType)
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// Aggregate count and sum of layer points in bbox by cell as FeatureCollection
+func (x *HexController) Aggregate() error {
+
+	c := x.webCtxt
+	dto := &hexAggregateDTO{}
+	err := c.Bind(dto)
+	if err != nil {
+		return err
+	}
+
+	if !dto.validate() {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	bbox, err := geojson.ParseBBox(dto.BBox)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	filter, ok := featureQueryDTO{Filter: dto.Filter}.filter()
+	if !ok {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	if x.appService.Config().Layer(dto.Layer) == nil {
+		return c.NoContent(http.StatusNotFound)
+	}
+
+	bins, err := x.appService.Hex().Aggregate(service.HexAggregate{
+		Layer:      dto.Layer,
+		BBox:       bbox,
+		Resolution: dto.Resolution,
+		Sum:        dto.Sum,
+		Filter:     filter,
+	})
+	if errors.Is(err, service.ErrInvalidArgument) {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		xlog.Error("hex service error: %v", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	res := geojson.NewFeatureCollection()
+	for _, v := range bins {
+		f := hexFeature(v.Cell)
+		f.Properties["count"] = v.Count
+		if dto.Sum != "" {
+			f.Properties["sum"] = v.Sum
+		}
+		res.Features = append(res.Features, f)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, geojson.MediaType)
+
+	return c.JSON(http.StatusOK, res)
+}
+
+func (x *HexController) feature(cell hexgrid.Cell) error {
+
+	c := x.webCtxt
+	c.Response().Header().Set(echo.HeaderContentType, geojson.MediaType)
+
+	return c.JSON(http.StatusOK, hexFeature(cell))
+}
+
+// hexFeature boundary polygon with id, resolution, center, parent and area (m²)
+func hexFeature(cell hexgrid.Cell) *geojson.Feature {
+
+	res := geojson.NewFeature(geojson.NewPolygon([][]geojson.Position{cell.Boundary()}))
+	res.ID = cell.String()
+
+	lng, lat := cell.Center()
+	res.Properties["resolution"] = cell.Resolution()
+	res.Properties["lat"] = lat
+	res.Properties["lng"] = lng
+	res.Properties["area"] = cell.Area()
+
+	if r := cell.Resolution(); r > 0 {
+		res.Properties["parent"] = cell.Parent(r - 1).String()
+	}
+
+	return res
+}
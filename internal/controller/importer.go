@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"errors"
+	"go-gis/internal/config/consts"
+	"go-gis/internal/geo/shp"
+	"go-gis/internal/service"
+	xlog "go-gis/internal/util/utillog"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxImportRejects rejects in response
+const maxImportRejects = 100
+
+type importShapefileDTO struct {
+	Layer string `query:"layer"`
+	SRID  int    `query:"srid"` // override of .prj
+	Batch int    `query:"batch"`
+}
+
+func (x importShapefileDTO) validate() bool {
+	return x.Layer != "" && len(x.Layer) <= consts.DefaultTextLength && x.SRID >= 0 && x.Batch >= 0
+}
+
+type importResultDTO struct {
+	Total    int                    `json:"total"`
+	Imported int                    `json:"imported"`
+	Rejected int                    `json:"rejected"`
+	Rejects  []service.ImportReject `json:"rejects,omitempty"` // first rejects only
+}
+
+// ImportController controller
+type ImportController struct {
+	appService service.AppService
+	webCtxt    echo.Context
+	Debug      bool
+}
+
+// NewImportController new controller
+func NewImportController(appService service.AppService, c echo.Context) *ImportController {
+
+	appConfig := appService.Config()
+	return &ImportController{
+		Debug:      appConfig.Debug,
+		appService: appService,
+		webCtxt:    c,
+	}
+}
+
+// Shapefile import zipped shapefile from multipart field "file" to layer
+func (x *ImportController) Shapefile() error {
+
+	c := x.webCtxt
+	dto := &importShapefileDTO{}
+	err := (&echo.DefaultBinder{}).BindQueryParams(c, dto) // body is upload
+	if err != nil {
+		return err
+	}
+
+	if !dto.validate() {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	appConfig := x.appService.Config()
+	if appConfig.Layer(dto.Layer) == nil {
+		return c.NoContent(http.StatusNotFound)
+	}
+
+	maxSize := int64(appConfig.HTTPServer.SysImportMaxSize) * 1024 * 1024
+	c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, maxSize)
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	defer func() { _ = src.Close() }()
+
+	// zip needs random access
+	tmp, err := os.CreateTemp("", "go-gis-import-*.zip")
+	if err != nil {
+		xlog.Error("import temp file error: %v", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	size, err := io.Copy(tmp, src)
+	if err != nil {
+		xlog.Error("import temp file error: %v", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	reader, err := shp.OpenZip(tmp, size, int64(appConfig.HTTPServer.SysImportMaxUnzipSize)*1024*1024)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	srid := dto.SRID
+	if srid == 0 {
+		if srid, err = reader.CRS(); err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	res := importResultDTO{}
+
+	imported, err := x.appService.Feature().Import(dto.Layer, reader, service.ImportOptions{
+		BatchSize: dto.Batch,
+		SRID:      srid,
+		OnReject: func(rej service.ImportReject) {
+			if len(res.Rejects) < maxImportRejects {
+				res.Rejects = append(res.Rejects, rej)
+			}
+		},
+	})
+	if errors.Is(err, service.ErrInvalidArgument) {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		xlog.Error("feature service error: %v", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	res.Total, res.Imported, res.Rejected = imported.Total, imported.Imported, imported.Rejected
+
+	xlog.Info("shapefile import: [layer: %v] [srid: %v] [total: %v] [imported: %v] [rejected: %v]",
+		dto.Layer, srid, res.Total, res.Imported, res.Rejected)
+
+	return c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"errors"
+	"go-gis/internal/config/consts"
+	"go-gis/internal/service"
+	xlog "go-gis/internal/util/utillog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type postcodeDTO struct {
+	Country  string `query:"country"` // ISO 3166-1 alpha-2
+	Postcode string `query:"postcode"`
+}
+
+func (x postcodeDTO) validate() bool {
+	return len(x.Country) == 2 && x.Postcode != "" && len(x.Postcode) <= consts.DefaultTextLength
+}
+
+type nearestPostcodeDTO struct {
+	LatLng  string `query:"lat_lng"`
+	Country string `query:"country"` // optional
+}
+
+func (x nearestPostcodeDTO) validate() bool {
+	return len(x.LatLng) <= consts.LocationTextLength && len(x.Country) <= 2
+}
+
+// PostcodeController controller
+type PostcodeController struct {
+	appService service.AppService
+	webCtxt    echo.Context
+	Debug      bool
+}
+
+// NewPostcodeController new controller
+func NewPostcodeController(appService service.AppService, c echo.Context) *PostcodeController {
+
+	appConfig := appService.Config()
+	return &PostcodeController{
+		Debug:      appConfig.Debug,
+		appService: appService,
+		webCtxt:    c,
+	}
+}
+
+// Lookup postcode to centroid and places
+func (x *PostcodeController) Lookup() error {
+
+	c := x.webCtxt
+	dto := &postcodeDTO{}
+	err := c.Bind(dto)
+	if err != nil {
+		return err
+	}
+
+	if !dto.validate() {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	res, err := x.appService.Postcode().Lookup(dto.Country, dto.Postcode)
+
+	return x.result(res, err)
+}
+
+// Nearest postcode to lat,lng
+func (x *PostcodeController) Nearest() error {
+
+	c := x.webCtxt
+	dto := &nearestPostcodeDTO{}
+	err := c.Bind(dto)
+	if err != nil {
+		return err
+	}
+
+	if !dto.validate() {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	p, err := parseLatLng(dto.LatLng)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	res, err := x.appService.Postcode().Nearest(p[1], p[0], dto.Country)
+
+	return x.result(res, err)
+}
+
+func (x *PostcodeController) result(res *service.Postcode, err error) error {
+
+	c := x.webCtxt
+
+	if errors.Is(err, service.ErrInvalidArgument) {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		xlog.Error("postcode service error: %v", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	if res == nil {
+		return c.NoContent(http.StatusNotFound)
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
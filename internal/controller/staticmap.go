@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"go-gis/internal/config/consts"
+	"go-gis/internal/geo/geojson"
+	"go-gis/internal/geo/render"
+	"go-gis/internal/service"
+	xlog "go-gis/internal/util/utillog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+type staticMapDTO struct {
+	Center  string   `query:"center"` // lat,lng
+	Zoom    string   `query:"zoom"`
+	Size    string   `query:"size"` // WxH
+	Scale   int      `query:"scale"`
+	Format  string   `query:"format"`
+	Source  string   `query:"source"`
+	Markers []string `query:"markers"` // [color:red|size:16|]lat,lng|lat,lng
+	Path    []string `query:"path"`    // [color:blue|weight:3|]lat,lng|lat,lng
+	Polygon []string `query:"polygon"` // [color:blue|fill:0x0000ff40|weight:2|]lat,lng|lat,lng
+}
+
+func (x staticMapDTO) validate() bool {
+
+	if len(x.Center) > consts.LocationTextLength || len(x.Size) > consts.DefaultTextLength {
+		return false
+	}
+
+	return len(x.Markers)+len(x.Path)+len(x.Polygon) <= consts.MaxQueryItems
+}
+
+// request convert params to service request
+func (x staticMapDTO) request() (*service.StaticMapRequest, error) {
+
+	res := &service.StaticMapRequest{
+		Zoom:   -1,
+		Scale:  x.Scale,
+		Format: strings.ToLower(x.Format),
+		Source: x.Source,
+	}
+
+	if res.Format == "jpg" {
+		res.Format = service.FormatJPEG
+	}
+
+	w, h, ok := strings.Cut(x.Size, "x")
+	if !ok {
+		return nil, fmt.Errorf("size must be WxH")
+	}
+
+	var err error
+	if res.Width, err = strconv.Atoi(w); err != nil {
+		return nil, fmt.Errorf("invalid size: %v", err)
+	}
+	if res.Height, err = strconv.Atoi(h); err != nil {
+		return nil, fmt.Errorf("invalid size: %v", err)
+	}
+
+	if x.Center != "" {
+		p, err := parseLatLng(x.Center)
+		if err != nil {
+			return nil, err
+		}
+		res.Center = &p
+	}
+
+	if x.Zoom != "" {
+		if res.Zoom, err = strconv.Atoi(x.Zoom); err != nil || res.Zoom < 0 {
+			return nil, fmt.Errorf("invalid zoom")
+		}
+	}
+
+	kinds := []string{service.OverlayMarker, service.OverlayPath, service.OverlayPolygon}
+
+	for i, items := range [][]string{x.Markers, x.Path, x.Polygon} {
+		for _, v := range items {
+			o, err := parseOverlay(kinds[i], v)
+			if err != nil {
+				return nil, err
+			}
+			res.Overlays = append(res.Overlays, o)
+		}
+	}
+
+	return res, nil
+}
+
+// parseOverlay "color:red|weight:3|lat,lng|lat,lng"
+func parseOverlay(kind string, text string) (service.StaticMapOverlay, error) {
+
+	res := service.StaticMapOverlay{Kind: kind, Style: service.DefaultStaticMapStyle(kind)}
+
+	for _, part := range strings.Split(text, "|") {
+
+		k, v, isStyle := strings.Cut(part, ":")
+		if !isStyle {
+			p, err := parseLatLng(part)
+			if err != nil {
+				return res, err
+			}
+			res.Points = append(res.Points, p)
+			continue
+		}
+
+		var err error
+		switch k {
+		case "color":
+			res.Style.Color, err = render.ParseColor(v)
+		case "fill":
+			res.Style.Fill, err = render.ParseColor(v)
+		case "weight", "size":
+			res.Style.Weight, err = strconv.ParseFloat(v, 64)
+			if err == nil && (res.Style.Weight < 0 || res.Style.Weight > 64) {
+				err = fmt.Errorf("%v must be 0..64", k)
+			}
+		default:
+			err = fmt.Errorf("unknown style: %v", k)
+		}
+
+		if err != nil {
+			return res, err
+		}
+	}
+
+	if kind == service.OverlayPolygon && len(res.Points) > 0 && len(res.Points) < 3 {
+		return res, fmt.Errorf("polygon must have at least 3 points")
+	}
+
+	return res, nil
+}
+
+// parseLatLng "lat,lng" to position
+func parseLatLng(text string) (geojson.Position, error) {
+
+	lat, lng, ok := strings.Cut(text, ",")
+	if !ok {
+		return geojson.Position{}, fmt.Errorf("invalid lat,lng: %q", text)
+	}
+
+	latV, err := strconv.ParseFloat(strings.TrimSpace(lat), 64)
+	if err != nil {
+		return geojson.Position{}, fmt.Errorf("invalid lat: %q", lat)
+	}
+
+	lngV, err := strconv.ParseFloat(strings.TrimSpace(lng), 64)
+	if err != nil {
+		return geojson.Position{}, fmt.Errorf("invalid lng: %q", lng)
+	}
+
+	if latV < -90 || latV > 90 || lngV < -180 || lngV > 180 {
+		return geojson.Position{}, fmt.Errorf("lat,lng out of range: %q", text)
+	}
+
+	return geojson.Position{lngV, latV}, nil
+}
+
+// StaticMapController controller
+type StaticMapController struct {
+	appService service.AppService
+	webCtxt    echo.Context
+	Debug      bool
+}
+
+// NewStaticMapController new controller
+func NewStaticMapController(appService service.AppService, c echo.Context) *StaticMapController {
+
+	appConfig := appService.Config()
+	return &StaticMapController{
+		Debug:      appConfig.Debug,
+		appService: appService,
+		webCtxt:    c,
+	}
+}
+
+// StaticMap map image with markers, paths and polygons
+func (x *StaticMapController) StaticMap() error {
+
+	c := x.webCtxt
+	dto := &staticMapDTO{}
+	err := c.Bind(dto)
+	if err != nil {
+		return err
+	}
+
+	if !dto.validate() {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	req, err := dto.request()
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	data, contentType, err := x.appService.StaticMap().Render(*req)
+	if errors.Is(err, service.ErrInvalidArgument) {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		xlog.Error("static map service error: %v", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return writeCached(c, contentType, data, x.appService.Config().StaticMap.MaxAge)
+}
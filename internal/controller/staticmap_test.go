@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"go-gis/internal/geo/geojson"
+	"go-gis/internal/service"
+	"image/color"
+	"testing"
+)
+
+// Test style and points of overlay, lat,lng to lng,lat
+func TestParseOverlay(t *testing.T) {
+
+	o, err := parseOverlay(service.OverlayPath, "color:0x00ff00|weight:5|51.5,-0.12|51.6,-0.1")
+	if err != nil {
+		t.Fatalf("Error : %v", err)
+	}
+
+	if o.Kind != service.OverlayPath || o.Style.Color != (color.RGBA{0, 255, 0, 255}) || o.Style.Weight != 5 {
+		t.Errorf("Expected green path of weight 5, got %+v", o)
+	}
+	if len(o.Points) != 2 || o.Points[0] != (geojson.Position{-0.12, 51.5}) || o.Points[1] != (geojson.Position{-0.1, 51.6}) {
+		t.Errorf("Expected 2 points in lng,lat, got %v", o.Points)
+	}
+
+	o, err = parseOverlay(service.OverlayMarker, "52.5,13.4")
+	if err != nil || o.Style != service.DefaultStaticMapStyle(service.OverlayMarker) || len(o.Points) != 1 {
+		t.Errorf("Expected marker of default style, got %+v %v", o, err)
+	}
+
+	o, err = parseOverlay(service.OverlayPolygon, "fill:#0000ff80|size:0|0,0|0,1|1,1")
+	if err != nil || o.Style.Fill != (color.RGBA{0, 0, 128, 128}) || o.Style.Weight != 0 || len(o.Points) != 3 {
+		t.Errorf("Expected polygon of half blue fill without stroke, got %+v %v", o, err)
+	}
+
+	for _, v := range []struct {
+		kind string
+		text string
+	}{
+		{service.OverlayPath, "color:nope|0,0"},
+		{service.OverlayPath, "weight:65|0,0"},
+		{service.OverlayPath, "weight:-1|0,0"},
+		{service.OverlayPath, "width:2|0,0"},
+		{service.OverlayPath, "0,0|91,0"},
+		{service.OverlayPath, "0,0|zero"},
+		{service.OverlayPolygon, "0,0|0,1"},
+	} {
+		if _, err := parseOverlay(v.kind, v.text); err == nil {
+			t.Errorf("Expected %v %q invalid", v.kind, v.text)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"crypto/sha1" //nolint:gosec // etag only
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-gis/internal/geo/tile"
+	"go-gis/internal/service"
+	xlog "go-gis/internal/util/utillog"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// MimeVectorTile mapbox vector tile
+const MimeVectorTile = "application/vnd.mapbox-vector-tile"
+
+// TileController controller
+type TileController struct {
+	appService service.AppService
+	webCtxt    echo.Context
+	Debug      bool
+}
+
+// NewTileController new controller
+func NewTileController(appService service.AppService, c echo.Context) *TileController {
+
+	appConfig := appService.Config()
+	return &TileController{
+		Debug:      appConfig.Debug,
+		appService: appService,
+		webCtxt:    c,
+	}
+}
+
+// VectorTile layer tile as MVT, path /:layer/:z/:x/:y.pbf
+func (x *TileController) VectorTile() error {
+
+	c := x.webCtxt
+
+	if ext := strings.TrimLeft(c.Param("y"), "0123456789"); ext != ".pbf" && ext != ".mvt" && ext != "" {
+		return c.NoContent(http.StatusNotFound)
+	}
+
+	t, err := tile.Parse(c.Param("z"), c.Param("x"), c.Param("y"))
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	layer := c.Param("layer")
+	if x.appService.Config().Layer(layer) == nil {
+		return c.NoContent(http.StatusNotFound)
+	}
+
+	data, err := x.appService.Tile().VectorTile(layer, t)
+	if errors.Is(err, service.ErrInvalidArgument) {
+		return c.NoContent(http.StatusNotFound)
+	}
+	if err != nil {
+		xlog.Error("tile service error: %v", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return writeCached(c, MimeVectorTile, data, x.appService.Config().Tiles.MaxAge)
+}
+
+// RasterTile upstream raster tile through cache, path /:source/:z/:x/:y.png
+func (x *TileController) RasterTile() error {
+
+	c := x.webCtxt
+
+	proxy := x.appService.TileProxy()
+	if proxy == nil {
+		return c.NoContent(http.StatusNotFound)
+	}
+
+	source := c.Param("source")
+	if !proxy.HasSource(source) {
+		return c.NoContent(http.StatusNotFound)
+	}
+
+	t, err := tile.Parse(c.Param("z"), c.Param("x"), c.Param("y"))
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	res, err := proxy.RasterTile(source, t)
+	if errors.Is(err, service.ErrInvalidArgument) {
+		return c.NoContent(http.StatusNotFound)
+	}
+	if err != nil {
+		xlog.Error("tile proxy error: %v", err)
+		return c.NoContent(http.StatusBadGateway)
+	}
+
+	c.Response().Header().Set("X-Cache", res.Cache)
+
+	return writeCached(c, res.ContentType, res.Data, x.appService.Config().TileProxy.MaxAge)
+}
+
+// writeCached write data with ETag and Cache-Control, 304 if client has same ETag, 204 if data is empty
+func writeCached(c echo.Context, contentType string, data []byte, maxAge int) error {
+
+	sum := sha1.Sum(data) //nolint:gosec // etag only
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+
+	h := c.Response().Header()
+	h.Set(echo.HeaderCacheControl, fmt.Sprintf("public, max-age=%d", maxAge))
+	h.Set("ETag", etag)
+
+	if match := c.Request().Header.Get("If-None-Match"); match != "" {
+		for _, v := range strings.Split(match, ",") {
+			v = strings.TrimSpace(v)
+			if v == etag || v == "W/"+etag || v == "*" {
+				return c.NoContent(http.StatusNotModified)
+			}
+		}
+	}
+
+	if len(data) == 0 {
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	return c.Blob(http.StatusOK, contentType, data)
+}
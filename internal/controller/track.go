@@ -0,0 +1,453 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-gis/internal/geo/geojson"
+	"go-gis/internal/geo/gpx"
+	"go-gis/internal/geo/kml"
+	"go-gis/internal/geo/track"
+	"go-gis/internal/service"
+	xlog "go-gis/internal/util/utillog"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// track file formats
+const (
+	FormatGPX     = "gpx"
+	FormatKML     = "kml"
+	FormatGeoJSON = "geojson"
+)
+
+type trackUploadDTO struct {
+	Format string `query:"format"` // gpx, kml or geojson, default from Content-Type or content
+}
+
+func (x trackUploadDTO) validate() bool {
+	return x.Format == "" || x.Format == FormatGPX || x.Format == FormatKML || x.Format == FormatGeoJSON
+}
+
+// maxTrackTolerance max simplify tolerance, meters
+const maxTrackTolerance = 10000
+
+type trackProcessDTO struct {
+	MaxSpeed  float64 `query:"max_speed"` // m/s
+	Smooth    bool    `query:"smooth"`
+	Accuracy  float64 `query:"accuracy"`  // m
+	Noise     float64 `query:"noise"`     // m/s
+	Tolerance float64 `query:"tolerance"` // m
+}
+
+func (x trackProcessDTO) validate() bool {
+	return x.MaxSpeed >= 0 && x.Accuracy >= 0 && x.Noise >= 0 && x.Tolerance >= 0 && x.Tolerance <= maxTrackTolerance
+}
+
+func (x trackProcessDTO) options() track.Options {
+	return track.Options{MaxSpeed: x.MaxSpeed, Smooth: x.Smooth, Accuracy: x.Accuracy, Noise: x.Noise, Tolerance: x.Tolerance}
+}
+
+type trackExportDTO struct {
+	Format string `query:"format"` // gpx, kml or geojson, default from Accept
+}
+
+func (x trackExportDTO) validate() bool {
+	return x.Format == "" || x.Format == FormatGPX || x.Format == FormatKML || x.Format == FormatGeoJSON
+}
+
+// trackWriter FeatureWriter of export format
+type trackWriter interface {
+	service.FeatureWriter
+	Close() error
+}
+
+// TrackController controller
+type TrackController struct {
+	appService service.AppService
+	webCtxt    echo.Context
+	Debug      bool
+}
+
+// NewTrackController new controller
+func NewTrackController(appService service.AppService, c echo.Context) *TrackController {
+
+	appConfig := appService.Config()
+	return &TrackController{
+		Debug:      appConfig.Debug,
+		appService: appService,
+		webCtxt:    c,
+	}
+}
+
+// Convert GPX or KML upload to GeoJSON FeatureCollection
+func (x *TrackController) Convert() error {
+
+	c := x.webCtxt
+
+	fc, err := x.decodeUpload()
+	if err != nil {
+		return err
+	}
+	if fc == nil {
+		return nil // response written
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, geojson.MediaType)
+
+	return c.JSON(http.StatusOK, fc)
+}
+
+// Process GPX, KML or GeoJSON upload by query options, result is GeoJSON FeatureCollection
+func (x *TrackController) Process() error {
+
+	c := x.webCtxt
+
+	opts, err := x.bindProcess(trackProcessDTO{})
+	if err != nil || opts == nil {
+		return err
+	}
+
+	fc, err := x.decodeUpload()
+	if err != nil {
+		return err
+	}
+	if fc == nil {
+		return nil // response written
+	}
+
+	// tracks without 2 points left are dropped
+	fc.Features = slices.DeleteFunc(fc.Features, func(f *geojson.Feature) bool {
+		return !track.ProcessFeature(f, *opts)
+	})
+
+	c.Response().Header().Set(echo.HeaderContentType, geojson.MediaType)
+
+	return c.JSON(http.StatusOK, fc)
+}
+
+// bindProcess query options over defaults, nil result if error response is written
+func (x *TrackController) bindProcess(dto trackProcessDTO) (*track.Options, error) {
+
+	c := x.webCtxt
+	err := (&echo.DefaultBinder{}).BindQueryParams(c, &dto) // params not in query keep defaults
+	if err != nil {
+		return nil, err
+	}
+
+	if !dto.validate() {
+		return nil, c.NoContent(http.StatusBadRequest)
+	}
+
+	res := dto.options()
+
+	return &res, nil
+}
+
+// Import GPX, KML or GeoJSON upload of sys api to tracks layer, processed by config tracks.process and query options
+func (x *TrackController) Import() error {
+
+	c := x.webCtxt
+
+	defaults := x.appService.Config().Tracks.Process
+	opts, err := x.bindProcess(trackProcessDTO{
+		MaxSpeed:  defaults.MaxSpeed,
+		Smooth:    defaults.Smooth,
+		Accuracy:  defaults.Accuracy,
+		Noise:     defaults.Noise,
+		Tolerance: defaults.Tolerance,
+	})
+	if err != nil || opts == nil {
+		return err
+	}
+
+	fc, err := x.decodeUpload()
+	if err != nil {
+		return err
+	}
+	if fc == nil {
+		return nil // response written
+	}
+
+	res := importResultDTO{}
+
+	reject := func(rej service.ImportReject) {
+		res.Rejected++
+		if len(res.Rejects) < maxImportRejects {
+			res.Rejects = append(res.Rejects, rej)
+		}
+	}
+
+	// tracks without 2 points left are rejected, index of kept feature in upload
+	features := make([]*geojson.Feature, 0, len(fc.Features))
+	indices := make([]int, 0, len(fc.Features))
+
+	for i, f := range fc.Features {
+		if !track.ProcessFeature(f, *opts) {
+			reject(service.ImportReject{Index: i, ID: f.ID, Reason: "less than 2 points after processing"})
+			continue
+		}
+		features = append(features, f)
+		indices = append(indices, i)
+	}
+
+	imported, err := x.appService.Feature().Import(x.appService.Config().Tracks.Layer, service.NewSliceFeatureReader(features), service.ImportOptions{
+		BatchSize: 100,
+		OnReject: func(rej service.ImportReject) {
+			if rej.Index >= 0 && rej.Index < len(indices) {
+				rej.Index = indices[rej.Index]
+			}
+			reject(rej)
+		},
+	})
+	if errors.Is(err, service.ErrInvalidArgument) {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		xlog.Error("feature service error: %v", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	res.Total, res.Imported = len(fc.Features), imported.Imported
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// decodeUpload body or multipart field "file", nil result if error response is written
+func (x *TrackController) decodeUpload() (*geojson.FeatureCollection, error) {
+
+	c := x.webCtxt
+	dto := &trackUploadDTO{}
+	err := (&echo.DefaultBinder{}).BindQueryParams(c, dto) // body is upload
+	if err != nil {
+		return nil, err
+	}
+
+	dto.Format = strings.ToLower(dto.Format)
+	if !dto.validate() {
+		return nil, c.NoContent(http.StatusBadRequest)
+	}
+
+	maxSize := int64(x.appService.Config().Tracks.MaxUploadSize) * 1024 * 1024
+	req := c.Request()
+	req.Body = http.MaxBytesReader(c.Response(), req.Body, maxSize)
+
+	var body io.Reader = req.Body
+	contentType := req.Header.Get(echo.HeaderContentType)
+
+	if strings.HasPrefix(contentType, echo.MIMEMultipartForm) {
+		file, err := c.FormFile("file")
+		if err != nil {
+			return nil, c.String(http.StatusBadRequest, err.Error())
+		}
+		src, err := file.Open()
+		if err != nil {
+			return nil, c.String(http.StatusBadRequest, err.Error())
+		}
+		defer func() { _ = src.Close() }()
+
+		body = src
+		contentType = file.Header.Get(echo.HeaderContentType)
+		if dto.Format == "" && strings.Contains(strings.ToLower(file.Filename), ".") {
+			ext := strings.ToLower(file.Filename[strings.LastIndex(file.Filename, ".")+1:])
+			if ext == FormatGPX || ext == FormatKML {
+				dto.Format = ext
+			}
+		}
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, c.String(http.StatusRequestEntityTooLarge, err.Error())
+	}
+
+	format := dto.Format
+	if format == "" {
+		format = sniffTrackFormat(contentType, data)
+	}
+
+	var res *geojson.FeatureCollection
+
+	switch format {
+	case FormatGPX:
+		res, err = gpx.Decode(bytes.NewReader(data))
+	case FormatKML:
+		res, err = kml.Decode(bytes.NewReader(data))
+	case FormatGeoJSON:
+		res, err = decodeGeoJSONTrack(data)
+	default:
+		return nil, c.String(http.StatusUnsupportedMediaType, "unknown format, use format=gpx, format=kml or format=geojson")
+	}
+
+	if err != nil {
+		return nil, c.String(http.StatusBadRequest, err.Error())
+	}
+
+	return res, nil
+}
+
+// sniffTrackFormat format by content type or root element
+func sniffTrackFormat(contentType string, data []byte) string {
+
+	switch {
+	case strings.Contains(contentType, "gpx"):
+		return FormatGPX
+	case strings.Contains(contentType, "kml"):
+		return FormatKML
+	case strings.Contains(contentType, "json"):
+		return FormatGeoJSON
+	}
+
+	head := data[:min(len(data), 1024)]
+
+	switch {
+	case bytes.Contains(head, []byte("<gpx")):
+		return FormatGPX
+	case bytes.Contains(head, []byte("<kml")):
+		return FormatKML
+	case bytes.HasPrefix(bytes.TrimSpace(head), []byte("{")):
+		return FormatGeoJSON
+	}
+
+	return ""
+}
+
+// decodeGeoJSONTrack FeatureCollection, Feature or geometry as collection
+func decodeGeoJSONTrack(data []byte) (*geojson.FeatureCollection, error) {
+
+	head := struct {
+		Type string `json:"type"`
+	}{}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return nil, err
+	}
+
+	res := geojson.NewFeatureCollection()
+
+	switch head.Type {
+	case geojson.TypeFeatureCollection:
+		if err := json.Unmarshal(data, res); err != nil {
+			return nil, err
+		}
+	case geojson.TypeFeature:
+		f := &geojson.Feature{}
+		if err := json.Unmarshal(data, f); err != nil {
+			return nil, err
+		}
+		res.Features = append(res.Features, f)
+	default:
+		g := &geojson.Geometry{}
+		if err := json.Unmarshal(data, g); err != nil {
+			return nil, err
+		}
+		res.Features = append(res.Features, geojson.NewFeature(g))
+	}
+
+	for i, f := range res.Features {
+		if f.Geometry == nil {
+			return nil, fmt.Errorf("feature %v has no geometry", i)
+		}
+		if err := f.Geometry.Validate(); err != nil {
+			return nil, fmt.Errorf("feature %v: %v", i, err)
+		}
+		if f.Properties == nil {
+			f.Properties = map[string]any{}
+		}
+	}
+
+	return res, nil
+}
+
+// Export tracks layer as GPX, KML or GeoJSON by format param or Accept header, at most tracks.max_export features
+func (x *TrackController) Export() error {
+
+	c := x.webCtxt
+	dto := &trackExportDTO{}
+	err := c.Bind(dto)
+	if err != nil {
+		return err
+	}
+
+	dto.Format = strings.ToLower(dto.Format)
+	if !dto.validate() {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	appConfig := x.appService.Config()
+
+	layer := appConfig.Tracks.Layer
+	if appConfig.Layer(layer) == nil {
+		return c.NoContent(http.StatusNotFound)
+	}
+
+	format := dto.Format
+	if format == "" {
+		format = acceptTrackFormat(c.Request().Header.Get(echo.HeaderAccept))
+	}
+
+	contentType := gpx.MediaType
+	switch format {
+	case FormatKML:
+		contentType = kml.MediaType
+	case FormatGeoJSON:
+		contentType = geojson.MediaType
+	default:
+		format = FormatGPX
+	}
+
+	resp := c.Response()
+	resp.Header().Set(echo.HeaderContentType, contentType)
+	resp.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", layer+"."+format))
+	resp.Header().Add(echo.HeaderVary, echo.HeaderAccept)
+	resp.WriteHeader(http.StatusOK)
+
+	var w trackWriter
+	switch format {
+	case FormatKML:
+		w = kml.NewWriter(resp)
+	case FormatGeoJSON:
+		w = geojson.NewWriter(resp)
+	default:
+		w = gpx.NewWriter(resp)
+	}
+
+	// body is streamed, errors can only be logged
+	count, err := x.appService.Feature().Export(layer, w, appConfig.Tracks.MaxExport)
+	if errors.Is(err, service.ErrInvalidArgument) {
+		xlog.Warn("track export: %v", err)
+	} else if err != nil {
+		xlog.Error("feature service error: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		xlog.Error("track export write error: %v", err)
+	}
+
+	if x.Debug {
+		xlog.Debug("track export: [layer: %v] [format: %v] [features: %v]", layer, format, count)
+	}
+
+	return nil
+}
+
+// acceptTrackFormat format of Accept header, gpx by default
+func acceptTrackFormat(accept string) string {
+
+	accept = strings.ToLower(accept)
+
+	switch {
+	case strings.Contains(accept, "gpx"):
+		return FormatGPX
+	case strings.Contains(accept, "kml"):
+		return FormatKML
+	case strings.Contains(accept, "json"):
+		return FormatGeoJSON
+	}
+
+	return FormatGPX
+}
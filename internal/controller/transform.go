@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-gis/internal/config/consts"
+	"go-gis/internal/geo/geojson"
+	"go-gis/internal/service"
+	xlog "go-gis/internal/util/utillog"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxTransformBody max size of posted geometry
+const maxTransformBody = 4 * 1024 * 1024
+
+type transformDTO struct {
+	From  int    `query:"from"`  // EPSG code
+	To    int    `query:"to"`    // EPSG code
+	Point string `query:"point"` // x,y in axis order lng,lat or easting,northing
+}
+
+func (x transformDTO) validate() bool {
+	return x.From > 0 && x.To > 0 && len(x.Point) <= consts.DefaultTextLength
+}
+
+// parseXY "x,y"
+func parseXY(text string) (geojson.Position, error) {
+
+	xs, ys, ok := strings.Cut(text, ",")
+	if !ok {
+		return geojson.Position{}, fmt.Errorf("invalid x,y: %q", text)
+	}
+
+	x, err := strconv.ParseFloat(strings.TrimSpace(xs), 64)
+	if err != nil {
+		return geojson.Position{}, fmt.Errorf("invalid x: %q", xs)
+	}
+
+	y, err := strconv.ParseFloat(strings.TrimSpace(ys), 64)
+	if err != nil {
+		return geojson.Position{}, fmt.Errorf("invalid y: %q", ys)
+	}
+
+	return geojson.Position{x, y}, nil
+}
+
+// TransformController controller
+type TransformController struct {
+	appService service.AppService
+	webCtxt    echo.Context
+	Debug      bool
+}
+
+// NewTransformController new controller
+func NewTransformController(appService service.AppService, c echo.Context) *TransformController {
+
+	appConfig := appService.Config()
+	return &TransformController{
+		Debug:      appConfig.Debug,
+		appService: appService,
+		webCtxt:    c,
+	}
+}
+
+// Transform point of query (GET) or geometry of body (POST), result is geometry
+func (x *TransformController) Transform() error {
+
+	c := x.webCtxt
+	dto := &transformDTO{}
+	err := (&echo.DefaultBinder{}).BindQueryParams(c, dto) // body is geometry
+	if err != nil {
+		return err
+	}
+
+	if !dto.validate() {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	var g *geojson.Geometry
+
+	if IsPOST(c) {
+		g = &geojson.Geometry{}
+		data, err := io.ReadAll(io.LimitReader(c.Request().Body, maxTransformBody+1))
+		if err != nil {
+			return err
+		}
+		if len(data) > maxTransformBody {
+			return c.NoContent(http.StatusRequestEntityTooLarge)
+		}
+		if err := json.Unmarshal(data, g); err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		if err := g.ValidateStructure(); err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+	} else {
+		p, err := parseXY(dto.Point)
+		if err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		g = geojson.NewPoint(p[0], p[1])
+	}
+
+	err = x.appService.Transform().Transform(dto.From, dto.To, g)
+	if errors.Is(err, service.ErrInvalidArgument) {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		xlog.Error("transform service error: %v", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, g)
+}
@@ -0,0 +1,314 @@
+// Package address free text address parsing and normalization
+package address
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Address components, empty if not found
+type Address struct {
+	Unit        string `json:"unit,omitempty"`
+	HouseNumber string `json:"house_number,omitempty"`
+	Street      string `json:"street,omitempty"`
+	City        string `json:"city,omitempty"`
+	Postcode    string `json:"postcode,omitempty"`
+	Country     string `json:"country,omitempty"`
+}
+
+// String components joined in postal order
+func (x Address) String() string {
+
+	street := strings.TrimSpace(x.HouseNumber + " " + x.Street)
+
+	var parts []string
+	for _, v := range []string{x.Unit, street, x.City, x.Postcode, x.Country} {
+		if v != "" {
+			parts = append(parts, v)
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// PostcodeRule postcode pattern, submatches are joined by space
+type PostcodeRule struct {
+	Country string `json:"country"` // key of Countries, implied country if not in text
+	Pattern string `json:"pattern"`
+}
+
+// Rules per language, file address.{lang}.json
+type Rules struct {
+	Abbreviations map[string]string `json:"abbreviations"` // lowercase word without dot: full form
+	StreetTypes   map[string]string `json:"street_types"`  // as Abbreviations, last word of street only
+	Units         []string          `json:"units"`         // lowercase unit designators: flat, apt, #
+	Countries     map[string]string `json:"countries"`     // lowercase name or code: full name
+	Postcodes     []PostcodeRule    `json:"postcodes"`     // first match wins
+	Lowercase     []string          `json:"lowercase"`     // words not capitalized inside names: of, upon
+}
+
+// Parser compiled rules
+type Parser struct {
+	rules     Rules
+	unit      *regexp.Regexp
+	postcodes []*regexp.Regexp
+	lowercase map[string]bool
+}
+
+var (
+	reSpaces       = regexp.MustCompile(`\s+`)
+	reNumberFirst  = regexp.MustCompile(`(?i)^(\d+[a-z]?(?:\s?[-/]\s?\d+[a-z]?)?)\b[\s,]*(.*)$`)
+	reNumberLast   = regexp.MustCompile(`(?i)^(.*\D)\s+(\d+[a-z]?(?:[-/]\d+[a-z]?)?)$`)
+	reNumberSingle = regexp.MustCompile(`(?i)^\d+[a-z]?(?:[-/]\d+[a-z]?)?$`)
+)
+
+// NewParser compile rules
+func NewParser(rules Rules) (*Parser, error) {
+
+	res := &Parser{rules: rules, lowercase: map[string]bool{}}
+
+	for _, v := range rules.Lowercase {
+		res.lowercase[strings.ToLower(v)] = true
+	}
+
+	if len(rules.Units) > 0 {
+		units := make([]string, len(rules.Units))
+		for i, v := range rules.Units {
+			units[i] = regexp.QuoteMeta(strings.ToLower(v))
+		}
+		// designator, optional dot and #, then identifier
+		res.unit = regexp.MustCompile(`(?i)^(` + strings.Join(units, "|") + `)(?:\.\s*|\s+)(?:no\.?\s*)?#?\s*([0-9a-z][0-9a-z-]*)\b[\s,]*(.*)$`)
+	}
+
+	for _, v := range rules.Postcodes {
+		re, err := regexp.Compile(`(?i)(?:^|[\s,])` + v.Pattern + `(?:$|[\s,])`)
+		if err != nil {
+			return nil, fmt.Errorf("error postcode pattern %q: %v", v.Pattern, err)
+		}
+		res.postcodes = append(res.postcodes, re)
+	}
+
+	return res, nil
+}
+
+// Parse split text into components, normalize abbreviations and casing
+func (x *Parser) Parse(text string) Address {
+
+	res := Address{}
+
+	text = strings.TrimSpace(reSpaces.ReplaceAllString(text, " "))
+
+	text, res.Postcode = x.cutPostcode(text, &res.Country)
+
+	parts := splitParts(text)
+
+	// country is the last part or its tail
+	if n := len(parts); n > 0 {
+		if country, rest, ok := x.cutCountry(parts[n-1]); ok {
+			res.Country = country
+			parts[n-1] = rest
+			if rest == "" {
+				parts = parts[:n-1]
+			}
+		}
+	}
+
+	// unit is a part or a prefix of the first part
+	for i, v := range parts {
+		if unit, rest, ok := x.cutUnit(v); ok {
+			res.Unit = unit
+			parts[i] = rest
+			break
+		}
+	}
+	parts = compact(parts)
+
+	// street is the first part with a house number
+	street := -1
+	for i, v := range parts {
+		if reNumberSingle.MatchString(v) && i+1 < len(parts) {
+			res.HouseNumber = strings.ToUpper(v)
+			parts[i] = ""
+			street = i + 1
+			break
+		}
+		if m := reNumberFirst.FindStringSubmatch(v); m != nil && m[2] != "" {
+			res.HouseNumber = strings.ToUpper(reSpaces.ReplaceAllString(m[1], ""))
+			parts[i] = m[2]
+			street = i
+			break
+		}
+		if m := reNumberLast.FindStringSubmatch(v); m != nil {
+			res.HouseNumber = strings.ToUpper(m[2])
+			parts[i] = m[1]
+			street = i
+			break
+		}
+	}
+
+	if street < 0 && len(parts) > 1 {
+		street = 0 // no number: "Downing Street, London"
+	}
+
+	if street >= 0 {
+		res.Street = x.normalize(parts[street], true)
+		parts[street] = ""
+	}
+
+	// city is the last one left, district and other parts are dropped
+	if parts = compact(parts); len(parts) > 0 {
+		res.City = x.normalize(parts[len(parts)-1], false)
+	}
+
+	return res
+}
+
+// cutPostcode first matching rule, implied country if found
+func (x *Parser) cutPostcode(text string, country *string) (string, string) {
+
+	for i, re := range x.postcodes {
+
+		loc := re.FindAllStringSubmatchIndex(text, -1)
+		if len(loc) == 0 || loc[len(loc)-1][0] == 0 {
+			continue // postcode is near the end, never first
+		}
+		m := loc[len(loc)-1]
+
+		var groups []string
+		for j := 2; j+1 < len(m); j += 2 {
+			if m[j] >= 0 && m[j+1] > m[j] {
+				groups = append(groups, text[m[j]:m[j+1]])
+			}
+		}
+		if len(groups) == 0 {
+			groups = append(groups, strings.Trim(text[m[0]:m[1]], " ,"))
+		}
+
+		postcode := strings.ToUpper(strings.Join(groups, " "))
+
+		if code := x.rules.Postcodes[i].Country; code != "" {
+			if name, ok := x.rules.Countries[strings.ToLower(code)]; ok {
+				*country = name
+			}
+		}
+
+		return text[:m[0]] + "," + text[m[1]:], postcode
+	}
+
+	return text, ""
+}
+
+// cutCountry whole part or its trailing words
+func (x *Parser) cutCountry(part string) (country string, rest string, ok bool) {
+
+	words := strings.Fields(part)
+
+	for i := range words {
+		key := strings.ToLower(strings.Join(words[i:], " "))
+		key = strings.ReplaceAll(key, ".", "")
+		if name, found := x.rules.Countries[key]; found {
+			return name, strings.Join(words[:i], " "), true
+		}
+	}
+
+	return "", part, false
+}
+
+// cutUnit unit designator with identifier at the start of part
+func (x *Parser) cutUnit(part string) (unit string, rest string, ok bool) {
+
+	if strings.HasPrefix(part, "#") {
+		part = "# " + strings.TrimPrefix(part, "#")
+	}
+
+	if x.unit == nil {
+		return "", part, false
+	}
+
+	m := x.unit.FindStringSubmatch(part)
+	if m == nil || (!hasDigit(m[2]) && len(m[2]) > 2) { // "Unit Lane" is a street
+		return "", part, false
+	}
+
+	return x.normalize(m[1], false) + " " + strings.ToUpper(m[2]), m[3], true
+}
+
+// normalize expand abbreviations, title case
+func (x *Parser) normalize(text string, street bool) string {
+
+	words := strings.Fields(strings.Trim(text, " ,"))
+
+	for i, v := range words {
+
+		key := strings.ToLower(strings.TrimSuffix(v, "."))
+
+		if full, ok := x.rules.StreetTypes[key]; ok && street && i == len(words)-1 {
+			words[i] = full
+			continue
+		}
+
+		if full, ok := x.rules.Abbreviations[key]; ok {
+			words[i] = full
+			continue
+		}
+
+		if i > 0 && x.lowercase[key] {
+			words[i] = key
+			continue
+		}
+
+		words[i] = x.titleWord(v)
+	}
+
+	return strings.Join(words, " ")
+}
+
+// titleWord capitalize word and its hyphen parts: stratford-upon-avon to Stratford-upon-Avon
+func (x *Parser) titleWord(word string) string {
+
+	parts := strings.Split(strings.ToLower(word), "-")
+
+	for i, v := range parts {
+		if v == "" || (i > 0 && x.lowercase[v]) {
+			continue
+		}
+		if hasDigit(v) {
+			parts[i] = strings.ToUpper(v)
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(v)
+		parts[i] = string(unicode.ToUpper(r)) + v[size:]
+	}
+
+	return strings.Join(parts, "-")
+}
+
+func hasDigit(text string) bool {
+	return strings.IndexFunc(text, unicode.IsDigit) >= 0
+}
+
+// splitParts by comma and semicolon, empty parts removed
+func splitParts(text string) []string {
+
+	parts := strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ';' })
+	for i, v := range parts {
+		parts[i] = strings.TrimSpace(v)
+	}
+
+	return compact(parts)
+}
+
+func compact(parts []string) []string {
+
+	res := parts[:0]
+	for _, v := range parts {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,77 @@
+package address
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+// Rules from config dir next to lang.en.json
+func mustParser(t *testing.T) *Parser {
+
+	data, err := os.ReadFile("../../../configs/go-gis/address.en.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rules := Rules{}
+	if err := json.Unmarshal(data, &rules); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := NewParser(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+// Test components and normalization of messy addresses
+func TestParse(t *testing.T) {
+
+	p := mustParser(t)
+
+	tests := []struct {
+		text     string
+		expected Address
+	}{
+		{
+			"Flat 3, 10 downing st., london SW1A2AA",
+			Address{Unit: "Flat 3", HouseNumber: "10", Street: "Downing Street", City: "London", Postcode: "SW1A 2AA", Country: "United Kingdom"},
+		},
+		{
+			"apt. 4b 221B BAKER ST, LONDON, nw1 6xe, uk",
+			Address{Unit: "Apartment 4B", HouseNumber: "221B", Street: "Baker Street", City: "London", Postcode: "NW1 6XE", Country: "United Kingdom"},
+		},
+		{
+			"12 st john's rd, stratford-upon-avon",
+			Address{HouseNumber: "12", Street: "Saint John's Road", City: "Stratford-upon-Avon"},
+		},
+		{
+			"#5, 100 queen street west, TORONTO M5H2N2",
+			Address{Unit: "# 5", HouseNumber: "100", Street: "Queen Street West", City: "Toronto", Postcode: "M5H 2N2", Country: "Canada"},
+		},
+		{
+			"unity lane, bristol, England",
+			Address{Street: "Unity Lane", City: "Bristol", Country: "United Kingdom"},
+		},
+	}
+
+	for _, v := range tests {
+		if res := p.Parse(v.text); res != v.expected {
+			t.Errorf("Parse(%q)\n got %+v\nwant %+v", v.text, res, v.expected)
+		}
+	}
+}
+
+// Test formatted text
+func TestString(t *testing.T) {
+
+	res := mustParser(t).Parse("Flat 3, 10 downing st., london SW1A2AA").String()
+	expected := "Flat 3, 10 Downing Street, London, SW1A 2AA, United Kingdom"
+
+	if res != expected {
+		t.Errorf("Expected %q, got %q", expected, res)
+	}
+}
@@ -0,0 +1,321 @@
+// Package cluster point clustering by zoom level in grids of web mercator cells,
+// index is updated incrementally by Set and Remove
+package cluster
+
+import (
+	"go-gis/internal/geo/geojson"
+	"go-gis/internal/geo/tile"
+	"iter"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+)
+
+// Point indexed point, ID is unique
+type Point struct {
+	ID         string
+	Lng        float64
+	Lat        float64
+	Properties map[string]any
+}
+
+// Cluster of Count points at mean position, Point is set if Count is 1
+type Cluster struct {
+	Lng   float64
+	Lat   float64
+	Count int
+	Point *Point
+}
+
+// Options of index
+type Options struct {
+	Radius  float64 // cluster cell size, pixels of 256 tile
+	MaxZoom int     // above points are not clustered
+}
+
+// cell sums of points, xor of slots is the slot of single point
+type cell struct {
+	count      int
+	sumX, sumY float64
+	xor        uint32
+}
+
+// grid cells of one zoom, size is cells per world width
+type grid struct {
+	size  float64
+	cells map[uint64]*cell
+}
+
+type entry struct {
+	point Point
+	x, y  float64 // world at zoom 0, 0..1
+}
+
+// leaves slots of points by cell of zoom MaxZoom+1
+type leaves struct {
+	grid
+	slots map[uint64][]uint32
+}
+
+// Index of points, safe for concurrent use
+type Index struct {
+	opts Options
+
+	mu     sync.RWMutex
+	grids  []grid // by zoom 0..MaxZoom
+	leaves leaves // above MaxZoom
+	points []entry
+	free   []uint32
+	slots  map[string]uint32 // by point id
+}
+
+// NewIndex empty index
+func NewIndex(opts Options) *Index {
+
+	opts.Radius = max(opts.Radius, 1)
+	opts.MaxZoom = min(max(opts.MaxZoom, 0), tile.MaxZoom-1)
+
+	res := &Index{opts: opts, slots: map[string]uint32{}}
+
+	size := func(z int) float64 {
+		return math.Ceil(256 / opts.Radius * float64(uint64(1)<<z))
+	}
+
+	for z := 0; z <= opts.MaxZoom; z++ {
+		res.grids = append(res.grids, grid{size: size(z), cells: map[uint64]*cell{}})
+	}
+
+	res.leaves = leaves{grid: grid{size: size(opts.MaxZoom + 1)}, slots: map[uint64][]uint32{}}
+
+	return res
+}
+
+// Len count of points
+func (x *Index) Len() int {
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	return len(x.slots)
+}
+
+// Set add or update point
+func (x *Index) Set(p Point) {
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.set(p)
+}
+
+// Remove point by id, false if not exists
+func (x *Index) Remove(id string) bool {
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	return x.remove(id)
+}
+
+// Sync set points and remove points not in list, returns count of removed
+func (x *Index) Sync(points []Point) int {
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	ids := make(map[string]bool, len(points))
+	for _, p := range points {
+		x.set(p)
+		ids[p.ID] = true
+	}
+
+	removed := 0
+	for id := range x.slots {
+		if !ids[id] && x.remove(id) {
+			removed++
+		}
+	}
+
+	return removed
+}
+
+func (x *Index) set(p Point) {
+
+	px, py := tile.LngLatToPixel(p.Lng, p.Lat, 0, 1)
+
+	if slot, ok := x.slots[p.ID]; ok {
+		e := &x.points[slot]
+		if e.x == px && e.y == py {
+			e.point = p // same cells
+			return
+		}
+		x.remove(p.ID)
+	}
+
+	var slot uint32
+	if n := len(x.free); n > 0 {
+		slot = x.free[n-1]
+		x.free = x.free[:n-1]
+		x.points[slot] = entry{point: p, x: px, y: py}
+	} else {
+		slot = uint32(len(x.points))
+		x.points = append(x.points, entry{point: p, x: px, y: py})
+	}
+
+	x.slots[p.ID] = slot
+
+	for i := range x.grids {
+		g := &x.grids[i]
+		k := g.key(px, py)
+		c := g.cells[k]
+		if c == nil {
+			c = &cell{}
+			g.cells[k] = c
+		}
+		c.count++
+		c.sumX += px
+		c.sumY += py
+		c.xor ^= slot
+	}
+
+	k := x.leaves.key(px, py)
+	x.leaves.slots[k] = append(x.leaves.slots[k], slot)
+}
+
+func (x *Index) remove(id string) bool {
+
+	slot, ok := x.slots[id]
+	if !ok {
+		return false
+	}
+
+	e := &x.points[slot]
+
+	for i := range x.grids {
+		g := &x.grids[i]
+		k := g.key(e.x, e.y)
+		c := g.cells[k]
+		c.count--
+		if c.count == 0 {
+			delete(g.cells, k)
+			continue
+		}
+		c.sumX -= e.x
+		c.sumY -= e.y
+		c.xor ^= slot
+	}
+
+	k := x.leaves.key(e.x, e.y)
+	leaf := x.leaves.slots[k]
+	if i := slices.Index(leaf, slot); i >= 0 {
+		leaf[i] = leaf[len(leaf)-1]
+		leaf = leaf[:len(leaf)-1]
+	}
+	if len(leaf) == 0 {
+		delete(x.leaves.slots, k)
+	} else {
+		x.leaves.slots[k] = leaf
+	}
+
+	*e = entry{}
+	delete(x.slots, id)
+	x.free = append(x.free, slot)
+
+	return true
+}
+
+// Query clusters of cells intersecting bbox at zoom, single points above MaxZoom
+func (x *Index) Query(bbox geojson.BBox, zoom int) []Cluster {
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	z := min(max(zoom, 0), x.opts.MaxZoom+1)
+
+	if bbox.CrossesAntimeridian() {
+		res := x.query(geojson.BBox{bbox[0], bbox[1], 180, bbox[3]}, z)
+		return append(res, x.query(geojson.BBox{-180, bbox[1], bbox[2], bbox[3]}, z)...)
+	}
+
+	return x.query(bbox, z)
+}
+
+func (x *Index) query(bbox geojson.BBox, z int) []Cluster {
+
+	res := []Cluster{}
+
+	if z > x.opts.MaxZoom {
+		x.leaves.each(bbox, maps.Keys(x.leaves.slots), len(x.leaves.slots), func(k uint64) {
+			for _, slot := range x.leaves.slots[k] {
+				res = append(res, x.single(slot))
+			}
+		})
+		return res
+	}
+
+	g := &x.grids[z]
+
+	g.each(bbox, maps.Keys(g.cells), len(g.cells), func(k uint64) {
+		c := g.cells[k]
+		if c == nil {
+			return
+		}
+		if c.count == 1 {
+			res = append(res, x.single(c.xor))
+			return
+		}
+		lng, lat := tile.PixelToLngLat(c.sumX/float64(c.count), c.sumY/float64(c.count), 0, 1)
+		res = append(res, Cluster{Lng: lng, Lat: lat, Count: c.count})
+	})
+
+	return res
+}
+
+func (x *Index) single(slot uint32) Cluster {
+
+	p := x.points[slot].point
+
+	return Cluster{Lng: p.Lng, Lat: p.Lat, Count: 1, Point: &p}
+}
+
+// each key of cells intersecting bbox, range keys are not checked for existence in n keys
+func (x *grid) each(bbox geojson.BBox, keys iter.Seq[uint64], n int, fn func(k uint64)) {
+
+	x0, y0 := tile.LngLatToPixel(bbox[0], bbox[3], 0, 1)
+	x1, y1 := tile.LngLatToPixel(bbox[2], bbox[1], 0, 1)
+	minX, minY := x.cell(x0, y0)
+	maxX, maxY := x.cell(x1, y1)
+
+	if (maxX-minX+1)*(maxY-minY+1) <= uint64(n) {
+		for cy := minY; cy <= maxY; cy++ {
+			for cx := minX; cx <= maxX; cx++ {
+				fn(cx<<32 | cy)
+			}
+		}
+		return
+	}
+
+	// bbox has more cells than map, scan keys
+	for k := range keys {
+		if cx, cy := k>>32, k&math.MaxUint32; cx >= minX && cx <= maxX && cy >= minY && cy <= maxY {
+			fn(k)
+		}
+	}
+}
+
+func (x *grid) cell(px, py float64) (uint64, uint64) {
+
+	clamp := func(v float64) uint64 {
+		return uint64(min(max(math.Floor(v*x.size), 0), x.size-1))
+	}
+
+	return clamp(px), clamp(py)
+}
+
+func (x *grid) key(px, py float64) uint64 {
+
+	cx, cy := x.cell(px, py)
+
+	return cx<<32 | cy
+}
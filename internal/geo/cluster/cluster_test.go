@@ -0,0 +1,90 @@
+package cluster
+
+import (
+	"fmt"
+	"go-gis/internal/geo/geojson"
+	"math/rand/v2"
+	"testing"
+)
+
+var world = geojson.BBox{-180, -85, 180, 85}
+
+func count(clusters []Cluster) int {
+	res := 0
+	for _, c := range clusters {
+		res += c.Count
+	}
+	return res
+}
+
+// Test close points cluster at low zoom and split at high zoom
+func TestQuery(t *testing.T) {
+
+	x := NewIndex(Options{Radius: 60, MaxZoom: 16})
+
+	x.Set(Point{ID: "a", Lng: 10, Lat: 50})
+	x.Set(Point{ID: "b", Lng: 10.001, Lat: 50.001})
+	x.Set(Point{ID: "c", Lng: -70, Lat: -30})
+
+	res := x.Query(world, 2)
+	if len(res) != 2 || count(res) != 3 {
+		t.Fatalf("Expected 2 clusters of 3 points, got %+v", res)
+	}
+
+	for _, c := range res {
+		if c.Count == 1 && (c.Point == nil || c.Point.ID != "c") {
+			t.Errorf("Expected single point c, got %+v", c)
+		}
+		if c.Count == 2 && (c.Point != nil || c.Lng < 10 || c.Lng > 10.001) {
+			t.Errorf("Expected cluster between a and b, got %+v", c)
+		}
+	}
+
+	res = x.Query(geojson.BBox{9, 49, 11, 51}, 17)
+	if len(res) != 2 || res[0].Point == nil || res[1].Point == nil {
+		t.Errorf("Expected points a and b above max zoom, got %+v", res)
+	}
+}
+
+// Test moving and removing points updates counts
+func TestUpdate(t *testing.T) {
+
+	x := NewIndex(Options{Radius: 60, MaxZoom: 10})
+
+	for i := range 100 {
+		x.Set(Point{ID: fmt.Sprint(i), Lng: rand.Float64()*360 - 180, Lat: rand.Float64()*170 - 85})
+	}
+
+	for i := range 50 {
+		x.Remove(fmt.Sprint(i))
+	}
+	x.Set(Point{ID: "60", Lng: 1, Lat: 1}) // move
+
+	for z := range 12 {
+		if n := count(x.Query(world, z)); n != 50 {
+			t.Fatalf("Expected 50 points at zoom %v, got %v", z, n)
+		}
+	}
+
+	removed := x.Sync([]Point{{ID: "60", Lng: 2, Lat: 2}, {ID: "new", Lng: 3, Lat: 3}})
+	if removed != 49 || x.Len() != 2 {
+		t.Errorf("Expected 49 removed and 2 points, got %v %v", removed, x.Len())
+	}
+
+	res := x.Query(geojson.BBox{170, -10, -170, 10}, 0) // across antimeridian
+	if count(res) != 0 {
+		t.Errorf("Expected no points near antimeridian, got %+v", res)
+	}
+}
+
+func BenchmarkQuery(b *testing.B) {
+
+	x := NewIndex(Options{Radius: 60, MaxZoom: 16})
+	for i := range 50000 {
+		x.Set(Point{ID: fmt.Sprint(i), Lng: rand.Float64()*20 - 10, Lat: rand.Float64()*20 + 40})
+	}
+
+	for b.Loop() {
+		x.Query(geojson.BBox{-5, 45, 5, 55}, 8)
+	}
+}
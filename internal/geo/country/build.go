@@ -0,0 +1,93 @@
+package country
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"go-gis/internal/geo/geojson"
+	"go-gis/internal/geo/geom"
+	"io"
+	"math"
+	"strings"
+)
+
+// codes of Natural Earth attributes in order of preference, "-99" is no code
+var (
+	countryCodeKeys     = []string{"ISO_A2_EH", "ISO_A2", "WB_A2"}
+	countryNameKeys     = []string{"NAME", "ADMIN"}
+	subdivisionCodeKeys = []string{"ISO_3166_2"}
+	subdivisionNameKeys = []string{"NAME", "NAME_EN"}
+)
+
+// property value by case-insensitive key, shapefile and geojson dumps differ in case
+func property(props map[string]any, keys []string) string {
+
+	for _, key := range keys {
+		for k, v := range props {
+			if !strings.EqualFold(k, key) {
+				continue
+			}
+			if s, ok := v.(string); ok && s != "" && s != "-99" && !strings.HasSuffix(s, "~") {
+				return strings.TrimSpace(s)
+			}
+		}
+	}
+
+	return ""
+}
+
+// FromNaturalEarth boundary of admin 0 or admin 1 feature, simplified by tolerance in degrees,
+// ok=false if feature has no ISO code or areal geometry
+func FromNaturalEarth(f *geojson.Feature, level int, tolerance float64) (*geojson.Feature, bool) {
+
+	if f.Geometry == nil || (f.Geometry.Type != geojson.TypePolygon && f.Geometry.Type != geojson.TypeMultiPolygon) {
+		return nil, false
+	}
+
+	codeKeys, nameKeys := countryCodeKeys, countryNameKeys
+	if level == LevelSubdivision {
+		codeKeys, nameKeys = subdivisionCodeKeys, subdivisionNameKeys
+	}
+
+	code := strings.ToUpper(property(f.Properties, codeKeys))
+	if code == "" || (level == LevelCountry && len(code) != 2) || (level == LevelSubdivision && !strings.Contains(code, "-")) {
+		return nil, false
+	}
+
+	g := f.Geometry
+	if tolerance > 0 {
+		g = geom.Simplify(g, tolerance, geom.MethodDouglasPeucker)
+	}
+	g.Positions(func(p *geojson.Position) {
+		*p = geojson.Position{round(p[0]), round(p[1])}
+	})
+
+	res := geojson.NewFeature(g)
+	res.Properties["code"] = code
+	res.Properties["name"] = property(f.Properties, nameKeys)
+	res.Properties["level"] = level
+
+	return res, true
+}
+
+// round 5 digits, about 1 m
+func round(v float64) float64 {
+	return math.Round(v*1e5) / 1e5
+}
+
+// Write gzip FeatureCollection of boundaries, input of Read
+func Write(w io.Writer, features []*geojson.Feature) error {
+
+	fc := geojson.NewFeatureCollection()
+	fc.Features = features
+
+	zw, err := gzip.NewWriterLevel(w, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+
+	if err := json.NewEncoder(zw).Encode(fc); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
@@ -0,0 +1,235 @@
+// Package country ISO 3166 country and subdivision of point by embedded boundaries
+package country
+
+import (
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"go-gis/internal/geo/geojson"
+	"io"
+	"math"
+	"strings"
+)
+
+// boundaries.geojson.gz is made by "go-gis boundaries build" of Natural Earth admin 0 and admin 1,
+// features have properties "code", "name" and "level"
+//
+//go:embed boundaries.geojson.gz
+var embedded []byte
+
+// levels of regions
+const (
+	LevelCountry     = 0 // ISO 3166-1 alpha-2
+	LevelSubdivision = 1 // ISO 3166-2
+)
+
+// Region country or subdivision
+type Region struct {
+	Code  string
+	Name  string
+	Level int
+}
+
+// Result of lookup, nil if point is not in any region of level
+type Result struct {
+	Country     *Region
+	Subdivision *Region
+}
+
+type polygon struct {
+	region *Region
+	bbox   geojson.BBox
+	rings  [][]geojson.Position
+}
+
+// Index regions in grid of 1 degree cells
+type Index struct {
+	regions map[string]*Region // by code
+	cells   [gridWidth * gridHeight][]int32
+	items   []polygon
+}
+
+const (
+	gridWidth  = 360
+	gridHeight = 180
+)
+
+// Embedded index of boundaries in binary
+func Embedded() (*Index, error) {
+	return Read(bytes.NewReader(embedded))
+}
+
+// Read FeatureCollection, gzip or plain
+func Read(r io.Reader) (*Index, error) {
+
+	br := &bytes.Buffer{}
+	if _, err := io.Copy(br, r); err != nil {
+		return nil, err
+	}
+
+	var data io.Reader = br
+
+	if b := br.Bytes(); len(b) > 2 && b[0] == 0x1f && b[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("error on boundaries: %v", err)
+		}
+		defer func() { _ = zr.Close() }()
+		data = zr
+	}
+
+	fc := &geojson.FeatureCollection{}
+	if err := json.NewDecoder(data).Decode(fc); err != nil {
+		return nil, fmt.Errorf("error on boundaries: %v", err)
+	}
+
+	return NewIndex(fc.Features)
+}
+
+// NewIndex features with "code", "name" and "level" properties, Polygon or MultiPolygon
+func NewIndex(features []*geojson.Feature) (*Index, error) {
+
+	res := &Index{regions: map[string]*Region{}}
+
+	for i, f := range features {
+
+		code, _ := f.Properties["code"].(string)
+		name, _ := f.Properties["name"].(string)
+		level := 0
+		switch v := f.Properties["level"].(type) {
+		case float64: // decoded json
+			level = int(v)
+		case int:
+			level = v
+		}
+
+		if code == "" || f.Geometry == nil {
+			return nil, fmt.Errorf("error boundary %v has no code or geometry", i)
+		}
+
+		region := res.regions[code]
+		if region == nil {
+			region = &Region{Code: code, Name: name, Level: level}
+			res.regions[code] = region
+		}
+
+		switch f.Geometry.Type {
+		case geojson.TypePolygon:
+			res.add(region, f.Geometry.Polygon)
+		case geojson.TypeMultiPolygon:
+			for _, p := range f.Geometry.MultiPolygon {
+				res.add(region, p)
+			}
+		default:
+			return nil, fmt.Errorf("error boundary %v is %v", code, f.Geometry.Type)
+		}
+	}
+
+	return res, nil
+}
+
+func (x *Index) add(region *Region, rings [][]geojson.Position) {
+
+	if len(rings) == 0 || len(rings[0]) < 4 {
+		return
+	}
+
+	bbox := geojson.BBox{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, p := range rings[0] {
+		bbox.Extend(p)
+	}
+
+	index := int32(len(x.items))
+	x.items = append(x.items, polygon{region: region, bbox: bbox, rings: rings})
+
+	minX, minY := cell(bbox[0], bbox[1])
+	maxX, maxY := cell(bbox[2], bbox[3])
+
+	for cy := minY; cy <= maxY; cy++ {
+		for cx := minX; cx <= maxX; cx++ {
+			k := cy*gridWidth + cx
+			x.cells[k] = append(x.cells[k], index)
+		}
+	}
+}
+
+// cell grid column and row of position
+func cell(lng, lat float64) (int, int) {
+
+	cx := int(math.Floor(lng + 180))
+	cy := int(math.Floor(lat + 90))
+
+	return min(max(cx, 0), gridWidth-1), min(max(cy, 0), gridHeight-1)
+}
+
+// Region by code, nil if not exists
+func (x *Index) Region(code string) *Region {
+	return x.regions[strings.ToUpper(code)]
+}
+
+// Len count of regions
+func (x *Index) Len() int {
+	return len(x.regions)
+}
+
+// Lookup regions containing point, country of subdivision code if country boundary misses point
+func (x *Index) Lookup(lng, lat float64) Result {
+
+	res := Result{}
+
+	p := geojson.Position{lng, lat}
+	cx, cy := cell(lng, lat)
+
+	for _, i := range x.cells[cy*gridWidth+cx] {
+
+		item := &x.items[i]
+
+		if item.region.Level == LevelCountry && res.Country != nil {
+			continue
+		}
+		if item.region.Level == LevelSubdivision && res.Subdivision != nil {
+			continue
+		}
+
+		if !item.bbox.Contains(p) || !inPolygon(p, item.rings) {
+			continue
+		}
+
+		if item.region.Level == LevelCountry {
+			res.Country = item.region
+		} else {
+			res.Subdivision = item.region
+		}
+
+		if res.Country != nil && res.Subdivision != nil {
+			break
+		}
+	}
+
+	if res.Country == nil && res.Subdivision != nil {
+		if code, _, ok := strings.Cut(res.Subdivision.Code, "-"); ok {
+			res.Country = x.regions[code]
+		}
+	}
+
+	return res
+}
+
+// inPolygon even-odd rule over outer ring and holes
+func inPolygon(p geojson.Position, rings [][]geojson.Position) bool {
+
+	inside := false
+
+	for _, ring := range rings {
+		for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+			a, b := ring[i], ring[j]
+			if (a[1] > p[1]) != (b[1] > p[1]) && p[0] < (b[0]-a[0])*(p[1]-a[1])/(b[1]-a[1])+a[0] {
+				inside = !inside
+			}
+		}
+	}
+
+	return inside
+}
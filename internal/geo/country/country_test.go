@@ -0,0 +1,153 @@
+package country
+
+import (
+	"bytes"
+	"go-gis/internal/geo/geojson"
+	"testing"
+)
+
+// Test embedded boundaries load and resolve points
+func TestEmbedded(t *testing.T) {
+
+	x, err := Embedded()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		lng, lat    float64
+		country     string
+		subdivision string
+	}{
+		{"Denver", -104.99, 39.74, "US", "US-CO"},
+		{"Paris", 2.35, 48.86, "FR", "FR-75"},
+		{"Oslo", 10.75, 59.91, "NO", "NO-03"},
+		{"Berlin", 13.40, 52.52, "DE", "DE-BE"},
+		{"London", -0.13, 51.51, "GB", ""},
+		{"Tokyo", 139.69, 35.69, "JP", "JP-13"},
+		{"Sydney", 151.21, -33.87, "AU", "AU-NSW"},
+		{"Sao Paulo", -46.63, -23.55, "BR", "BR-SP"},
+		{"Nairobi", 36.82, -1.29, "KE", ""},
+		{"Mumbai", 72.88, 19.08, "IN", "IN-MH"},
+		{"Toronto", -79.38, 43.65, "CA", "CA-ON"},
+		{"Atlantic", -40, 30, "", ""},
+	}
+
+	for _, v := range tests {
+		res := x.Lookup(v.lng, v.lat)
+		country, subdivision := "", ""
+		if res.Country != nil {
+			country = res.Country.Code
+		}
+		if res.Subdivision != nil {
+			subdivision = res.Subdivision.Code
+		}
+		if country != v.country || (v.subdivision != "" && subdivision != v.subdivision) {
+			t.Errorf("%v expected %q %q, got %q %q", v.name, v.country, v.subdivision, country, subdivision)
+		}
+	}
+
+	if x.Len() < 4000 {
+		t.Errorf("Expected admin 0 and admin 1 boundaries, got %v", x.Len())
+	}
+}
+
+// Test holes, multipolygons and country of subdivision code
+func TestLookup(t *testing.T) {
+
+	square := func(x0, y0, x1, y1 float64) []geojson.Position {
+		return []geojson.Position{{x0, y0}, {x1, y0}, {x1, y1}, {x0, y1}, {x0, y0}}
+	}
+
+	feature := func(code string, level int, g *geojson.Geometry) *geojson.Feature {
+		f := geojson.NewFeature(g)
+		f.Properties["code"] = code
+		f.Properties["level"] = float64(level)
+		return f
+	}
+
+	outer := geojson.NewPolygon([][]geojson.Position{square(0, 0, 10, 10), square(4, 4, 6, 6)})
+	inner := geojson.NewPolygon([][]geojson.Position{square(4, 4, 6, 6)})
+	islands := &geojson.Geometry{Type: geojson.TypeMultiPolygon, MultiPolygon: [][][]geojson.Position{{square(20, 0, 21, 1)}, {square(-179.5, -10, -179, -9)}}}
+
+	x, err := NewIndex([]*geojson.Feature{
+		feature("AA", LevelCountry, outer),
+		feature("BB", LevelCountry, inner),
+		feature("AA-01", LevelSubdivision, islands),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		lng, lat    float64
+		country     string
+		subdivision string
+	}{
+		{1, 1, "AA", ""},
+		{5, 5, "BB", ""},
+		{20.5, 0.5, "AA", "AA-01"},
+		{-179.2, -9.5, "AA", "AA-01"},
+		{15, 5, "", ""},
+	}
+
+	for _, v := range tests {
+		res := x.Lookup(v.lng, v.lat)
+		country, subdivision := "", ""
+		if res.Country != nil {
+			country = res.Country.Code
+		}
+		if res.Subdivision != nil {
+			subdivision = res.Subdivision.Code
+		}
+		if country != v.country || subdivision != v.subdivision {
+			t.Errorf("Lookup(%v, %v) expected %q %q, got %q %q", v.lng, v.lat, v.country, v.subdivision, country, subdivision)
+		}
+	}
+}
+
+// Test Natural Earth attributes and Write, Read round trip
+func TestBuild(t *testing.T) {
+
+	ne := geojson.NewFeature(geojson.NewPolygon([][]geojson.Position{{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}}))
+	ne.Properties["ISO_A2"] = "-99"
+	ne.Properties["ISO_A2_EH"] = "FR"
+	ne.Properties["NAME"] = "France"
+
+	f, ok := FromNaturalEarth(ne, LevelCountry, 0.01)
+	if !ok || f.Properties["code"] != "FR" || f.Properties["name"] != "France" {
+		t.Fatalf("Unexpected feature %+v", f)
+	}
+
+	ne.Properties["ISO_A2_EH"] = "-99"
+	if _, ok := FromNaturalEarth(ne, LevelCountry, 0); ok {
+		t.Error("Expected feature without code to be skipped")
+	}
+
+	buf := &bytes.Buffer{}
+	if err := Write(buf, []*geojson.Feature{f}); err != nil {
+		t.Fatal(err)
+	}
+
+	x, err := Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res := x.Lookup(0.5, 0.5); res.Country == nil || res.Country.Name != "France" {
+		t.Errorf("Expected France, got %+v", res)
+	}
+}
+
+func BenchmarkLookup(b *testing.B) {
+
+	x, err := Embedded()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for b.Loop() {
+		x.Lookup(-111.89, 40.76)
+	}
+}
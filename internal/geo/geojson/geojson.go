@@ -0,0 +1,481 @@
+// Package geojson RFC 7946 types
+package geojson
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// geometry and object types
+const (
+	TypePoint              = "Point"
+	TypeMultiPoint         = "MultiPoint"
+	TypeLineString         = "LineString"
+	TypeMultiLineString    = "MultiLineString"
+	TypePolygon            = "Polygon"
+	TypeMultiPolygon       = "MultiPolygon"
+	TypeGeometryCollection = "GeometryCollection"
+
+	TypeFeature           = "Feature"
+	TypeFeatureCollection = "FeatureCollection"
+)
+
+// MediaType RFC 7946 media type
+const MediaType = "application/geo+json"
+
+// Position [lng, lat], altitude is dropped on decode
+type Position [2]float64
+
+func (x Position) Lng() float64 { return x[0] }
+func (x Position) Lat() float64 { return x[1] }
+
+// UnmarshalJSON accept [lng, lat] and [lng, lat, alt]
+func (x *Position) UnmarshalJSON(data []byte) error {
+
+	var arr []float64
+	if err := json.Unmarshal(data, &arr); err != nil {
+		return err
+	}
+
+	if len(arr) < 2 {
+		return fmt.Errorf("error position must have at least 2 elements")
+	}
+
+	x[0], x[1] = arr[0], arr[1]
+
+	return nil
+}
+
+// Geometry RFC 7946 geometry, only the field matching Type is used
+type Geometry struct {
+	Type            string
+	Point           Position
+	MultiPoint      []Position
+	LineString      []Position
+	MultiLineString [][]Position
+	Polygon         [][]Position
+	MultiPolygon    [][][]Position
+	Geometries      []*Geometry
+}
+
+type geometryJSON struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates,omitempty"`
+	Geometries  []*Geometry     `json:"geometries,omitempty"`
+}
+
+// NewPoint point geometry
+func NewPoint(lng, lat float64) *Geometry {
+	return &Geometry{Type: TypePoint, Point: Position{lng, lat}}
+}
+
+// NewLineString line geometry
+func NewLineString(coords []Position) *Geometry {
+	return &Geometry{Type: TypeLineString, LineString: coords}
+}
+
+// NewPolygon polygon geometry, first ring is exterior
+func NewPolygon(rings [][]Position) *Geometry {
+	return &Geometry{Type: TypePolygon, Polygon: rings}
+}
+
+func (x *Geometry) coordinates() any {
+	switch x.Type {
+	case TypePoint:
+		return x.Point
+	case TypeMultiPoint:
+		return nonNil(x.MultiPoint)
+	case TypeLineString:
+		return nonNil(x.LineString)
+	case TypeMultiLineString:
+		return nonNil(x.MultiLineString)
+	case TypePolygon:
+		return nonNil(x.Polygon)
+	case TypeMultiPolygon:
+		return nonNil(x.MultiPolygon)
+	}
+	return nil
+}
+
+func nonNil[T any](v []T) []T {
+	if v == nil {
+		return []T{}
+	}
+	return v
+}
+
+// MarshalJSON geometry object
+func (x *Geometry) MarshalJSON() ([]byte, error) {
+
+	res := geometryJSON{Type: x.Type}
+
+	if x.Type == TypeGeometryCollection {
+		res.Geometries = nonNil(x.Geometries)
+	} else {
+		coords := x.coordinates()
+		if coords == nil {
+			return nil, fmt.Errorf("error unknown geometry type: %q", x.Type)
+		}
+		data, err := json.Marshal(coords)
+		if err != nil {
+			return nil, err
+		}
+		res.Coordinates = data
+	}
+
+	return json.Marshal(res)
+}
+
+// UnmarshalJSON geometry object
+func (x *Geometry) UnmarshalJSON(data []byte) error {
+
+	raw := geometryJSON{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*x = Geometry{Type: raw.Type}
+
+	var dst any
+	switch raw.Type {
+	case TypePoint:
+		dst = &x.Point
+	case TypeMultiPoint:
+		dst = &x.MultiPoint
+	case TypeLineString:
+		dst = &x.LineString
+	case TypeMultiLineString:
+		dst = &x.MultiLineString
+	case TypePolygon:
+		dst = &x.Polygon
+	case TypeMultiPolygon:
+		dst = &x.MultiPolygon
+	case TypeGeometryCollection:
+		x.Geometries = raw.Geometries
+		return nil
+	default:
+		return fmt.Errorf("error unknown geometry type: %q", raw.Type)
+	}
+
+	if len(raw.Coordinates) == 0 {
+		return fmt.Errorf("error geometry %v has no coordinates", raw.Type)
+	}
+
+	return json.Unmarshal(raw.Coordinates, dst)
+}
+
+// Positions call fn for every position of geometry, fn may modify position
+func (x *Geometry) Positions(fn func(p *Position)) {
+
+	walk := func(arr []Position) {
+		for i := range arr {
+			fn(&arr[i])
+		}
+	}
+
+	switch x.Type {
+	case TypePoint:
+		fn(&x.Point)
+	case TypeMultiPoint:
+		walk(x.MultiPoint)
+	case TypeLineString:
+		walk(x.LineString)
+	case TypeMultiLineString:
+		for _, v := range x.MultiLineString {
+			walk(v)
+		}
+	case TypePolygon:
+		for _, v := range x.Polygon {
+			walk(v)
+		}
+	case TypeMultiPolygon:
+		for _, p := range x.MultiPolygon {
+			for _, v := range p {
+				walk(v)
+			}
+		}
+	case TypeGeometryCollection:
+		for _, g := range x.Geometries {
+			if g != nil {
+				g.Positions(fn)
+			}
+		}
+	}
+}
+
+// Bound bounding box of geometry, ok=false if empty
+func (x *Geometry) Bound() (res BBox, ok bool) {
+
+	res = BBox{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+
+	x.Positions(func(p *Position) {
+		ok = true
+		res.Extend(*p)
+	})
+
+	return res, ok
+}
+
+// Validate check structure and coordinate ranges of geometry
+func (x *Geometry) Validate() error {
+
+	if err := x.ValidateStructure(); err != nil {
+		return err
+	}
+
+	var err error
+
+	x.Positions(func(p *Position) {
+		if err == nil && (p[0] < -180 || p[0] > 180 || p[1] < -90 || p[1] > 90) {
+			err = fmt.Errorf("error position out of range: %v", *p)
+		}
+	})
+
+	return err
+}
+
+// ValidateStructure check structure of geometry, coordinates may be in any CRS
+func (x *Geometry) ValidateStructure() error {
+
+	var err error
+
+	x.Positions(func(p *Position) {
+		if err == nil && (math.IsNaN(p[0]) || math.IsNaN(p[1]) || math.IsInf(p[0], 0) || math.IsInf(p[1], 0)) {
+			err = fmt.Errorf("error position is not finite: %v", *p)
+		}
+	})
+
+	if err != nil {
+		return err
+	}
+
+	line := func(arr []Position) error {
+		if len(arr) < 2 {
+			return fmt.Errorf("error line must have at least 2 positions")
+		}
+		return nil
+	}
+	polygon := func(rings [][]Position) error {
+		if len(rings) == 0 {
+			return fmt.Errorf("error polygon has no rings")
+		}
+		for _, ring := range rings {
+			if len(ring) < 4 {
+				return fmt.Errorf("error polygon ring must have at least 4 positions")
+			}
+			if ring[0] != ring[len(ring)-1] {
+				return fmt.Errorf("error polygon ring is not closed")
+			}
+		}
+		return nil
+	}
+
+	switch x.Type {
+	case TypePoint:
+		return nil
+	case TypeMultiPoint:
+		if len(x.MultiPoint) == 0 {
+			return fmt.Errorf("error multipoint is empty")
+		}
+	case TypeLineString:
+		return line(x.LineString)
+	case TypeMultiLineString:
+		if len(x.MultiLineString) == 0 {
+			return fmt.Errorf("error multilinestring is empty")
+		}
+		for _, v := range x.MultiLineString {
+			if err := line(v); err != nil {
+				return err
+			}
+		}
+	case TypePolygon:
+		return polygon(x.Polygon)
+	case TypeMultiPolygon:
+		if len(x.MultiPolygon) == 0 {
+			return fmt.Errorf("error multipolygon is empty")
+		}
+		for _, v := range x.MultiPolygon {
+			if err := polygon(v); err != nil {
+				return err
+			}
+		}
+	case TypeGeometryCollection:
+		for _, g := range x.Geometries {
+			if g == nil {
+				return fmt.Errorf("error geometry collection has null member")
+			}
+			if err := g.ValidateStructure(); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("error unknown geometry type: %q", x.Type)
+	}
+
+	return nil
+}
+
+// Feature RFC 7946 feature
+type Feature struct {
+	Type       string         `json:"type"`
+	ID         any            `json:"id,omitempty"`
+	Geometry   *Geometry      `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+// NewFeature feature with empty properties
+func NewFeature(geometry *Geometry) *Feature {
+	return &Feature{Type: TypeFeature, Geometry: geometry, Properties: map[string]any{}}
+}
+
+// FeatureCollection RFC 7946 feature collection
+type FeatureCollection struct {
+	Type     string     `json:"type"`
+	BBox     []float64  `json:"bbox,omitempty"`
+	Features []*Feature `json:"features"`
+}
+
+// CoordTimes times of line positions from "coordTimes" property,
+// []string for LineString or [][]string for MultiLineString, value may be json text
+func CoordTimes(v any) [][]string {
+
+	if s, ok := v.(string); ok {
+		var decoded any
+		if json.Unmarshal([]byte(s), &decoded) != nil {
+			return nil
+		}
+		v = decoded
+	}
+
+	strs := func(v any) []string {
+		switch v := v.(type) {
+		case []string:
+			return v
+		case []any:
+			res := make([]string, len(v))
+			for i, s := range v {
+				res[i], _ = s.(string)
+			}
+			return res
+		}
+		return nil
+	}
+
+	switch v := v.(type) {
+	case []string:
+		return [][]string{v}
+	case [][]string:
+		return v
+	case []any:
+		if len(v) > 0 {
+			if _, nested := v[0].([]any); nested {
+				res := make([][]string, len(v))
+				for i, s := range v {
+					res[i] = strs(s)
+				}
+				return res
+			}
+		}
+		return [][]string{strs(v)}
+	}
+
+	return nil
+}
+
+// NewFeatureCollection empty collection
+func NewFeatureCollection() *FeatureCollection {
+	return &FeatureCollection{Type: TypeFeatureCollection, Features: []*Feature{}}
+}
+
+// BBox [minLng, minLat, maxLng, maxLat], minLng > maxLng crosses antimeridian
+type BBox [4]float64
+
+// ParseBBox parse "minLng,minLat,maxLng,maxLat"
+func ParseBBox(text string) (BBox, error) {
+
+	res := BBox{}
+
+	parts := strings.Split(text, ",")
+	if len(parts) != 4 {
+		return res, fmt.Errorf("error bbox must have 4 values")
+	}
+
+	for i, v := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return res, fmt.Errorf("error bbox value %q: %v", v, err)
+		}
+		res[i] = f
+	}
+
+	if err := res.Validate(); err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+// Validate check ranges
+func (x BBox) Validate() error {
+
+	for i, v := range x {
+		limit := 180.0
+		if i%2 == 1 {
+			limit = 90
+		}
+		if math.IsNaN(v) || v < -limit || v > limit {
+			return fmt.Errorf("error bbox value out of range: %v", v)
+		}
+	}
+
+	if x[1] > x[3] {
+		return fmt.Errorf("error bbox minLat > maxLat")
+	}
+
+	return nil
+}
+
+// CrossesAntimeridian minLng > maxLng
+func (x BBox) CrossesAntimeridian() bool { return x[0] > x[2] }
+
+// Extend grow box to include position
+func (x *BBox) Extend(p Position) {
+	x[0] = math.Min(x[0], p[0])
+	x[1] = math.Min(x[1], p[1])
+	x[2] = math.Max(x[2], p[0])
+	x[3] = math.Max(x[3], p[1])
+}
+
+// Contains position inside box
+func (x BBox) Contains(p Position) bool {
+
+	if p[1] < x[1] || p[1] > x[3] {
+		return false
+	}
+
+	if x.CrossesAntimeridian() {
+		return p[0] >= x[0] || p[0] <= x[2]
+	}
+
+	return p[0] >= x[0] && p[0] <= x[2]
+}
+
+// Intersects boxes overlap, other must not cross antimeridian
+func (x BBox) Intersects(other BBox) bool {
+
+	if other[1] > x[3] || other[3] < x[1] {
+		return false
+	}
+
+	if x.CrossesAntimeridian() {
+		return other[2] >= x[0] || other[0] <= x[2]
+	}
+
+	return other[0] <= x[2] && other[2] >= x[0]
+}
+
+// Slice as []float64 for bbox member
+func (x BBox) Slice() []float64 { return x[:] }
@@ -0,0 +1,165 @@
+package geojson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// Test geometry json round trip
+func TestGeometryJSON(t *testing.T) {
+
+	data := `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1,5],[0,0]]]}`
+
+	g := &Geometry{}
+	if err := json.Unmarshal([]byte(data), g); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+
+	if g.Type != TypePolygon || len(g.Polygon[0]) != 4 {
+		t.Fatalf("Expected polygon with 4 positions, got %+v", g)
+	}
+
+	if g.Polygon[0][2] != (Position{1, 1}) {
+		t.Errorf("Expected altitude to be dropped, got %v", g.Polygon[0][2])
+	}
+
+	out, err := json.Marshal(g)
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+
+	expected := `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}`
+	if string(out) != expected {
+		t.Errorf("Expected %s, got %s", expected, out)
+	}
+
+	if err := g.Validate(); err != nil {
+		t.Errorf("Expected valid polygon, got %v", err)
+	}
+}
+
+// Test validation errors
+func TestGeometryValidate(t *testing.T) {
+
+	items := []struct {
+		title string
+		data  string
+		valid bool
+	}{
+		{title: "point", data: `{"type":"Point","coordinates":[10,20]}`, valid: true},
+		{title: "point out of range", data: `{"type":"Point","coordinates":[200,20]}`, valid: false},
+		{title: "short line", data: `{"type":"LineString","coordinates":[[10,20]]}`, valid: false},
+		{title: "open ring", data: `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1]]]}`, valid: false},
+		{title: "collection", data: `{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[1,2]}]}`, valid: true},
+	}
+
+	for _, itm := range items {
+		t.Run(itm.title, func(t *testing.T) {
+			g := &Geometry{}
+			if err := json.Unmarshal([]byte(itm.data), g); err != nil {
+				t.Fatalf("Unmarshal error: %v", err)
+			}
+			err := g.Validate()
+			if (err == nil) != itm.valid {
+				t.Errorf("Expected valid=%v, got %v", itm.valid, err)
+			}
+		})
+	}
+}
+
+// Test bbox parsing and antimeridian handling
+func TestParseBBox(t *testing.T) {
+
+	b, err := ParseBBox("170,-10,-170,10")
+	if err != nil {
+		t.Fatalf("ParseBBox error: %v", err)
+	}
+
+	if !b.CrossesAntimeridian() {
+		t.Error("Expected bbox to cross antimeridian")
+	}
+
+	if !b.Contains(Position{175, 0}) || !b.Contains(Position{-175, 0}) || b.Contains(Position{0, 0}) {
+		t.Error("Unexpected Contains result for antimeridian bbox")
+	}
+
+	if _, err := ParseBBox("0,10,1,5"); err == nil {
+		t.Error("Expected error for minLat > maxLat")
+	}
+
+	if _, err := ParseBBox("0,1,2"); err == nil {
+		t.Error("Expected error for 3 values")
+	}
+}
+
+// Test streaming of collection with broken feature
+func TestReader(t *testing.T) {
+
+	data := `{"type":"FeatureCollection","name":"x","features":[
+		{"type":"Feature","id":1,"geometry":{"type":"Point","coordinates":[1,2]},"properties":{"a":"b"}},
+		{"type":"Feature","geometry":{"type":"Circle","coordinates":[1,2]},"properties":null},
+		{"type":"Feature","id":"3","geometry":null,"properties":{}}
+	],"crs":null}`
+
+	r := NewReader(strings.NewReader(data))
+
+	count, broken := 0, 0
+	for {
+		_, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		fe := &FeatureError{}
+		if errors.As(err, &fe) {
+			broken++
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Next error: %v", err)
+		}
+		count++
+	}
+
+	if count != 2 || broken != 1 {
+		t.Errorf("Expected 2 features and 1 broken, got %v and %v", count, broken)
+	}
+
+	// single feature
+	r = NewReader(strings.NewReader(`{"properties":{},"type":"Feature","geometry":{"type":"Point","coordinates":[1,2]}}`))
+	f, err := r.Next()
+	if err != nil || f.Geometry.Point != (Position{1, 2}) {
+		t.Errorf("Expected single feature, got %v %v", f, err)
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("Expected EOF, got %v", err)
+	}
+}
+
+// Test writer output is valid collection
+func TestWriter(t *testing.T) {
+
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf)
+
+	for i := 0; i < 2; i++ {
+		if err := w.Write(NewFeature(NewPoint(1, 2))); err != nil {
+			t.Fatalf("Write error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	fc := &FeatureCollection{}
+	if err := json.Unmarshal(buf.Bytes(), fc); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+
+	if fc.Type != TypeFeatureCollection || len(fc.Features) != 2 {
+		t.Errorf("Unexpected collection %s", buf.String())
+	}
+}
@@ -0,0 +1,239 @@
+package geojson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// FeatureError feature can not be decoded, reader can continue with next feature
+type FeatureError struct {
+	Index int
+	Err   error
+}
+
+func (x *FeatureError) Error() string {
+	return fmt.Sprintf("error on feature %v: %v", x.Index, x.Err)
+}
+
+func (x *FeatureError) Unwrap() error { return x.Err }
+
+// Reader stream features from FeatureCollection or single Feature
+type Reader struct {
+	dec     *json.Decoder
+	started bool
+	inArray bool
+	single  *Feature
+	index   int
+}
+
+// NewReader stream reader
+func NewReader(r io.Reader) *Reader {
+	return &Reader{dec: json.NewDecoder(r)}
+}
+
+// Next next feature, io.EOF at end, *FeatureError for broken feature
+func (x *Reader) Next() (*Feature, error) {
+
+	if !x.started {
+		x.started = true
+		if err := x.open(); err != nil {
+			return nil, err
+		}
+	}
+
+	if x.single != nil {
+		res := x.single
+		x.single = nil
+		return res, nil
+	}
+
+	if !x.inArray {
+		return nil, io.EOF
+	}
+
+	if x.dec.More() {
+		raw := json.RawMessage{}
+		if err := x.dec.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("error on features array: %v", err)
+		}
+
+		index := x.index
+		x.index++
+
+		res := &Feature{}
+		if err := json.Unmarshal(raw, res); err != nil {
+			return nil, &FeatureError{Index: index, Err: err}
+		}
+		if res.Type != TypeFeature {
+			return nil, &FeatureError{Index: index, Err: fmt.Errorf("type is not Feature: %q", res.Type)}
+		}
+
+		return res, nil
+	}
+
+	x.inArray = false
+
+	if err := x.expectDelim(']'); err != nil {
+		return nil, err
+	}
+
+	// members after features
+	if err := x.skipMembers(); err != nil {
+		return nil, err
+	}
+
+	if err := x.expectDelim('}'); err != nil {
+		return nil, err
+	}
+
+	return nil, io.EOF
+}
+
+// open read members up to features array, or whole Feature object
+func (x *Reader) open() error {
+
+	if err := x.expectDelim('{'); err != nil {
+		return err
+	}
+
+	members := map[string]json.RawMessage{}
+
+	for x.dec.More() {
+		key, err := x.key()
+		if err != nil {
+			return err
+		}
+
+		if key == "features" {
+			if err := x.expectDelim('['); err != nil {
+				return err
+			}
+			x.inArray = true
+			return nil
+		}
+
+		raw := json.RawMessage{}
+		if err := x.dec.Decode(&raw); err != nil {
+			return fmt.Errorf("error on member %v: %v", key, err)
+		}
+		members[key] = raw
+	}
+
+	if err := x.expectDelim('}'); err != nil {
+		return err
+	}
+
+	if !bytes.Equal(bytes.TrimSpace(members["type"]), []byte(`"`+TypeFeature+`"`)) {
+		return fmt.Errorf("error root object is not FeatureCollection or Feature")
+	}
+
+	data, _ := json.Marshal(members)
+
+	res := &Feature{}
+	if err := json.Unmarshal(data, res); err != nil {
+		return &FeatureError{Index: 0, Err: err}
+	}
+
+	x.single = res
+
+	return nil
+}
+
+func (x *Reader) key() (string, error) {
+
+	tok, err := x.dec.Token()
+	if err != nil {
+		return "", fmt.Errorf("error on geojson: %v", err)
+	}
+
+	key, ok := tok.(string)
+	if !ok {
+		return "", fmt.Errorf("error on geojson: unexpected %v", tok)
+	}
+
+	return key, nil
+}
+
+func (x *Reader) skipMembers() error {
+
+	for x.dec.More() {
+		if _, err := x.key(); err != nil {
+			return err
+		}
+		raw := json.RawMessage{}
+		if err := x.dec.Decode(&raw); err != nil {
+			return fmt.Errorf("error on geojson: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (x *Reader) expectDelim(d json.Delim) error {
+
+	tok, err := x.dec.Token()
+	if err != nil {
+		return fmt.Errorf("error on geojson: %v", err)
+	}
+
+	if tok != d {
+		return fmt.Errorf("error on geojson: expected %v got %v", d, tok)
+	}
+
+	return nil
+}
+
+// Writer stream features as FeatureCollection, Close must be called
+type Writer struct {
+	w     io.Writer
+	count int
+	err   error
+}
+
+// NewWriter stream writer
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+func (x *Writer) write(data []byte) {
+	if x.err == nil {
+		_, x.err = x.w.Write(data)
+	}
+}
+
+// Write append feature to collection
+func (x *Writer) Write(f *Feature) error {
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+
+	if x.count == 0 {
+		x.write([]byte(`{"type":"FeatureCollection","features":[` + "\n"))
+	} else {
+		x.write([]byte(",\n"))
+	}
+
+	x.write(data)
+	x.count++
+
+	return x.err
+}
+
+// Count written features
+func (x *Writer) Count() int { return x.count }
+
+// Close end collection
+func (x *Writer) Close() error {
+
+	if x.count == 0 {
+		x.write([]byte(`{"type":"FeatureCollection","features":[`))
+	}
+
+	x.write([]byte("\n]}\n"))
+
+	return x.err
+}
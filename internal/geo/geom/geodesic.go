@@ -0,0 +1,163 @@
+package geom
+
+import (
+	"go-gis/internal/geo/geojson"
+	"math"
+)
+
+const (
+	deg2rad = math.Pi / 180
+
+	// wgs84 ellipsoid
+	wgs84A = 6378137.0
+	wgs84F = 1 / 298.257223563
+	wgs84B = wgs84A * (1 - wgs84F)
+
+	// authalicRadius sphere of same surface as WGS84
+	authalicRadius = 6371007.181
+)
+
+// Distance geodesic distance in meters on WGS84, Vincenty inverse,
+// haversine on authalic sphere for nearly antipodal points
+func Distance(a, b geojson.Position) float64 {
+
+	if a == b {
+		return 0
+	}
+
+	l := (b[0] - a[0]) * deg2rad
+	u1 := math.Atan((1 - wgs84F) * math.Tan(a[1]*deg2rad))
+	u2 := math.Atan((1 - wgs84F) * math.Tan(b[1]*deg2rad))
+	sinU1, cosU1 := math.Sincos(u1)
+	sinU2, cosU2 := math.Sincos(u2)
+
+	lambda := l
+	var sinSigma, cosSigma, sigma, cos2Alpha, cos2SigmaM float64
+
+	for range 200 {
+		sinLambda, cosLambda := math.Sincos(lambda)
+		sinSigma = math.Hypot(cosU2*sinLambda, cosU1*sinU2-sinU1*cosU2*cosLambda)
+		if sinSigma == 0 {
+			return 0 // coincident
+		}
+		cosSigma = sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma = math.Atan2(sinSigma, cosSigma)
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cos2Alpha = 1 - sinAlpha*sinAlpha
+		cos2SigmaM = 0
+		if cos2Alpha != 0 {
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cos2Alpha
+		}
+		c := wgs84F / 16 * cos2Alpha * (4 + wgs84F*(4-3*cos2Alpha))
+		prev := lambda
+		lambda = l + (1-c)*wgs84F*sinAlpha*(sigma+c*sinSigma*(cos2SigmaM+c*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+		if math.Abs(lambda-prev) < 1e-12 {
+			u2 := cos2Alpha * (wgs84A*wgs84A - wgs84B*wgs84B) / (wgs84B * wgs84B)
+			k1 := 1 + u2/16384*(4096+u2*(-768+u2*(320-175*u2)))
+			k2 := u2 / 1024 * (256 + u2*(-128+u2*(74-47*u2)))
+			deltaSigma := k2 * sinSigma * (cos2SigmaM + k2/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+				k2/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+			return wgs84B * k1 * (sigma - deltaSigma)
+		}
+	}
+
+	return haversine(a, b)
+}
+
+func haversine(a, b geojson.Position) float64 {
+
+	dLat := (b[1] - a[1]) * deg2rad
+	dLng := (b[0] - a[0]) * deg2rad
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(a[1]*deg2rad)*math.Cos(b[1]*deg2rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return 2 * authalicRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Length geodesic length of lines and perimeter of polygons in meters
+func Length(g *geojson.Geometry) float64 {
+
+	line := func(arr []geojson.Position) float64 {
+		res := 0.0
+		for i := 0; i+1 < len(arr); i++ {
+			res += Distance(arr[i], arr[i+1])
+		}
+		return res
+	}
+
+	res := 0.0
+
+	switch g.Type {
+	case geojson.TypeLineString:
+		res = line(g.LineString)
+	case geojson.TypeMultiLineString:
+		for _, l := range g.MultiLineString {
+			res += line(l)
+		}
+	case geojson.TypePolygon:
+		for _, r := range g.Polygon {
+			res += line(r)
+		}
+	case geojson.TypeMultiPolygon:
+		for _, p := range g.MultiPolygon {
+			for _, r := range p {
+				res += line(r)
+			}
+		}
+	case geojson.TypeGeometryCollection:
+		for _, v := range g.Geometries {
+			if v != nil {
+				res += Length(v)
+			}
+		}
+	}
+
+	return res
+}
+
+// Area geodesic area of polygons in square meters on authalic sphere
+func Area(g *geojson.Geometry) float64 {
+
+	polygon := func(rings [][]geojson.Position) float64 {
+		res := 0.0
+		for i, r := range rings {
+			a := math.Abs(ringArea(r))
+			if i > 0 {
+				a = -a
+			}
+			res += a
+		}
+		return math.Max(0, res)
+	}
+
+	res := 0.0
+
+	switch g.Type {
+	case geojson.TypePolygon:
+		res = polygon(g.Polygon)
+	case geojson.TypeMultiPolygon:
+		for _, p := range g.MultiPolygon {
+			res += polygon(p)
+		}
+	case geojson.TypeGeometryCollection:
+		for _, v := range g.Geometries {
+			if v != nil {
+				res += Area(v)
+			}
+		}
+	}
+
+	return res
+}
+
+// ringArea signed spherical area, Chamberlain and Duquette
+func ringArea(ring []geojson.Position) float64 {
+
+	res := 0.0
+	for i := 0; i+1 < len(ring); i++ {
+		p, q := ring[i], ring[i+1]
+		res += (q[0] - p[0]) * deg2rad * (2 + math.Sin(p[1]*deg2rad) + math.Sin(q[1]*deg2rad))
+	}
+
+	return res * authalicRadius * authalicRadius / 2
+}
@@ -0,0 +1,344 @@
+// Package geom planar and geodesic operations on geojson geometries
+package geom
+
+import (
+	"go-gis/internal/geo/geojson"
+	"math"
+	"sort"
+)
+
+// simplify methods
+const (
+	MethodDouglasPeucker = "dp"
+	MethodVisvalingam    = "vw"
+)
+
+// SimplifyDP Douglas-Peucker, tolerance is max distance in coordinate units
+func SimplifyDP(line []geojson.Position, tolerance float64) []geojson.Position {
+
+	if len(line) < 3 {
+		return line
+	}
+
+	kept := SimplifyDPIndex(line, tolerance)
+
+	res := make([]geojson.Position, len(kept))
+	for i, v := range kept {
+		res[i] = line[v]
+	}
+
+	return res
+}
+
+// SimplifyDPIndex Douglas-Peucker, ascending indices of kept positions,
+// for attributes of positions like times of track points
+func SimplifyDPIndex(line []geojson.Position, tolerance float64) []int {
+
+	if len(line) < 3 {
+		res := make([]int, len(line))
+		for i := range res {
+			res[i] = i
+		}
+		return res
+	}
+
+	keep := make([]bool, len(line))
+	keep[0], keep[len(line)-1] = true, true
+
+	var walk func(first, last int)
+	walk = func(first, last int) {
+		maxDist, index := 0.0, -1
+		for i := first + 1; i < last; i++ {
+			if d := segmentDistance(line[i], line[first], line[last]); d > maxDist {
+				maxDist, index = d, i
+			}
+		}
+		if index >= 0 && maxDist > tolerance {
+			keep[index] = true
+			walk(first, index)
+			walk(index, last)
+		}
+	}
+
+	walk(0, len(line)-1)
+
+	res := make([]int, 0, len(line))
+	for i, v := range keep {
+		if v {
+			res = append(res, i)
+		}
+	}
+
+	return res
+}
+
+// SimplifyVW Visvalingam-Whyatt, tolerance is min triangle area in squared coordinate units
+func SimplifyVW(line []geojson.Position, tolerance float64) []geojson.Position {
+
+	if len(line) < 3 {
+		return line
+	}
+
+	// linked list with O(n^2) worst case, fine for request sized input
+	prev := make([]int, len(line))
+	next := make([]int, len(line))
+	for i := range line {
+		prev[i], next[i] = i-1, i+1
+	}
+
+	area := func(i int) float64 {
+		return triangleArea(line[prev[i]], line[i], line[next[i]])
+	}
+
+	removed := make([]bool, len(line))
+	count := len(line)
+
+	for count > 2 {
+		minArea, index := math.Inf(1), -1
+		for i := next[0]; i < len(line)-1; i = next[i] {
+			if a := area(i); a < minArea {
+				minArea, index = a, i
+			}
+		}
+		if index < 0 || minArea >= tolerance {
+			break
+		}
+		removed[index] = true
+		next[prev[index]] = next[index]
+		prev[next[index]] = prev[index]
+		count--
+	}
+
+	res := make([]geojson.Position, 0, count)
+	for i, p := range line {
+		if !removed[i] {
+			res = append(res, p)
+		}
+	}
+
+	return res
+}
+
+// Simplify lines and rings of geometry, rings collapsing below 4 positions are kept as is
+func Simplify(g *geojson.Geometry, tolerance float64, method string) *geojson.Geometry {
+
+	fn := SimplifyDP
+	if method == MethodVisvalingam {
+		fn = SimplifyVW
+	}
+
+	line := func(arr []geojson.Position) []geojson.Position {
+		return fn(arr, tolerance)
+	}
+	ring := func(arr []geojson.Position) []geojson.Position {
+		if res := fn(arr, tolerance); len(res) >= 4 {
+			return res
+		}
+		return arr
+	}
+	polygon := func(rings [][]geojson.Position) [][]geojson.Position {
+		res := make([][]geojson.Position, len(rings))
+		for i, r := range rings {
+			res[i] = ring(r)
+		}
+		return res
+	}
+
+	res := &geojson.Geometry{Type: g.Type, Point: g.Point, MultiPoint: g.MultiPoint}
+
+	switch g.Type {
+	case geojson.TypeLineString:
+		res.LineString = line(g.LineString)
+	case geojson.TypeMultiLineString:
+		for _, l := range g.MultiLineString {
+			res.MultiLineString = append(res.MultiLineString, line(l))
+		}
+	case geojson.TypePolygon:
+		res.Polygon = polygon(g.Polygon)
+	case geojson.TypeMultiPolygon:
+		for _, p := range g.MultiPolygon {
+			res.MultiPolygon = append(res.MultiPolygon, polygon(p))
+		}
+	case geojson.TypeGeometryCollection:
+		for _, v := range g.Geometries {
+			res.Geometries = append(res.Geometries, Simplify(v, tolerance, method))
+		}
+	}
+
+	return res
+}
+
+// ConvexHull monotone chain, result is Point, LineString or Polygon
+func ConvexHull(g *geojson.Geometry) *geojson.Geometry {
+
+	points := []geojson.Position{}
+	g.Positions(func(p *geojson.Position) { points = append(points, *p) })
+
+	sort.Slice(points, func(i, j int) bool {
+		if points[i][0] != points[j][0] {
+			return points[i][0] < points[j][0]
+		}
+		return points[i][1] < points[j][1]
+	})
+
+	// dedupe
+	uniq := points[:0]
+	for i, p := range points {
+		if i == 0 || p != points[i-1] {
+			uniq = append(uniq, p)
+		}
+	}
+	points = uniq
+
+	switch len(points) {
+	case 0:
+		return &geojson.Geometry{Type: geojson.TypeGeometryCollection}
+	case 1:
+		return geojson.NewPoint(points[0][0], points[0][1])
+	}
+
+	hull := make([]geojson.Position, 0, 2*len(points))
+	for _, p := range points {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	lower := len(hull) + 1
+	for i := len(points) - 2; i >= 0; i-- {
+		p := points[i]
+		for len(hull) >= lower && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+
+	// hull is closed, counterclockwise
+	if len(hull) < 4 {
+		return geojson.NewLineString([]geojson.Position{hull[0], hull[1]})
+	}
+
+	return geojson.NewPolygon([][]geojson.Position{hull})
+}
+
+// Centroid planar centroid of highest dimension components
+func Centroid(g *geojson.Geometry) (geojson.Position, bool) {
+
+	var areaSum, areaX, areaY float64
+	var lenSum, lenX, lenY float64
+	var count, sumX, sumY float64
+
+	var walk func(g *geojson.Geometry)
+
+	line := func(arr []geojson.Position) {
+		for i := 0; i+1 < len(arr); i++ {
+			a, b := arr[i], arr[i+1]
+			l := math.Hypot(b[0]-a[0], b[1]-a[1])
+			lenSum += l
+			lenX += l * (a[0] + b[0]) / 2
+			lenY += l * (a[1] + b[1]) / 2
+		}
+	}
+	polygon := func(rings [][]geojson.Position) {
+		for i, r := range rings {
+			a, cx, cy := ringCentroid(r)
+			a = math.Abs(a)
+			if i > 0 {
+				a = -a // hole
+			}
+			areaSum += a
+			areaX += a * cx
+			areaY += a * cy
+		}
+	}
+	point := func(p geojson.Position) {
+		count++
+		sumX += p[0]
+		sumY += p[1]
+	}
+
+	walk = func(g *geojson.Geometry) {
+		switch g.Type {
+		case geojson.TypePoint:
+			point(g.Point)
+		case geojson.TypeMultiPoint:
+			for _, p := range g.MultiPoint {
+				point(p)
+			}
+		case geojson.TypeLineString:
+			line(g.LineString)
+		case geojson.TypeMultiLineString:
+			for _, l := range g.MultiLineString {
+				line(l)
+			}
+		case geojson.TypePolygon:
+			polygon(g.Polygon)
+		case geojson.TypeMultiPolygon:
+			for _, p := range g.MultiPolygon {
+				polygon(p)
+			}
+		case geojson.TypeGeometryCollection:
+			for _, v := range g.Geometries {
+				if v != nil {
+					walk(v)
+				}
+			}
+		}
+	}
+
+	walk(g)
+
+	switch {
+	case areaSum > 0:
+		return geojson.Position{areaX / areaSum, areaY / areaSum}, true
+	case lenSum > 0:
+		return geojson.Position{lenX / lenSum, lenY / lenSum}, true
+	case count > 0:
+		return geojson.Position{sumX / count, sumY / count}, true
+	}
+
+	return geojson.Position{}, false
+}
+
+// ringCentroid signed area and centroid of ring
+func ringCentroid(ring []geojson.Position) (float64, float64, float64) {
+
+	var a, cx, cy float64
+	for i := 0; i+1 < len(ring); i++ {
+		p, q := ring[i], ring[i+1]
+		f := p[0]*q[1] - q[0]*p[1]
+		a += f
+		cx += (p[0] + q[0]) * f
+		cy += (p[1] + q[1]) * f
+	}
+
+	if a == 0 {
+		return 0, 0, 0
+	}
+
+	a /= 2
+
+	return a, cx / (6 * a), cy / (6 * a)
+}
+
+func cross(o, a, b geojson.Position) float64 {
+	return (a[0]-o[0])*(b[1]-o[1]) - (a[1]-o[1])*(b[0]-o[0])
+}
+
+func triangleArea(a, b, c geojson.Position) float64 {
+	return math.Abs(cross(a, b, c)) / 2
+}
+
+// segmentDistance planar distance of p to segment ab
+func segmentDistance(p, a, b geojson.Position) float64 {
+
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	if dx == 0 && dy == 0 {
+		return math.Hypot(p[0]-a[0], p[1]-a[1])
+	}
+
+	t := ((p[0]-a[0])*dx + (p[1]-a[1])*dy) / (dx*dx + dy*dy)
+	t = max(0, min(1, t))
+
+	return math.Hypot(p[0]-a[0]-t*dx, p[1]-a[1]-t*dy)
+}
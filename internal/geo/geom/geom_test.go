@@ -0,0 +1,59 @@
+package geom
+
+import (
+	"go-gis/internal/geo/geojson"
+	"math"
+	"testing"
+)
+
+// Test simplify keeps ends and drops points within tolerance
+func TestSimplify(t *testing.T) {
+
+	line := []geojson.Position{{0, 0}, {1, 0.01}, {2, -0.01}, {3, 5}, {4, 6}, {5, 7.02}, {6, 8}}
+
+	if res := SimplifyDP(line, 0.1); len(res) != 4 || res[0] != line[0] || res[len(res)-1] != line[len(line)-1] {
+		t.Errorf("Unexpected DP result %v", res)
+	}
+
+	if res := SimplifyVW(line, 0.1); len(res) != 4 {
+		t.Errorf("Unexpected VW result %v", res)
+	}
+}
+
+// Test hull and centroid of square with inner points
+func TestHullCentroid(t *testing.T) {
+
+	g := &geojson.Geometry{Type: geojson.TypeMultiPoint, MultiPoint: []geojson.Position{{0, 0}, {2, 0}, {1, 1}, {2, 2}, {0, 2}, {1, 0}}}
+
+	hull := ConvexHull(g)
+	if hull.Type != geojson.TypePolygon || len(hull.Polygon[0]) != 5 {
+		t.Fatalf("Expected square hull, got %+v", hull)
+	}
+
+	c, ok := Centroid(hull)
+	if !ok || math.Abs(c[0]-1) > 1e-12 || math.Abs(c[1]-1) > 1e-12 {
+		t.Errorf("Expected centroid 1,1, got %v", c)
+	}
+}
+
+// Test geodesic distance and area
+func TestGeodesic(t *testing.T) {
+
+	// Vincenty test line: Flinders Peak to Buninyong
+	a := geojson.Position{144 + 25/60.0 + 29.52440/3600, -(37 + 57/60.0 + 3.72030/3600)}
+	b := geojson.Position{143 + 55/60.0 + 35.38390/3600, -(37 + 39/60.0 + 10.15610/3600)}
+
+	if d := Distance(a, b); math.Abs(d-54972.271) > 0.01 {
+		t.Errorf("Expected 54972.271, got %.3f", d)
+	}
+
+	// 1x1 degree at equator is about 12364 km2
+	square := geojson.NewPolygon([][]geojson.Position{{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}})
+	if area := Area(square); math.Abs(area/1e6-12364) > 50 {
+		t.Errorf("Expected about 12364 km2, got %.0f", area/1e6)
+	}
+
+	if l := Length(square); math.Abs(l-4*111000) > 2000 {
+		t.Errorf("Expected perimeter about 444 km, got %.0f", l)
+	}
+}
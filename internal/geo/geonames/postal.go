@@ -0,0 +1,207 @@
+// Package geonames GeoNames postal code dump reader
+package geonames
+
+import (
+	"archive/zip"
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// PostalCode row of dump, admin codes and names are optional
+type PostalCode struct {
+	CountryCode string
+	PostalCode  string
+	PlaceName   string
+	AdminName1  string
+	AdminCode1  string
+	AdminName2  string
+	AdminCode2  string
+	AdminName3  string
+	AdminCode3  string
+	Lat         float64
+	Lng         float64
+	Accuracy    int // 1=estimated, 4=geonameid, 6=centroid of addresses or shape
+}
+
+// RowError broken row, reading continues
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (x *RowError) Error() string { return fmt.Sprintf("line %v: %v", x.Line, x.Err) }
+
+func (x *RowError) Unwrap() error { return x.Err }
+
+// columns of dump
+const (
+	colCountryCode = iota
+	colPostalCode
+	colPlaceName
+	colAdminName1
+	colAdminCode1
+	colAdminName2
+	colAdminCode2
+	colAdminName3
+	colAdminCode3
+	colLatitude
+	colLongitude
+	colAccuracy
+	columns
+)
+
+// Reader tab-separated rows of allCountries.txt or XX.txt
+type Reader struct {
+	scanner *bufio.Scanner
+	line    int
+	closers []io.Closer
+}
+
+// NewReader reader of dump text
+func NewReader(r io.Reader) *Reader {
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	return &Reader{scanner: scanner}
+}
+
+// Open .txt or .zip with country .txt inside, readme.txt is skipped
+func Open(path string) (*Reader, error) {
+
+	path = filepath.Clean(path)
+
+	if !strings.EqualFold(filepath.Ext(path), ".zip") {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		res := NewReader(f)
+		res.closers = append(res.closers, f)
+		return res, nil
+	}
+
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range z.File {
+		name := strings.ToLower(filepath.Base(f.Name))
+		if !strings.HasSuffix(name, ".txt") || name == "readme.txt" {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			_ = z.Close()
+			return nil, fmt.Errorf("error on zip entry %v: %v", f.Name, err)
+		}
+		res := NewReader(r)
+		res.closers = append(res.closers, r, z)
+		return res, nil
+	}
+
+	_ = z.Close()
+
+	return nil, fmt.Errorf("error zip has no .txt file")
+}
+
+// Close files of Open
+func (x *Reader) Close() error {
+
+	var res error
+	for _, c := range x.closers {
+		if err := c.Close(); err != nil && res == nil {
+			res = err
+		}
+	}
+
+	return res
+}
+
+// Next row, io.EOF at end, *RowError on broken row
+func (x *Reader) Next() (*PostalCode, error) {
+
+	for x.scanner.Scan() {
+
+		x.line++
+
+		text := strings.TrimRight(x.scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		res, err := parseRow(text)
+		if err != nil {
+			return nil, &RowError{Line: x.line, Err: err}
+		}
+
+		return res, nil
+	}
+
+	if err := x.scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, io.EOF
+}
+
+func parseRow(text string) (*PostalCode, error) {
+
+	cols := strings.Split(text, "\t")
+	if len(cols) < colAccuracy { // accuracy is empty in some dumps
+		return nil, fmt.Errorf("expected %v columns, got %v", columns, len(cols))
+	}
+
+	for i := range cols {
+		cols[i] = strings.TrimSpace(cols[i])
+	}
+
+	res := &PostalCode{
+		CountryCode: strings.ToUpper(cols[colCountryCode]),
+		PostalCode:  cols[colPostalCode],
+		PlaceName:   cols[colPlaceName],
+		AdminName1:  cols[colAdminName1],
+		AdminCode1:  cols[colAdminCode1],
+		AdminName2:  cols[colAdminName2],
+		AdminCode2:  cols[colAdminCode2],
+		AdminName3:  cols[colAdminName3],
+		AdminCode3:  cols[colAdminCode3],
+	}
+
+	if len(res.CountryCode) != 2 {
+		return nil, fmt.Errorf("invalid country code: %q", res.CountryCode)
+	}
+
+	if res.PostalCode == "" {
+		return nil, fmt.Errorf("postal code is empty")
+	}
+
+	var err error
+
+	if res.Lat, err = strconv.ParseFloat(cols[colLatitude], 64); err != nil || res.Lat < -90 || res.Lat > 90 {
+		return nil, fmt.Errorf("invalid latitude: %q", cols[colLatitude])
+	}
+
+	if res.Lng, err = strconv.ParseFloat(cols[colLongitude], 64); err != nil || res.Lng < -180 || res.Lng > 180 {
+		return nil, fmt.Errorf("invalid longitude: %q", cols[colLongitude])
+	}
+
+	if len(cols) > colAccuracy && cols[colAccuracy] != "" {
+		if res.Accuracy, err = strconv.Atoi(cols[colAccuracy]); err != nil {
+			return nil, fmt.Errorf("invalid accuracy: %q", cols[colAccuracy])
+		}
+	}
+
+	return res, nil
+}
+
+// NormalizePostalCode upper case without spaces, key of lookup
+func NormalizePostalCode(text string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(text), ""))
+}
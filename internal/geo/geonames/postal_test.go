@@ -0,0 +1,53 @@
+package geonames
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// Test rows with optional accuracy, broken row does not stop reading
+func TestReader(t *testing.T) {
+
+	data := "GB\tSW1A 2AA\tLondon\tEngland\tENG\tGreater London\t11609024\tWestminster\tE09000033\t51.5034\t-0.1276\t6\n" +
+		"\n" +
+		"FR\t75001\tParis 01\tÎle-de-France\t11\tParis\t75\tParis\t751\t48.8592\t2.3417\t\n" +
+		"DE\t10115\tBerlin\tBerlin\tBE\t\t\t\t\tnorth\t13.3833\t4\n" +
+		"AD\tAD100\tCanillo\t\t\t\t\t\t\t42.5833\t1.6667\t6\r\n"
+
+	r := NewReader(strings.NewReader(data))
+
+	p, err := r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.CountryCode != "GB" || p.PostalCode != "SW1A 2AA" || p.AdminName3 != "Westminster" || p.Lat != 51.5034 || p.Lng != -0.1276 || p.Accuracy != 6 {
+		t.Errorf("Unexpected row %+v", p)
+	}
+
+	if p, err = r.Next(); err != nil || p.PlaceName != "Paris 01" || p.Accuracy != 0 {
+		t.Errorf("Unexpected row %+v %v", p, err)
+	}
+
+	rowErr := &RowError{}
+	if _, err = r.Next(); !errors.As(err, &rowErr) || rowErr.Line != 4 {
+		t.Errorf("Expected row error on line 4, got %v", err)
+	}
+
+	if p, err = r.Next(); err != nil || p.PostalCode != "AD100" {
+		t.Errorf("Unexpected row %+v %v", p, err)
+	}
+
+	if _, err = r.Next(); err != io.EOF {
+		t.Errorf("Expected EOF, got %v", err)
+	}
+}
+
+// Test lookup key
+func TestNormalizePostalCode(t *testing.T) {
+
+	if res := NormalizePostalCode(" sw1a  2aa "); res != "SW1A2AA" {
+		t.Errorf("Expected SW1A2AA, got %q", res)
+	}
+}
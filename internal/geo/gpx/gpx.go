@@ -0,0 +1,279 @@
+// Package gpx GPX 1.1 decoder and encoder
+package gpx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"go-gis/internal/geo/geojson"
+	"io"
+)
+
+const (
+	MediaType = "application/gpx+xml"
+	Namespace = "http://www.topografix.com/GPX/1/1"
+)
+
+type document struct {
+	XMLName xml.Name `xml:"gpx"`
+	Wpt     []point  `xml:"wpt"`
+	Rte     []route  `xml:"rte"`
+	Trk     []track  `xml:"trk"`
+}
+
+type point struct {
+	Lat  float64  `xml:"lat,attr"`
+	Lon  float64  `xml:"lon,attr"`
+	Ele  *float64 `xml:"ele,omitempty"`
+	Time string   `xml:"time,omitempty"`
+	Name string   `xml:"name,omitempty"`
+	Desc string   `xml:"desc,omitempty"`
+	Sym  string   `xml:"sym,omitempty"`
+	Type string   `xml:"type,omitempty"`
+}
+
+type route struct {
+	Name  string  `xml:"name,omitempty"`
+	Desc  string  `xml:"desc,omitempty"`
+	Type  string  `xml:"type,omitempty"`
+	Rtept []point `xml:"rtept"`
+}
+
+type track struct {
+	XMLName xml.Name  `xml:"trk"`
+	Name    string    `xml:"name,omitempty"`
+	Desc    string    `xml:"desc,omitempty"`
+	Type    string    `xml:"type,omitempty"`
+	Trkseg  []segment `xml:"trkseg"`
+}
+
+type segment struct {
+	Trkpt []point `xml:"trkpt"`
+}
+
+// Decode waypoints as Point, routes as LineString, tracks as LineString or MultiLineString,
+// point times of tracks and routes are in "coordTimes" property
+func Decode(r io.Reader) (*geojson.FeatureCollection, error) {
+
+	doc := &document{}
+	if err := xml.NewDecoder(r).Decode(doc); err != nil {
+		return nil, fmt.Errorf("error on gpx: %v", err)
+	}
+
+	res := geojson.NewFeatureCollection()
+
+	for _, p := range doc.Wpt {
+		f := geojson.NewFeature(geojson.NewPoint(p.Lon, p.Lat))
+		setProps(f.Properties, p.Name, p.Desc, p.Type)
+		if p.Time != "" {
+			f.Properties["time"] = p.Time
+		}
+		if p.Ele != nil {
+			f.Properties["ele"] = *p.Ele
+		}
+		if p.Sym != "" {
+			f.Properties["sym"] = p.Sym
+		}
+		res.Features = append(res.Features, f)
+	}
+
+	for _, rte := range doc.Rte {
+		coords, times := line(rte.Rtept)
+		if len(coords) < 2 {
+			continue
+		}
+		f := geojson.NewFeature(geojson.NewLineString(coords))
+		setProps(f.Properties, rte.Name, rte.Desc, rte.Type)
+		if times != nil {
+			f.Properties["coordTimes"] = times
+		}
+		res.Features = append(res.Features, f)
+	}
+
+	for _, trk := range doc.Trk {
+		lines := [][]geojson.Position{}
+		times := [][]string{}
+		hasTimes := false
+
+		for _, seg := range trk.Trkseg {
+			coords, t := line(seg.Trkpt)
+			if len(coords) < 2 {
+				continue
+			}
+			lines = append(lines, coords)
+			times = append(times, t)
+			hasTimes = hasTimes || t != nil
+		}
+
+		if len(lines) == 0 {
+			continue
+		}
+
+		var f *geojson.Feature
+		if len(lines) == 1 {
+			f = geojson.NewFeature(geojson.NewLineString(lines[0]))
+			if hasTimes {
+				f.Properties["coordTimes"] = times[0]
+			}
+		} else {
+			f = geojson.NewFeature(&geojson.Geometry{Type: geojson.TypeMultiLineString, MultiLineString: lines})
+			if hasTimes {
+				f.Properties["coordTimes"] = times
+			}
+		}
+		setProps(f.Properties, trk.Name, trk.Desc, trk.Type)
+		res.Features = append(res.Features, f)
+	}
+
+	return res, nil
+}
+
+func setProps(props map[string]any, name, desc, kind string) {
+
+	if name != "" {
+		props["name"] = name
+	}
+	if desc != "" {
+		props["desc"] = desc
+	}
+	if kind != "" {
+		props["type"] = kind
+	}
+}
+
+// line positions and times, times is nil if no point has time
+func line(points []point) ([]geojson.Position, []string) {
+
+	coords := make([]geojson.Position, 0, len(points))
+	times := make([]string, 0, len(points))
+	hasTimes := false
+
+	for _, p := range points {
+		coords = append(coords, geojson.Position{p.Lon, p.Lat})
+		times = append(times, p.Time)
+		hasTimes = hasTimes || p.Time != ""
+	}
+
+	if !hasTimes {
+		times = nil
+	}
+
+	return coords, times
+}
+
+// Writer stream features as GPX, points are written as waypoints, lines as tracks,
+// polygons as tracks of rings, Close must be called
+type Writer struct {
+	w      io.Writer
+	enc    *xml.Encoder
+	tracks bytes.Buffer // gpx requires tracks after waypoints
+	trkEnc *xml.Encoder
+	count  int
+	err    error
+}
+
+// NewWriter stream writer
+func NewWriter(w io.Writer) *Writer {
+
+	res := &Writer{w: w, enc: xml.NewEncoder(w)}
+	res.trkEnc = xml.NewEncoder(&res.tracks)
+
+	_, res.err = io.WriteString(w, xml.Header+`<gpx version="1.1" creator="go-gis" xmlns="`+Namespace+`">`+"\n")
+
+	return res
+}
+
+// Write append feature
+func (x *Writer) Write(f *geojson.Feature) error {
+
+	if x.err != nil {
+		return x.err
+	}
+
+	if f.Geometry == nil {
+		return nil
+	}
+
+	name, _ := f.Properties["name"].(string)
+	desc, _ := f.Properties["desc"].(string)
+	if desc == "" {
+		desc, _ = f.Properties["description"].(string)
+	}
+	kind, _ := f.Properties["type"].(string)
+
+	g := f.Geometry
+	times := geojson.CoordTimes(f.Properties["coordTimes"])
+
+	var lines [][]geojson.Position
+
+	switch g.Type {
+	case geojson.TypePoint, geojson.TypeMultiPoint:
+		pts := g.MultiPoint
+		if g.Type == geojson.TypePoint {
+			pts = []geojson.Position{g.Point}
+		}
+		for _, p := range pts {
+			wpt := point{Lat: p.Lat(), Lon: p.Lng(), Name: name, Desc: desc, Type: kind}
+			wpt.Time, _ = f.Properties["time"].(string)
+			wpt.Sym, _ = f.Properties["sym"].(string)
+			if ele, ok := f.Properties["ele"].(float64); ok {
+				wpt.Ele = &ele
+			}
+			x.err = x.enc.EncodeElement(wpt, xml.StartElement{Name: xml.Name{Local: "wpt"}})
+		}
+		x.count++
+		return x.err
+	case geojson.TypeLineString:
+		lines = [][]geojson.Position{g.LineString}
+	case geojson.TypeMultiLineString:
+		lines = g.MultiLineString
+	case geojson.TypePolygon:
+		lines = g.Polygon
+	case geojson.TypeMultiPolygon:
+		for _, p := range g.MultiPolygon {
+			lines = append(lines, p...)
+		}
+	default:
+		return nil // geometry collection is not supported
+	}
+
+	trk := track{Name: name, Desc: desc, Type: kind}
+	for i, l := range lines {
+		seg := segment{}
+		for j, p := range l {
+			pt := point{Lat: p.Lat(), Lon: p.Lng()}
+			if i < len(times) && j < len(times[i]) {
+				pt.Time = times[i][j]
+			}
+			seg.Trkpt = append(seg.Trkpt, pt)
+		}
+		trk.Trkseg = append(trk.Trkseg, seg)
+	}
+
+	x.err = x.trkEnc.Encode(trk)
+	x.count++
+
+	return x.err
+}
+
+// Count written features
+func (x *Writer) Count() int { return x.count }
+
+// Close write tracks and end document
+func (x *Writer) Close() error {
+
+	if x.err == nil {
+		x.err = x.enc.Flush()
+	}
+	if x.err == nil {
+		x.err = x.trkEnc.Flush()
+	}
+	if x.err == nil && x.tracks.Len() > 0 {
+		_, x.err = x.w.Write(x.tracks.Bytes())
+	}
+	if x.err == nil {
+		_, x.err = io.WriteString(x.w, "\n</gpx>\n")
+	}
+
+	return x.err
+}
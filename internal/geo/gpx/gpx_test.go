@@ -0,0 +1,72 @@
+package gpx
+
+import (
+	"bytes"
+	"go-gis/internal/geo/geojson"
+	"strings"
+	"testing"
+)
+
+const testGPX = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+  <wpt lat="52.5" lon="13.4"><ele>34.5</ele><name>Camp</name></wpt>
+  <trk><name>Morning</name>
+    <trkseg>
+      <trkpt lat="52.50" lon="13.40"><time>2024-05-01T08:00:00Z</time></trkpt>
+      <trkpt lat="52.51" lon="13.41"><time>2024-05-01T08:01:00Z</time></trkpt>
+    </trkseg>
+  </trk>
+</gpx>`
+
+// Test decode and encode round trip
+func TestRoundTrip(t *testing.T) {
+
+	fc, err := Decode(strings.NewReader(testGPX))
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+
+	if len(fc.Features) != 2 {
+		t.Fatalf("Expected 2 features, got %v", len(fc.Features))
+	}
+
+	wpt, trk := fc.Features[0], fc.Features[1]
+
+	if wpt.Geometry.Point != (geojson.Position{13.4, 52.5}) || wpt.Properties["name"] != "Camp" || wpt.Properties["ele"] != 34.5 {
+		t.Errorf("Unexpected waypoint %+v %v", wpt.Geometry, wpt.Properties)
+	}
+
+	times, _ := trk.Properties["coordTimes"].([]string)
+	if trk.Geometry.Type != geojson.TypeLineString || len(times) != 2 {
+		t.Errorf("Expected line with times, got %+v %v", trk.Geometry, trk.Properties)
+	}
+
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf)
+	for _, f := range []*geojson.Feature{trk, wpt} {
+		if err := w.Write(f); err != nil {
+			t.Fatalf("Write error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	out := buf.String()
+	if strings.Index(out, "<wpt") > strings.Index(out, "<trk>") {
+		t.Errorf("Expected waypoints before tracks, got %s", out)
+	}
+
+	fc2, err := Decode(buf)
+	if err != nil {
+		t.Fatalf("Decode of written gpx error: %v", err)
+	}
+
+	if len(fc2.Features) != 2 || fc2.Features[1].Properties["name"] != "Morning" {
+		t.Errorf("Unexpected round trip %s", out)
+	}
+
+	if times2, _ := fc2.Features[1].Properties["coordTimes"].([]string); len(times2) != 2 || times2[1] != times[1] {
+		t.Errorf("Expected times to survive round trip, got %v", times2)
+	}
+}
@@ -0,0 +1,233 @@
+// Package hexgrid hierarchical hexagonal grid of Lambert cylindrical equal-area plane, aperture 7 like H3.
+//
+// Resolutions 0..15 have H3 average edge lengths, each resolution is rotated by atan(sqrt(3)/5)
+// against the previous one, so centers of coarser cells are centers of finer cells and
+// 7 children cover their parent approximately.
+// The projection keeps area: cells of a resolution have the same area at any latitude,
+// they are stretched east-west and flattened north-south by 1/cos(lat) towards the poles.
+// Cell ids are not H3 ids.
+package hexgrid
+
+import (
+	"fmt"
+	"go-gis/internal/geo/geojson"
+	"math"
+	"strconv"
+)
+
+// MaxResolution finest resolution
+const MaxResolution = 15
+
+// earthRadius authalic sphere of WGS84, meters
+const earthRadius = 6371007.181
+
+// edge0 edge length of resolution 0, meters in plane
+const edge0 = 1107712.591
+
+// Cell id: resolution in bits 60..63, axial q and r in 30 bits each with offset
+type Cell uint64
+
+const (
+	axisBits   = 30
+	axisOffset = 1 << (axisBits - 1)
+	axisMask   = 1<<axisBits - 1
+)
+
+var (
+	sqrt3    = math.Sqrt(3)
+	sqrt7    = math.Sqrt(7)
+	rotation = math.Atan2(sqrt3, 5) // of odd resolutions
+)
+
+// EdgeLength of cell at resolution, meters in plane, on ground at the equator
+func EdgeLength(res int) float64 {
+	return edge0 / math.Pow(sqrt7, float64(res))
+}
+
+// FromLngLat cell containing position at resolution
+func FromLngLat(lng, lat float64, res int) Cell {
+
+	x, y := project(lng, lat)
+
+	return cellAt(x, y, res)
+}
+
+func cellAt(x, y float64, res int) Cell {
+
+	// frame of resolution
+	x, y = rotate(x, y, -angle(res))
+
+	a := spacing(res)
+	fq := (x - y/sqrt3) / a
+	fr := 2 * y / sqrt3 / a
+	fs := -fq - fr
+
+	q, r, s := math.Round(fq), math.Round(fr), math.Round(fs)
+	dq, dr, ds := math.Abs(q-fq), math.Abs(r-fr), math.Abs(s-fs)
+
+	switch {
+	case dq > dr && dq > ds:
+		q = -r - s
+	case dr > ds:
+		r = -q - s
+	}
+
+	return newCell(res, int64(q), int64(r))
+}
+
+func newCell(res int, q, r int64) Cell {
+	return Cell(uint64(res)<<(2*axisBits) | uint64(q+axisOffset)&axisMask<<axisBits | uint64(r+axisOffset)&axisMask)
+}
+
+// Parse hex text of cell
+func Parse(text string) (Cell, error) {
+
+	v, err := strconv.ParseUint(text, 16, 64)
+	if err != nil || !Cell(v).Valid() {
+		return 0, fmt.Errorf("invalid cell: %q", text)
+	}
+
+	return Cell(v), nil
+}
+
+func (x Cell) String() string { return fmt.Sprintf("%016x", uint64(x)) }
+
+// Resolution of cell
+func (x Cell) Resolution() int { return int(x >> (2 * axisBits)) }
+
+func (x Cell) axial() (int64, int64) {
+	return int64(x>>axisBits&axisMask) - axisOffset, int64(x&axisMask) - axisOffset
+}
+
+// Valid resolution in range and center in projected world
+func (x Cell) Valid() bool {
+
+	if x.Resolution() > MaxResolution {
+		return false
+	}
+
+	cx, cy := x.center()
+	a := spacing(x.Resolution())
+
+	return math.Abs(cx) <= math.Pi*earthRadius+a && math.Abs(cy) <= earthRadius+a
+}
+
+// center projected meters
+func (x Cell) center() (float64, float64) {
+
+	res := x.Resolution()
+	q, r := x.axial()
+	a := spacing(res)
+
+	return rotate(a*(float64(q)+float64(r)/2), a*float64(r)*sqrt3/2, angle(res))
+}
+
+// Center lng, lat
+func (x Cell) Center() (float64, float64) {
+	return unproject(x.center())
+}
+
+// Boundary closed ring of 6 vertices, counterclockwise, lng may be out of -180..180 at antimeridian,
+// vertices beyond the poles are clamped to them
+func (x Cell) Boundary() []geojson.Position {
+
+	res := x.Resolution()
+	cx, cy := x.center()
+	edge := spacing(res) / sqrt3
+
+	ring := make([]geojson.Position, 7)
+	for i := range 6 {
+		a := math.Pi/6 + float64(i)*math.Pi/3 + angle(res)
+		lng, lat := unproject(cx+edge*math.Cos(a), cy+edge*math.Sin(a))
+		ring[i] = geojson.Position{lng, lat}
+	}
+	ring[6] = ring[0]
+
+	return ring
+}
+
+// Area square meters, same for all cells of resolution, less for cells cut by the poles
+func (x Cell) Area() float64 {
+
+	edge := EdgeLength(x.Resolution())
+
+	return 3 * sqrt3 / 2 * edge * edge
+}
+
+// Parent at coarser resolution, cell itself if res is not coarser
+func (x Cell) Parent(res int) Cell {
+
+	if res >= x.Resolution() || res < 0 {
+		return x
+	}
+
+	cx, cy := x.center()
+
+	return cellAt(cx, cy, res)
+}
+
+// Children at next resolution, center child first, nil at MaxResolution
+func (x Cell) Children() []Cell {
+
+	res := x.Resolution()
+	if res >= MaxResolution {
+		return nil
+	}
+
+	cx, cy := x.center()
+
+	return cellAt(cx, cy, res+1).Ring(1)
+}
+
+// Ring cells within k steps, cell itself first
+func (x Cell) Ring(k int) []Cell {
+
+	res := x.Resolution()
+	q, r := x.axial()
+
+	cells := []Cell{x}
+	for dq := -k; dq <= k; dq++ {
+		for dr := max(-k, -dq-k); dr <= min(k, -dq+k); dr++ {
+			if dq != 0 || dr != 0 {
+				cells = append(cells, newCell(res, q+int64(dq), r+int64(dr)))
+			}
+		}
+	}
+
+	return cells
+}
+
+// RingSize count of cells of Ring(k)
+func RingSize(k int) int { return 3*k*(k+1) + 1 }
+
+// spacing distance of neighbor centers at resolution
+func spacing(res int) float64 { return EdgeLength(res) * sqrt3 }
+
+func angle(res int) float64 {
+	if res%2 == 1 {
+		return -rotation
+	}
+	return 0
+}
+
+func rotate(x, y, a float64) (float64, float64) {
+
+	sin, cos := math.Sincos(a)
+
+	return x*cos - y*sin, x*sin + y*cos
+}
+
+// project Lambert cylindrical equal-area, meters
+func project(lng, lat float64) (float64, float64) {
+
+	lat = min(max(lat, -90), 90)
+
+	return earthRadius * lng * math.Pi / 180, earthRadius * math.Sin(lat*math.Pi/180)
+}
+
+func unproject(x, y float64) (float64, float64) {
+
+	sin := min(max(y/earthRadius, -1), 1)
+
+	return x / earthRadius * 180 / math.Pi, math.Asin(sin) * 180 / math.Pi
+}
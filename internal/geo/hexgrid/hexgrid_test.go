@@ -0,0 +1,87 @@
+package hexgrid
+
+import (
+	"go-gis/internal/geo/geojson"
+	"go-gis/internal/geo/geom"
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+// Test center of cell is in cell, id round trip
+func TestFromLngLat(t *testing.T) {
+
+	for res := range MaxResolution + 1 {
+		for range 100 {
+			lng, lat := rand.Float64()*358-179, rand.Float64()*160-80
+
+			c := FromLngLat(lng, lat, res)
+			if c.Resolution() != res {
+				t.Fatalf("Expected resolution %v, got %v", res, c.Resolution())
+			}
+
+			clng, clat := c.Center()
+			if v := FromLngLat(clng, clat, res); v != c {
+				t.Fatalf("Expected center of %v in cell, got %v", c, v)
+			}
+
+			v, err := Parse(c.String())
+			if err != nil || v != c {
+				t.Fatalf("Expected %v, got %v %v", c, v, err)
+			}
+		}
+	}
+}
+
+// Test parent of children and edge length
+func TestHierarchy(t *testing.T) {
+
+	for res := range MaxResolution {
+
+		c := FromLngLat(13.4, 52.5, res)
+
+		children := c.Children()
+		if len(children) != 7 || children[0].Parent(res) != c {
+			t.Fatalf("Expected 7 children of %v, got %v", c, children)
+		}
+		for _, v := range children {
+			if v.Parent(res) != c {
+				t.Errorf("Expected parent %v of %v at resolution %v, got %v", c, v, res, v.Parent(res))
+			}
+		}
+
+		if res < 3 {
+			continue // edges of coarse cells bend
+		}
+
+		// equal area at any latitude
+		for _, lat := range []float64{0, 52.5, 75} {
+			v := FromLngLat(13.4, lat, res)
+			area := geom.Area(geojson.NewPolygon([][]geojson.Position{v.Boundary()}))
+			if math.Abs(area-v.Area())/v.Area() > 0.01 {
+				t.Errorf("Expected area %.0f at resolution %v lat %v, got %.0f", v.Area(), res, lat, area)
+			}
+		}
+	}
+}
+
+// Test ring sizes and invalid ids
+func TestRing(t *testing.T) {
+
+	c := FromLngLat(-74, 40.7, 9)
+	for k := range 4 {
+		if n := len(c.Ring(k)); n != RingSize(k) {
+			t.Errorf("Expected %v cells in ring %v, got %v", RingSize(k), k, n)
+		}
+	}
+
+	if len(c.Boundary()) != 7 {
+		t.Errorf("Expected closed ring of 6 vertices, got %v", c.Boundary())
+	}
+
+	for _, v := range []string{"", "zz", "ffffffffffffffff", "0000000000000000"} {
+		if _, err := Parse(v); err == nil {
+			t.Errorf("Expected %q invalid", v)
+		}
+	}
+}
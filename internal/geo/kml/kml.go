@@ -0,0 +1,526 @@
+// Package kml KML 2.2 decoder and encoder
+package kml
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"go-gis/internal/geo/geojson"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	MediaType   = "application/vnd.google-earth.kml+xml"
+	Namespace   = "http://www.opengis.net/kml/2.2"
+	NamespaceGx = "http://www.google.com/kml/ext/2.2"
+)
+
+// container Document or Folder
+type container struct {
+	Document  []container `xml:"Document"`
+	Folder    []container `xml:"Folder"`
+	Placemark []placemark `xml:"Placemark"`
+}
+
+type document struct {
+	XMLName xml.Name `xml:"kml"`
+	container
+}
+
+type placemark struct {
+	Name         string `xml:"name"`
+	Description  string `xml:"description"`
+	When         string `xml:"TimeStamp>when"`
+	ExtendedData []data `xml:"ExtendedData>Data"`
+	SimpleData   []data `xml:"ExtendedData>SchemaData>SimpleData"`
+	geometries
+}
+
+type data struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+	Text  string `xml:",chardata"` // SimpleData
+}
+
+// geometries of Placemark or MultiGeometry
+type geometries struct {
+	Point         []coords       `xml:"Point"`
+	LineString    []coords       `xml:"LineString"`
+	LinearRing    []coords       `xml:"LinearRing"`
+	Polygon       []polygon      `xml:"Polygon"`
+	Track         []gxTrack      `xml:"Track"`
+	MultiTrack    []gxMultiTrack `xml:"MultiTrack"`
+	MultiGeometry []geometries   `xml:"MultiGeometry"`
+}
+
+type coords struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type polygon struct {
+	Outer coords   `xml:"outerBoundaryIs>LinearRing"`
+	Inner []coords `xml:"innerBoundaryIs>LinearRing"`
+}
+
+type gxTrack struct {
+	When  []string `xml:"when"`
+	Coord []string `xml:"coord"` // "lng lat alt"
+}
+
+type gxMultiTrack struct {
+	Track []gxTrack `xml:"Track"`
+}
+
+// Decode placemarks of all documents and folders, gx:Track times are in "coordTimes" property,
+// ExtendedData is in properties
+func Decode(r io.Reader) (*geojson.FeatureCollection, error) {
+
+	doc := &document{}
+	if err := xml.NewDecoder(r).Decode(doc); err != nil {
+		return nil, fmt.Errorf("error on kml: %v", err)
+	}
+
+	res := geojson.NewFeatureCollection()
+
+	var walk func(c *container) error
+	walk = func(c *container) error {
+		for i := range c.Placemark {
+			f, err := c.Placemark[i].feature()
+			if err != nil {
+				return err
+			}
+			res.Features = append(res.Features, f)
+		}
+		for i := range c.Document {
+			if err := walk(&c.Document[i]); err != nil {
+				return err
+			}
+		}
+		for i := range c.Folder {
+			if err := walk(&c.Folder[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := walk(&doc.container); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (x *placemark) feature() (*geojson.Feature, error) {
+
+	items, times, err := x.geometries.collect()
+	if err != nil {
+		return nil, fmt.Errorf("error on placemark %q: %v", x.Name, err)
+	}
+
+	res := geojson.NewFeature(nil)
+
+	switch len(items) {
+	case 0:
+	case 1:
+		res.Geometry = items[0]
+		if len(times) == 1 && times[0] != nil {
+			res.Properties["coordTimes"] = times[0]
+		}
+	default:
+		res.Geometry = combine(items)
+		if res.Geometry.Type == geojson.TypeMultiLineString && hasTimes(times) {
+			res.Properties["coordTimes"] = times
+		}
+	}
+
+	for _, d := range x.ExtendedData {
+		res.Properties[d.Name] = strings.TrimSpace(d.Value)
+	}
+	for _, d := range x.SimpleData {
+		res.Properties[d.Name] = strings.TrimSpace(d.Text)
+	}
+	if v := strings.TrimSpace(x.Name); v != "" {
+		res.Properties["name"] = v
+	}
+	if v := strings.TrimSpace(x.Description); v != "" {
+		res.Properties["description"] = v
+	}
+	if v := strings.TrimSpace(x.When); v != "" {
+		res.Properties["time"] = v
+	}
+
+	return res, nil
+}
+
+// collect flat list of geometries, times per geometry (nil if none)
+func (x *geometries) collect() ([]*geojson.Geometry, [][]string, error) {
+
+	res := []*geojson.Geometry{}
+	times := [][]string{}
+
+	add := func(g *geojson.Geometry, t []string) {
+		res = append(res, g)
+		times = append(times, t)
+	}
+
+	for _, v := range x.Point {
+		p, err := parseCoordinates(v.Coordinates)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(p) != 1 {
+			return nil, nil, fmt.Errorf("point must have 1 position")
+		}
+		add(geojson.NewPoint(p[0].Lng(), p[0].Lat()), nil)
+	}
+	for _, v := range append(x.LineString, x.LinearRing...) {
+		p, err := parseCoordinates(v.Coordinates)
+		if err != nil {
+			return nil, nil, err
+		}
+		add(geojson.NewLineString(p), nil)
+	}
+	for _, v := range x.Polygon {
+		rings := [][]geojson.Position{}
+		for _, c := range append([]coords{v.Outer}, v.Inner...) {
+			p, err := parseCoordinates(c.Coordinates)
+			if err != nil {
+				return nil, nil, err
+			}
+			rings = append(rings, p)
+		}
+		add(geojson.NewPolygon(rings), nil)
+	}
+
+	tracks := x.Track
+	for _, v := range x.MultiTrack {
+		tracks = append(tracks, v.Track...)
+	}
+	for _, v := range tracks {
+		p, err := parseTrack(v.Coord)
+		if err != nil {
+			return nil, nil, err
+		}
+		var t []string
+		if len(v.When) == len(p) {
+			t = v.When
+		}
+		add(geojson.NewLineString(p), t)
+	}
+
+	for i := range x.MultiGeometry {
+		items, t, err := x.MultiGeometry[i].collect()
+		if err != nil {
+			return nil, nil, err
+		}
+		res = append(res, items...)
+		times = append(times, t...)
+	}
+
+	return res, times, nil
+}
+
+// combine geometries to Multi* of same type or GeometryCollection
+func combine(items []*geojson.Geometry) *geojson.Geometry {
+
+	kind := items[0].Type
+	for _, g := range items {
+		if g.Type != kind {
+			kind = ""
+			break
+		}
+	}
+
+	res := &geojson.Geometry{}
+
+	switch kind {
+	case geojson.TypePoint:
+		res.Type = geojson.TypeMultiPoint
+		for _, g := range items {
+			res.MultiPoint = append(res.MultiPoint, g.Point)
+		}
+	case geojson.TypeLineString:
+		res.Type = geojson.TypeMultiLineString
+		for _, g := range items {
+			res.MultiLineString = append(res.MultiLineString, g.LineString)
+		}
+	case geojson.TypePolygon:
+		res.Type = geojson.TypeMultiPolygon
+		for _, g := range items {
+			res.MultiPolygon = append(res.MultiPolygon, g.Polygon)
+		}
+	default:
+		res.Type = geojson.TypeGeometryCollection
+		res.Geometries = items
+	}
+
+	return res
+}
+
+func hasTimes(times [][]string) bool {
+
+	for _, t := range times {
+		if t != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseCoordinates "lng,lat[,alt] lng,lat[,alt] ..."
+func parseCoordinates(text string) ([]geojson.Position, error) {
+
+	res := []geojson.Position{}
+
+	for _, tuple := range strings.Fields(text) {
+		parts := strings.Split(tuple, ",")
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("invalid coordinates: %q", tuple)
+		}
+		p, err := parsePosition(parts[0], parts[1])
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, p)
+	}
+
+	return res, nil
+}
+
+// parseTrack gx:coord "lng lat alt" values
+func parseTrack(items []string) ([]geojson.Position, error) {
+
+	res := make([]geojson.Position, 0, len(items))
+
+	for _, v := range items {
+		parts := strings.Fields(v)
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("invalid gx:coord: %q", v)
+		}
+		p, err := parsePosition(parts[0], parts[1])
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, p)
+	}
+
+	return res, nil
+}
+
+func parsePosition(lng, lat string) (geojson.Position, error) {
+
+	x, err := strconv.ParseFloat(lng, 64)
+	if err != nil {
+		return geojson.Position{}, fmt.Errorf("invalid longitude: %q", lng)
+	}
+	y, err := strconv.ParseFloat(lat, 64)
+	if err != nil {
+		return geojson.Position{}, fmt.Errorf("invalid latitude: %q", lat)
+	}
+
+	return geojson.Position{x, y}, nil
+}
+
+// Writer stream features as KML Document, Close must be called
+type Writer struct {
+	w     io.Writer
+	count int
+	err   error
+}
+
+// NewWriter stream writer
+func NewWriter(w io.Writer) *Writer {
+
+	res := &Writer{w: w}
+	res.write(xml.Header + `<kml xmlns="` + Namespace + `" xmlns:gx="` + NamespaceGx + `">` + "\n<Document>\n")
+
+	return res
+}
+
+func (x *Writer) write(s string) {
+	if x.err == nil {
+		_, x.err = io.WriteString(x.w, s)
+	}
+}
+
+func escape(s string) string {
+
+	b := &strings.Builder{}
+	_ = xml.EscapeText(b, []byte(s))
+
+	return b.String()
+}
+
+// Write append feature as Placemark
+func (x *Writer) Write(f *geojson.Feature) error {
+
+	if x.err != nil {
+		return x.err
+	}
+
+	b := &strings.Builder{}
+	b.WriteString("<Placemark>")
+
+	if v, ok := f.Properties["name"].(string); ok {
+		b.WriteString("<name>" + escape(v) + "</name>")
+	}
+	desc, ok := f.Properties["description"].(string)
+	if !ok {
+		desc, _ = f.Properties["desc"].(string)
+	}
+	if desc != "" {
+		b.WriteString("<description>" + escape(desc) + "</description>")
+	}
+	if v, ok := f.Properties["time"].(string); ok && f.Geometry != nil && f.Geometry.Type == geojson.TypePoint {
+		b.WriteString("<TimeStamp><when>" + escape(v) + "</when></TimeStamp>")
+	}
+
+	keys := make([]string, 0, len(f.Properties))
+	for k := range f.Properties {
+		switch k {
+		case "name", "description", "desc", "coordTimes":
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	if len(keys) > 0 {
+		b.WriteString("<ExtendedData>")
+		for _, k := range keys {
+			b.WriteString(`<Data name="` + escape(k) + `"><value>` + escape(text(f.Properties[k])) + "</value></Data>")
+		}
+		b.WriteString("</ExtendedData>")
+	}
+
+	if f.Geometry != nil {
+		writeGeometry(b, f.Geometry, geojson.CoordTimes(f.Properties["coordTimes"]))
+	}
+
+	b.WriteString("</Placemark>\n")
+
+	x.write(b.String())
+	x.count++
+
+	return x.err
+}
+
+func writeGeometry(b *strings.Builder, g *geojson.Geometry, times [][]string) {
+
+	line := func(tag string, arr []geojson.Position) {
+		b.WriteString("<" + tag + "><coordinates>")
+		for i, p := range arr {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(strconv.FormatFloat(p.Lng(), 'f', -1, 64) + "," + strconv.FormatFloat(p.Lat(), 'f', -1, 64))
+		}
+		b.WriteString("</coordinates></" + tag + ">")
+	}
+
+	// track with times when count matches
+	track := func(arr []geojson.Position, t []string) {
+		if len(t) != len(arr) {
+			line("LineString", arr)
+			return
+		}
+		b.WriteString("<gx:Track>")
+		for _, v := range t {
+			b.WriteString("<when>" + escape(v) + "</when>")
+		}
+		for _, p := range arr {
+			b.WriteString("<gx:coord>" + strconv.FormatFloat(p.Lng(), 'f', -1, 64) + " " + strconv.FormatFloat(p.Lat(), 'f', -1, 64) + "</gx:coord>")
+		}
+		b.WriteString("</gx:Track>")
+	}
+
+	polygon := func(rings [][]geojson.Position) {
+		b.WriteString("<Polygon>")
+		for i, r := range rings {
+			tag := "innerBoundaryIs"
+			if i == 0 {
+				tag = "outerBoundaryIs"
+			}
+			b.WriteString("<" + tag + ">")
+			line("LinearRing", r)
+			b.WriteString("</" + tag + ">")
+		}
+		b.WriteString("</Polygon>")
+	}
+
+	timesAt := func(i int) []string {
+		if i < len(times) {
+			return times[i]
+		}
+		return nil
+	}
+
+	switch g.Type {
+	case geojson.TypePoint:
+		line("Point", []geojson.Position{g.Point})
+	case geojson.TypeLineString:
+		track(g.LineString, timesAt(0))
+	case geojson.TypePolygon:
+		polygon(g.Polygon)
+	case geojson.TypeMultiPoint:
+		b.WriteString("<MultiGeometry>")
+		for _, p := range g.MultiPoint {
+			line("Point", []geojson.Position{p})
+		}
+		b.WriteString("</MultiGeometry>")
+	case geojson.TypeMultiLineString:
+		b.WriteString("<MultiGeometry>")
+		for i, l := range g.MultiLineString {
+			track(l, timesAt(i))
+		}
+		b.WriteString("</MultiGeometry>")
+	case geojson.TypeMultiPolygon:
+		b.WriteString("<MultiGeometry>")
+		for _, p := range g.MultiPolygon {
+			polygon(p)
+		}
+		b.WriteString("</MultiGeometry>")
+	case geojson.TypeGeometryCollection:
+		b.WriteString("<MultiGeometry>")
+		for _, v := range g.Geometries {
+			if v != nil {
+				writeGeometry(b, v, nil)
+			}
+		}
+		b.WriteString("</MultiGeometry>")
+	}
+}
+
+// text property value for ExtendedData
+func text(v any) string {
+
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// Count written features
+func (x *Writer) Count() int { return x.count }
+
+// Close end document
+func (x *Writer) Close() error {
+
+	x.write("</Document>\n</kml>\n")
+
+	return x.err
+}
@@ -0,0 +1,78 @@
+package kml
+
+import (
+	"bytes"
+	"go-gis/internal/geo/geojson"
+	"strings"
+	"testing"
+)
+
+const testKML = `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2" xmlns:gx="http://www.google.com/kml/ext/2.2">
+<Document><Folder>
+  <Placemark><name>Lake</name>
+    <ExtendedData><Data name="depth"><value>12</value></Data></ExtendedData>
+    <Polygon>
+      <outerBoundaryIs><LinearRing><coordinates>0,0,0 1,0,0 1,1,0 0,0,0</coordinates></LinearRing></outerBoundaryIs>
+    </Polygon>
+  </Placemark>
+  <Placemark><name>Walk</name>
+    <gx:Track>
+      <when>2024-05-01T08:00:00Z</when><when>2024-05-01T08:01:00Z</when>
+      <gx:coord>13.40 52.50 30</gx:coord><gx:coord>13.41 52.51 31</gx:coord>
+    </gx:Track>
+  </Placemark>
+  <Placemark><MultiGeometry><Point><coordinates>1,2</coordinates></Point><Point><coordinates>3,4</coordinates></Point></MultiGeometry></Placemark>
+</Folder></Document>
+</kml>`
+
+// Test decode and encode round trip
+func TestRoundTrip(t *testing.T) {
+
+	fc, err := Decode(strings.NewReader(testKML))
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+
+	if len(fc.Features) != 3 {
+		t.Fatalf("Expected 3 features, got %v", len(fc.Features))
+	}
+
+	lake, walk, multi := fc.Features[0], fc.Features[1], fc.Features[2]
+
+	if lake.Geometry.Type != geojson.TypePolygon || lake.Properties["depth"] != "12" || lake.Properties["name"] != "Lake" {
+		t.Errorf("Unexpected polygon %+v %v", lake.Geometry, lake.Properties)
+	}
+
+	if walk.Geometry.Type != geojson.TypeLineString || len(walk.Properties["coordTimes"].([]string)) != 2 {
+		t.Errorf("Unexpected track %+v %v", walk.Geometry, walk.Properties)
+	}
+
+	if multi.Geometry.Type != geojson.TypeMultiPoint || len(multi.Geometry.MultiPoint) != 2 {
+		t.Errorf("Unexpected multi geometry %+v", multi.Geometry)
+	}
+
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf)
+	for _, f := range fc.Features {
+		if err := w.Write(f); err != nil {
+			t.Fatalf("Write error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	fc2, err := Decode(buf)
+	if err != nil {
+		t.Fatalf("Decode of written kml error: %v", err)
+	}
+
+	if len(fc2.Features) != 3 || fc2.Features[0].Properties["depth"] != "12" {
+		t.Fatalf("Unexpected round trip %+v", fc2.Features)
+	}
+
+	if times, _ := fc2.Features[1].Properties["coordTimes"].([]string); len(times) != 2 {
+		t.Errorf("Expected gx:Track with times, got %v", fc2.Features[1].Properties)
+	}
+}
@@ -0,0 +1,168 @@
+package proj
+
+import (
+	"fmt"
+	"sync"
+)
+
+// CRS coordinate reference system, geographic when Projection is nil
+type CRS struct {
+	Code       int
+	Name       string
+	Ellipsoid  Ellipsoid
+	ToWGS84    *Helmert // nil if datum is WGS84 compatible
+	Projection Projection
+}
+
+// Geographic axis order is lng, lat in degrees
+func (x *CRS) Geographic() bool { return x.Projection == nil }
+
+// toWGS84 lng, lat on WGS84
+func (x *CRS) toWGS84(cx, cy float64) (float64, float64, error) {
+
+	lng, lat := cx, cy
+
+	if x.Projection != nil {
+		var err error
+		if lng, lat, err = x.Projection.Inverse(cx, cy); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if x.ToWGS84 != nil {
+		ex, ey, ez := x.Ellipsoid.ToECEF(lng*deg2rad, lat*deg2rad)
+		ex, ey, ez = x.ToWGS84.apply(ex, ey, ez, false)
+		lng, lat = WGS84.FromECEF(ex, ey, ez)
+		lng, lat = lng*rad2deg, lat*rad2deg
+	}
+
+	return lng, lat, nil
+}
+
+// fromWGS84 lng, lat on WGS84 to coordinates of crs
+func (x *CRS) fromWGS84(lng, lat float64) (float64, float64, error) {
+
+	if x.ToWGS84 != nil {
+		ex, ey, ez := WGS84.ToECEF(lng*deg2rad, lat*deg2rad)
+		ex, ey, ez = x.ToWGS84.apply(ex, ey, ez, true)
+		lng, lat = x.Ellipsoid.FromECEF(ex, ey, ez)
+		lng, lat = lng*rad2deg, lat*rad2deg
+	}
+
+	if x.Projection != nil {
+		return x.Projection.Forward(lng, lat)
+	}
+
+	return lng, lat, nil
+}
+
+// Transform coordinates between systems through WGS84
+func Transform(from, to *CRS, cx, cy float64) (float64, float64, error) {
+
+	if from.Code == to.Code && from.Code != 0 {
+		return cx, cy, nil
+	}
+
+	lng, lat, err := from.toWGS84(cx, cy)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return to.fromWGS84(lng, lat)
+}
+
+var osgb36 = &Helmert{TX: 446.448, TY: -125.157, TZ: 542.06, RX: 0.15, RY: 0.247, RZ: 0.842, S: -20.489}
+
+// Registry systems by EPSG code, UTM zones are built on demand
+type Registry struct {
+	mu    sync.RWMutex
+	items map[int]*CRS
+}
+
+// NewRegistry registry with built-in systems:
+// 4326 4258 4269 4277 3857 326xx 327xx 258xx 269xx 27700 2154
+func NewRegistry() *Registry {
+
+	res := &Registry{items: map[int]*CRS{}}
+
+	res.items[4326] = &CRS{Code: 4326, Name: "WGS 84", Ellipsoid: WGS84}
+	res.items[4258] = &CRS{Code: 4258, Name: "ETRS89", Ellipsoid: GRS80}
+	res.items[4269] = &CRS{Code: 4269, Name: "NAD83", Ellipsoid: GRS80}
+	res.items[4277] = &CRS{Code: 4277, Name: "OSGB36", Ellipsoid: Airy1830, ToWGS84: osgb36}
+	res.items[3857] = &CRS{Code: 3857, Name: "WGS 84 / Pseudo-Mercator", Ellipsoid: WGS84, Projection: WebMercator{}}
+	res.items[27700] = &CRS{Code: 27700, Name: "OSGB36 / British National Grid", Ellipsoid: Airy1830, ToWGS84: osgb36,
+		Projection: NewTransverseMercator(Airy1830, 49, -2, 0.9996012717, 400000, -100000)}
+
+	lambert93, _ := NewLambertConformalConic(GRS80, 46.5, 3, 49, 44, 1, 700000, 6600000)
+	res.items[2154] = &CRS{Code: 2154, Name: "RGF93 / Lambert-93", Ellipsoid: GRS80, Projection: lambert93}
+
+	return res
+}
+
+// Add register system, replaces existing code
+func (x *Registry) Add(crs *CRS) {
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.items[crs.Code] = crs
+}
+
+// Lookup system by EPSG code
+func (x *Registry) Lookup(code int) (*CRS, error) {
+
+	x.mu.RLock()
+	res, ok := x.items[code]
+	x.mu.RUnlock()
+
+	if ok {
+		return res, nil
+	}
+
+	res, err := utm(code)
+	if err != nil {
+		return nil, err
+	}
+
+	x.Add(res)
+
+	return res, nil
+}
+
+// utm WGS84 326zz 327zz, ETRS89 258zz, NAD83 269zz
+func utm(code int) (*CRS, error) {
+
+	zone := code % 100
+	south := false
+	ell := WGS84
+	name := "WGS 84"
+
+	switch code / 100 {
+	case 326:
+	case 327:
+		south = true
+	case 258:
+		ell, name = GRS80, "ETRS89"
+	case 269:
+		ell, name = GRS80, "NAD83"
+	default:
+		return nil, fmt.Errorf("error unknown crs: EPSG:%v", code)
+	}
+
+	p, err := NewUTM(ell, zone, south)
+	if err != nil {
+		return nil, fmt.Errorf("error unknown crs: EPSG:%v", code)
+	}
+
+	hemisphere := "N"
+	if south {
+		hemisphere = "S"
+	}
+
+	return &CRS{
+		Code:       code,
+		Name:       fmt.Sprintf("%v / UTM zone %v%v", name, zone, hemisphere),
+		Ellipsoid:  ell,
+		Projection: p,
+	}, nil
+}
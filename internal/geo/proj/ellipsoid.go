@@ -0,0 +1,133 @@
+// Package proj coordinate reference systems and projections
+package proj
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	deg2rad = math.Pi / 180
+	rad2deg = 180 / math.Pi
+	arcsec  = deg2rad / 3600
+)
+
+// Ellipsoid reference ellipsoid by semi-major axis and flattening
+type Ellipsoid struct {
+	A float64
+	F float64
+}
+
+var (
+	WGS84    = Ellipsoid{A: 6378137, F: 1 / 298.257223563}
+	GRS80    = Ellipsoid{A: 6378137, F: 1 / 298.257222101}
+	Airy1830 = Ellipsoid{A: 6377563.396, F: 1 / 299.3249646}
+	Intl1924 = Ellipsoid{A: 6378388, F: 1 / 297}
+	Clarke66 = Ellipsoid{A: 6378206.4, F: 1 / 294.978698214}
+	Bessel41 = Ellipsoid{A: 6377397.155, F: 1 / 299.1528128}
+)
+
+var ellipsoids = map[string]Ellipsoid{
+	"WGS84":    WGS84,
+	"GRS80":    GRS80,
+	"AIRY1830": Airy1830,
+	"INTL1924": Intl1924,
+	"CLARKE66": Clarke66,
+	"BESSEL41": Bessel41,
+}
+
+// EllipsoidByName WGS84, GRS80, Airy1830, Intl1924, Clarke66, Bessel41
+func EllipsoidByName(name string) (Ellipsoid, error) {
+
+	res, ok := ellipsoids[strings.ToUpper(strings.ReplaceAll(name, "_", ""))]
+	if !ok {
+		return Ellipsoid{}, fmt.Errorf("error unknown ellipsoid: %v", name)
+	}
+
+	return res, nil
+}
+
+// E2 first eccentricity squared
+func (x Ellipsoid) E2() float64 { return x.F * (2 - x.F) }
+
+// E first eccentricity
+func (x Ellipsoid) E() float64 { return math.Sqrt(x.E2()) }
+
+// ToECEF geodetic radians to earth-centered cartesian, height 0
+func (x Ellipsoid) ToECEF(lng, lat float64) (float64, float64, float64) {
+
+	e2 := x.E2()
+	sinLat := math.Sin(lat)
+	n := x.A / math.Sqrt(1-e2*sinLat*sinLat)
+
+	return n * math.Cos(lat) * math.Cos(lng),
+		n * math.Cos(lat) * math.Sin(lng),
+		n * (1 - e2) * sinLat
+}
+
+// FromECEF earth-centered cartesian to geodetic radians, height is dropped
+func (x Ellipsoid) FromECEF(cx, cy, cz float64) (float64, float64) {
+
+	e2 := x.E2()
+	p := math.Hypot(cx, cy)
+	lat := math.Atan2(cz, p*(1-e2))
+
+	for range 10 {
+		sinLat := math.Sin(lat)
+		n := x.A / math.Sqrt(1-e2*sinLat*sinLat)
+		h := p/math.Cos(lat) - n
+		next := math.Atan2(cz, p*(1-e2*n/(n+h)))
+		if math.Abs(next-lat) < 1e-14 {
+			lat = next
+			break
+		}
+		lat = next
+	}
+
+	return math.Atan2(cy, cx), lat
+}
+
+// Helmert 7-parameter datum shift to WGS84, position vector convention as proj "towgs84":
+// translations in meters, rotations in arc seconds, scale in ppm
+type Helmert struct {
+	TX, TY, TZ float64
+	RX, RY, RZ float64
+	S          float64
+}
+
+// NewHelmert from towgs84 values, 3 or 7 items
+func NewHelmert(values []float64) (*Helmert, error) {
+
+	switch len(values) {
+	case 0:
+		return nil, nil
+	case 3:
+		return &Helmert{TX: values[0], TY: values[1], TZ: values[2]}, nil
+	case 7:
+		return &Helmert{
+			TX: values[0], TY: values[1], TZ: values[2],
+			RX: values[3], RY: values[4], RZ: values[5],
+			S: values[6],
+		}, nil
+	}
+
+	return nil, fmt.Errorf("error towgs84 must have 3 or 7 values")
+}
+
+// apply shift, inverse=true for WGS84 to datum (small rotation approximation)
+func (x *Helmert) apply(cx, cy, cz float64, inverse bool) (float64, float64, float64) {
+
+	sign := 1.0
+	if inverse {
+		sign = -1
+	}
+
+	tx, ty, tz := sign*x.TX, sign*x.TY, sign*x.TZ
+	rx, ry, rz := sign*x.RX*arcsec, sign*x.RY*arcsec, sign*x.RZ*arcsec
+	s := 1 + sign*x.S*1e-6
+
+	return tx + s*(cx-rz*cy+ry*cz),
+		ty + s*(rz*cx+cy-rx*cz),
+		tz + s*(-ry*cx+rx*cy+cz)
+}
@@ -0,0 +1,94 @@
+package proj
+
+import (
+	"math"
+	"testing"
+)
+
+func dms(d, m, s float64) float64 { return d + m/60 + s/3600 }
+
+// Test OS worked example of transverse mercator on Airy 1830
+func TestTransverseMercatorOSGB(t *testing.T) {
+
+	p := NewTransverseMercator(Airy1830, 49, -2, 0.9996012717, 400000, -100000)
+
+	lat, lng := dms(52, 39, 27.2531), dms(1, 43, 4.5177)
+
+	e, n, err := p.Forward(lng, lat)
+	if err != nil {
+		t.Fatalf("Forward error: %v", err)
+	}
+
+	if math.Abs(e-651409.903) > 0.001 || math.Abs(n-313177.270) > 0.001 {
+		t.Errorf("Expected E 651409.903 N 313177.270, got %.3f %.3f", e, n)
+	}
+
+	lng2, lat2, _ := p.Inverse(e, n)
+	if math.Abs(lng2-lng) > 1e-8 || math.Abs(lat2-lat) > 1e-8 {
+		t.Errorf("Expected inverse %v %v, got %v %v", lng, lat, lng2, lat2)
+	}
+}
+
+// Test Lambert-93 origin
+func TestLambert93(t *testing.T) {
+
+	r := NewRegistry()
+	l93, err := r.Lookup(2154)
+	if err != nil {
+		t.Fatalf("Lookup error: %v", err)
+	}
+
+	wgs84, _ := r.Lookup(4326)
+
+	x, y, err := Transform(wgs84, l93, 3, 46.5)
+	if err != nil {
+		t.Fatalf("Transform error: %v", err)
+	}
+
+	if math.Abs(x-700000) > 0.001 || math.Abs(y-6600000) > 0.001 {
+		t.Errorf("Expected 700000 6600000, got %.3f %.3f", x, y)
+	}
+
+	lng, lat, _ := Transform(l93, wgs84, x, y)
+	if math.Abs(lng-3) > 1e-9 || math.Abs(lat-46.5) > 1e-9 {
+		t.Errorf("Expected inverse 3 46.5, got %v %v", lng, lat)
+	}
+}
+
+// Test UTM, web mercator and datum shift round trips
+func TestRoundTrip(t *testing.T) {
+
+	r := NewRegistry()
+	wgs84, _ := r.Lookup(4326)
+
+	items := []struct {
+		code     int
+		lng, lat float64
+	}{
+		{code: 32633, lng: 15.5, lat: 52.1},
+		{code: 32719, lng: -70.6, lat: -33.4},
+		{code: 3857, lng: -122.4, lat: 37.8},
+		{code: 27700, lng: -0.1276, lat: 51.5072},
+	}
+
+	for _, itm := range items {
+		crs, err := r.Lookup(itm.code)
+		if err != nil {
+			t.Fatalf("Lookup %v error: %v", itm.code, err)
+		}
+
+		x, y, err := Transform(wgs84, crs, itm.lng, itm.lat)
+		if err != nil {
+			t.Fatalf("Transform %v error: %v", itm.code, err)
+		}
+
+		lng, lat, _ := Transform(crs, wgs84, x, y)
+		if math.Abs(lng-itm.lng) > 1e-7 || math.Abs(lat-itm.lat) > 1e-7 {
+			t.Errorf("EPSG:%v expected %v %v, got %v %v", itm.code, itm.lng, itm.lat, lng, lat)
+		}
+	}
+
+	if _, err := r.Lookup(32661); err == nil {
+		t.Error("Expected error for zone 61")
+	}
+}
@@ -0,0 +1,259 @@
+package proj
+
+import (
+	"fmt"
+	"math"
+)
+
+// Projection geodetic degrees on ellipsoid of CRS to projected meters and back
+type Projection interface {
+	Forward(lng, lat float64) (x, y float64, err error)
+	Inverse(x, y float64) (lng, lat float64, err error)
+}
+
+// webMercatorMaxLat latitude of square world
+const webMercatorMaxLat = 85.05112877980659
+
+// WebMercator spherical mercator EPSG:3857 on WGS84 semi-major axis
+type WebMercator struct{}
+
+func (WebMercator) Forward(lng, lat float64) (float64, float64, error) {
+
+	lat = max(-webMercatorMaxLat, min(webMercatorMaxLat, lat))
+
+	return WGS84.A * lng * deg2rad,
+		WGS84.A * math.Log(math.Tan(math.Pi/4+lat*deg2rad/2)), nil
+}
+
+func (WebMercator) Inverse(x, y float64) (float64, float64, error) {
+
+	return x / WGS84.A * rad2deg,
+		(2*math.Atan(math.Exp(y/WGS84.A)) - math.Pi/2) * rad2deg, nil
+}
+
+// TransverseMercator ellipsoidal transverse mercator, Krüger series to n^4
+type TransverseMercator struct {
+	lon0, k0, x0, y0 float64
+	e                float64
+	scale            float64 // k0 * rectifying radius
+	m0               float64 // northing of lat0 on central meridian
+	alpha, beta      [4]float64
+	delta            [4]float64
+}
+
+// NewTransverseMercator origin in degrees, k0 scale on central meridian, false easting/northing
+func NewTransverseMercator(ell Ellipsoid, lat0, lon0, k0, x0, y0 float64) *TransverseMercator {
+
+	n := ell.F / (2 - ell.F)
+	n2, n3, n4 := n*n, n*n*n, n*n*n*n
+
+	res := &TransverseMercator{
+		lon0: lon0 * deg2rad,
+		k0:   k0,
+		x0:   x0,
+		y0:   y0,
+		e:    ell.E(),
+	}
+
+	res.scale = k0 * ell.A / (1 + n) * (1 + n2/4 + n4/64)
+
+	res.alpha = [4]float64{
+		n/2 - 2*n2/3 + 5*n3/16 + 41*n4/180,
+		13*n2/48 - 3*n3/5 + 557*n4/1440,
+		61*n3/240 - 103*n4/140,
+		49561 * n4 / 161280,
+	}
+	res.beta = [4]float64{
+		n/2 - 2*n2/3 + 37*n3/96 - n4/360,
+		n2/48 + n3/15 - 437*n4/1440,
+		17*n3/480 - 37*n4/840,
+		4397 * n4 / 161280,
+	}
+	res.delta = [4]float64{
+		2*n - 2*n2/3 - 2*n3 + 116*n4/45,
+		7*n2/3 - 8*n3/5 - 227*n4/45,
+		56*n3/15 - 136*n4/35,
+		4279 * n4 / 630,
+	}
+
+	_, res.m0 = res.project(0, lat0*deg2rad)
+
+	return res
+}
+
+// NewUTM zone 1..60, south hemisphere with false northing 10000000
+func NewUTM(ell Ellipsoid, zone int, south bool) (*TransverseMercator, error) {
+
+	if zone < 1 || zone > 60 {
+		return nil, fmt.Errorf("error utm zone out of range: %v", zone)
+	}
+
+	y0 := 0.0
+	if south {
+		y0 = 10000000
+	}
+
+	return NewTransverseMercator(ell, 0, float64(zone)*6-183, 0.9996, 500000, y0), nil
+}
+
+// project radians relative to central meridian to unshifted easting, northing
+func (x *TransverseMercator) project(dLng, lat float64) (float64, float64) {
+
+	sinLat := math.Sin(lat)
+	t := math.Sinh(math.Atanh(sinLat) - x.e*math.Atanh(x.e*sinLat))
+	xi := math.Atan2(t, math.Cos(dLng))
+	eta := math.Atanh(math.Sin(dLng) / math.Sqrt(1+t*t))
+
+	e, n := eta, xi
+	for j, a := range x.alpha {
+		k := float64(2 * (j + 1))
+		e += a * math.Cos(k*xi) * math.Sinh(k*eta)
+		n += a * math.Sin(k*xi) * math.Cosh(k*eta)
+	}
+
+	return x.scale * e, x.scale * n
+}
+
+func (x *TransverseMercator) Forward(lng, lat float64) (float64, float64, error) {
+
+	if math.Abs(lat) > 90 {
+		return 0, 0, fmt.Errorf("error latitude out of range: %v", lat)
+	}
+
+	dLng := math.Remainder(lng*deg2rad-x.lon0, 2*math.Pi)
+	if math.Abs(dLng) >= math.Pi/2 {
+		return 0, 0, fmt.Errorf("error longitude too far from central meridian: %v", lng)
+	}
+
+	e, n := x.project(dLng, lat*deg2rad)
+
+	return x.x0 + e, x.y0 + n - x.m0, nil
+}
+
+func (x *TransverseMercator) Inverse(e, n float64) (float64, float64, error) {
+
+	xi := (n - x.y0 + x.m0) / x.scale
+	eta := (e - x.x0) / x.scale
+
+	xi1, eta1 := xi, eta
+	for j, b := range x.beta {
+		k := float64(2 * (j + 1))
+		xi1 -= b * math.Sin(k*xi) * math.Cosh(k*eta)
+		eta1 -= b * math.Cos(k*xi) * math.Sinh(k*eta)
+	}
+
+	chi := math.Asin(math.Sin(xi1) / math.Cosh(eta1))
+	lat := chi
+	for j, d := range x.delta {
+		lat += d * math.Sin(float64(2*(j+1))*chi)
+	}
+
+	lng := x.lon0 + math.Atan2(math.Sinh(eta1), math.Cos(xi1))
+
+	return lng * rad2deg, lat * rad2deg, nil
+}
+
+// LambertConformalConic ellipsoidal lambert conformal conic, 1SP when lat1 == lat2
+type LambertConformalConic struct {
+	a, e       float64
+	lon0       float64
+	n, f, rho0 float64
+	x0, y0     float64
+}
+
+// NewLambertConformalConic standard parallels lat1 lat2, origin lat0 lon0 in degrees,
+// k0 scale (1 for 2SP), false easting/northing
+func NewLambertConformalConic(ell Ellipsoid, lat0, lon0, lat1, lat2, k0, x0, y0 float64) (*LambertConformalConic, error) {
+
+	if lat1 == 0 && lat2 == 0 {
+		lat1, lat2 = lat0, lat0
+	}
+
+	if math.Abs(lat1+lat2) < 1e-10 {
+		return nil, fmt.Errorf("error standard parallels are symmetric to equator")
+	}
+
+	if k0 == 0 {
+		k0 = 1
+	}
+
+	res := &LambertConformalConic{a: ell.A, e: ell.E(), lon0: lon0 * deg2rad, x0: x0, y0: y0}
+
+	phi1, phi2 := lat1*deg2rad, lat2*deg2rad
+	m1, m2 := res.m(phi1), res.m(phi2)
+	t1, t2 := res.t(phi1), res.t(phi2)
+
+	if math.Abs(phi1-phi2) < 1e-12 {
+		res.n = math.Sin(phi1)
+	} else {
+		res.n = (math.Log(m1) - math.Log(m2)) / (math.Log(t1) - math.Log(t2))
+	}
+
+	res.f = k0 * m1 / (res.n * math.Pow(t1, res.n))
+	res.rho0 = res.a * res.f * math.Pow(res.t(lat0*deg2rad), res.n)
+
+	return res, nil
+}
+
+func (x *LambertConformalConic) m(phi float64) float64 {
+	s := x.e * math.Sin(phi)
+	return math.Cos(phi) / math.Sqrt(1-s*s)
+}
+
+func (x *LambertConformalConic) t(phi float64) float64 {
+	s := x.e * math.Sin(phi)
+	return math.Tan(math.Pi/4-phi/2) / math.Pow((1-s)/(1+s), x.e/2)
+}
+
+func (x *LambertConformalConic) Forward(lng, lat float64) (float64, float64, error) {
+
+	if math.Abs(lat) > 90 {
+		return 0, 0, fmt.Errorf("error latitude out of range: %v", lat)
+	}
+
+	phi := lat * deg2rad
+	rho := 0.0
+	if math.Abs(math.Abs(phi)-math.Pi/2) > 1e-12 {
+		rho = x.a * x.f * math.Pow(x.t(phi), x.n)
+	} else if phi*x.n < 0 {
+		return 0, 0, fmt.Errorf("error pole is not projected: %v", lat)
+	}
+
+	theta := x.n * math.Remainder(lng*deg2rad-x.lon0, 2*math.Pi)
+
+	return x.x0 + rho*math.Sin(theta), x.y0 + x.rho0 - rho*math.Cos(theta), nil
+}
+
+func (x *LambertConformalConic) Inverse(e, n float64) (float64, float64, error) {
+
+	dx, dy := e-x.x0, x.rho0-(n-x.y0)
+
+	sign := 1.0
+	if x.n < 0 {
+		sign = -1
+	}
+
+	rho := sign * math.Hypot(dx, dy)
+	theta := math.Atan2(sign*dx, sign*dy)
+
+	lng := theta/x.n + x.lon0
+
+	if rho == 0 {
+		return lng * rad2deg, sign * 90, nil
+	}
+
+	t := math.Pow(rho/(x.a*x.f), 1/x.n)
+	phi := math.Pi/2 - 2*math.Atan(t)
+
+	for range 15 {
+		s := x.e * math.Sin(phi)
+		next := math.Pi/2 - 2*math.Atan(t*math.Pow((1-s)/(1+s), x.e/2))
+		if math.Abs(next-phi) < 1e-14 {
+			phi = next
+			break
+		}
+		phi = next
+	}
+
+	return lng * rad2deg, phi * rad2deg, nil
+}
@@ -0,0 +1,245 @@
+// Package render pure go raster drawing for static maps
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Point pixel coordinates
+type Point struct {
+	X float64
+	Y float64
+}
+
+// Canvas RGBA image with shape drawing
+type Canvas struct {
+	Image *image.RGBA
+}
+
+// NewCanvas canvas filled with background color
+func NewCanvas(width, height int, background color.Color) *Canvas {
+
+	res := &Canvas{Image: image.NewRGBA(image.Rect(0, 0, width, height))}
+	draw.Draw(res.Image, res.Image.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+
+	return res
+}
+
+// DrawImage draw src scaled into dst rect, bilinear
+func (x *Canvas) DrawImage(src image.Image, dst image.Rectangle) {
+
+	sb := src.Bounds()
+	if sb.Empty() || dst.Empty() {
+		return
+	}
+
+	clip := dst.Intersect(x.Image.Bounds())
+
+	sx := float64(sb.Dx()) / float64(dst.Dx())
+	sy := float64(sb.Dy()) / float64(dst.Dy())
+
+	at := func(px, py int) (r, g, b, a float64) {
+		px = min(max(px, 0), sb.Dx()-1) + sb.Min.X
+		py = min(max(py, 0), sb.Dy()-1) + sb.Min.Y
+		cr, cg, cb, ca := src.At(px, py).RGBA()
+		return float64(cr), float64(cg), float64(cb), float64(ca)
+	}
+
+	for y := clip.Min.Y; y < clip.Max.Y; y++ {
+		fy := (float64(y-dst.Min.Y)+0.5)*sy - 0.5
+		y0 := int(math.Floor(fy))
+		wy := fy - float64(y0)
+
+		for xx := clip.Min.X; xx < clip.Max.X; xx++ {
+			fx := (float64(xx-dst.Min.X)+0.5)*sx - 0.5
+			x0 := int(math.Floor(fx))
+			wx := fx - float64(x0)
+
+			r00, g00, b00, a00 := at(x0, y0)
+			r10, g10, b10, a10 := at(x0+1, y0)
+			r01, g01, b01, a01 := at(x0, y0+1)
+			r11, g11, b11, a11 := at(x0+1, y0+1)
+
+			mix := func(v00, v10, v01, v11 float64) uint8 {
+				v := (v00*(1-wx)+v10*wx)*(1-wy) + (v01*(1-wx)+v11*wx)*wy
+				return uint8(min(max(v/257, 0), 255))
+			}
+
+			x.Image.SetRGBA(xx, y, color.RGBA{
+				R: mix(r00, r10, r01, r11),
+				G: mix(g00, g10, g01, g11),
+				B: mix(b00, b10, b01, b11),
+				A: mix(a00, a10, a01, a11),
+			})
+		}
+	}
+}
+
+// Shape coverage mask, shapes are merged and painted once with a color
+type Shape struct {
+	mask *image.Alpha
+}
+
+// NewShape empty shape for canvas
+func (x *Canvas) NewShape() *Shape {
+	return &Shape{mask: image.NewAlpha(x.Image.Bounds())}
+}
+
+// Paint composite shape with color over canvas
+func (x *Canvas) Paint(s *Shape, c color.Color) {
+	draw.DrawMask(x.Image, x.Image.Bounds(), image.NewUniform(c), image.Point{}, s.mask, image.Point{}, draw.Over)
+}
+
+// Polygon add rings to shape, even-odd rule so inner rings are holes
+func (x *Shape) Polygon(rings [][]Point) {
+
+	b := x.mask.Bounds()
+
+	type edge struct{ x0, y0, x1, y1 float64 }
+
+	edges := []edge{}
+	minY, maxY := math.Inf(1), math.Inf(-1)
+
+	for _, ring := range rings {
+		n := len(ring)
+		for i := 0; i < n; i++ {
+			p, q := ring[i], ring[(i+1)%n]
+			if p.Y == q.Y {
+				continue
+			}
+			edges = append(edges, edge{p.X, p.Y, q.X, q.Y})
+			minY, maxY = math.Min(minY, math.Min(p.Y, q.Y)), math.Max(maxY, math.Max(p.Y, q.Y))
+		}
+	}
+
+	if len(edges) == 0 {
+		return
+	}
+
+	y0 := max(int(math.Floor(minY)), b.Min.Y)
+	y1 := min(int(math.Ceil(maxY)), b.Max.Y-1)
+
+	xs := []float64{}
+
+	for y := y0; y <= y1; y++ {
+		cy := float64(y) + 0.5 // pixel center
+		xs = xs[:0]
+
+		for _, e := range edges {
+			if (cy >= e.y0 && cy < e.y1) || (cy >= e.y1 && cy < e.y0) {
+				xs = append(xs, e.x0+(cy-e.y0)*(e.x1-e.x0)/(e.y1-e.y0))
+			}
+		}
+
+		slices.Sort(xs)
+
+		for i := 0; i+1 < len(xs); i += 2 {
+			from := max(int(math.Ceil(xs[i]-0.5)), b.Min.X)
+			to := min(int(math.Ceil(xs[i+1]-0.5)), b.Max.X)
+			for px := from; px < to; px++ {
+				x.mask.SetAlpha(px, y, color.Alpha{A: 255})
+			}
+		}
+	}
+}
+
+// Circle add filled circle to shape
+func (x *Shape) Circle(center Point, radius float64) {
+
+	const steps = 24
+
+	ring := make([]Point, 0, steps)
+	for i := 0; i < steps; i++ {
+		a := 2 * math.Pi * float64(i) / steps
+		ring = append(ring, Point{center.X + radius*math.Cos(a), center.Y + radius*math.Sin(a)})
+	}
+
+	x.Polygon([][]Point{ring})
+}
+
+// Polyline add stroked line with round joins to shape
+func (x *Shape) Polyline(points []Point, width float64) {
+
+	r := width / 2
+
+	for i := 0; i+1 < len(points); i++ {
+		p, q := points[i], points[i+1]
+		dx, dy := q.X-p.X, q.Y-p.Y
+		l := math.Hypot(dx, dy)
+		if l == 0 {
+			continue
+		}
+		nx, ny := -dy/l*r, dx/l*r
+
+		x.Polygon([][]Point{{
+			{p.X + nx, p.Y + ny},
+			{q.X + nx, q.Y + ny},
+			{q.X - nx, q.Y - ny},
+			{p.X - nx, p.Y - ny},
+		}})
+	}
+
+	for _, p := range points {
+		x.Circle(p, r)
+	}
+}
+
+// Marker add pin with tip at point, size is pin head diameter
+func (x *Shape) Marker(tip Point, size float64) {
+
+	r := size / 2
+	head := Point{tip.X, tip.Y - size*1.2}
+
+	x.Circle(head, r)
+	x.Polygon([][]Point{{
+		{head.X - r*0.8, head.Y + r*0.6},
+		{head.X + r*0.8, head.Y + r*0.6},
+		tip,
+	}})
+}
+
+var namedColors = map[string]color.RGBA{
+	"black":  {0, 0, 0, 255},
+	"white":  {255, 255, 255, 255},
+	"red":    {220, 38, 38, 255},
+	"green":  {22, 163, 74, 255},
+	"blue":   {37, 99, 235, 255},
+	"yellow": {234, 179, 8, 255},
+	"orange": {234, 88, 12, 255},
+	"purple": {147, 51, 234, 255},
+	"gray":   {107, 114, 128, 255},
+}
+
+// ParseColor "red", "0xRRGGBB", "#RRGGBBAA"
+func ParseColor(text string) (color.RGBA, error) {
+
+	if c, ok := namedColors[strings.ToLower(text)]; ok {
+		return c, nil
+	}
+
+	hex := strings.TrimPrefix(strings.TrimPrefix(text, "0x"), "#")
+	if len(hex) != 6 && len(hex) != 8 {
+		return color.RGBA{}, fmt.Errorf("error invalid color: %q", text)
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("error invalid color: %q", text)
+	}
+
+	// premultiplied as required by color.RGBA
+	a := uint32(v & 0xff)
+	pm := func(c uint32) uint8 { return uint8(c * a / 255) } //nolint:gosec // c*a/255 <= 255
+
+	return color.RGBA{R: pm(uint32(v >> 24)), G: pm(uint32(v>>16) & 0xff), B: pm(uint32(v>>8) & 0xff), A: uint8(a)}, nil
+}
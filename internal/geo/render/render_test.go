@@ -0,0 +1,125 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+var (
+	white = color.RGBA{255, 255, 255, 255}
+	red   = color.RGBA{255, 0, 0, 255}
+	blue  = color.RGBA{0, 0, 255, 255}
+)
+
+// Test filled pixels are the ones with centers inside polygon
+func TestPolygonPixels(t *testing.T) {
+
+	canvas := NewCanvas(10, 10, white)
+
+	s := canvas.NewShape()
+	s.Polygon([][]Point{{{2, 3}, {6, 3}, {6, 8}, {2, 8}}})
+	canvas.Paint(s, red)
+
+	for y := range 10 {
+		for x := range 10 {
+			expected := white
+			if x >= 2 && x < 6 && y >= 3 && y < 8 {
+				expected = red
+			}
+			if v := canvas.Image.RGBAAt(x, y); v != expected {
+				t.Fatalf("Expected %v at %v,%v, got %v", expected, x, y, v)
+			}
+		}
+	}
+}
+
+// Test hole of even-odd rule and shapes out of canvas
+func TestPolygonHoleAndClip(t *testing.T) {
+
+	canvas := NewCanvas(10, 10, white)
+
+	s := canvas.NewShape()
+	s.Polygon([][]Point{{{-5, -5}, {15, -5}, {15, 15}, {-5, 15}}, {{4, 4}, {6, 4}, {6, 6}, {4, 6}}})
+	canvas.Paint(s, red)
+
+	for _, v := range []struct {
+		x, y     int
+		expected color.RGBA
+	}{{0, 0, red}, {9, 9, red}, {3, 5, red}, {4, 4, white}, {5, 5, white}, {6, 6, red}} {
+		if c := canvas.Image.RGBAAt(v.x, v.y); c != v.expected {
+			t.Errorf("Expected %v at %v,%v, got %v", v.expected, v.x, v.y, c)
+		}
+	}
+}
+
+// Test image is drawn into dst rect, scaled and clipped
+func TestDrawImage(t *testing.T) {
+
+	src := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for y := range 4 {
+		for x := range 4 {
+			c := red
+			if x >= 2 {
+				c = blue
+			}
+			src.SetRGBA(x, y, c)
+		}
+	}
+
+	canvas := NewCanvas(20, 20, white)
+	canvas.DrawImage(src, image.Rect(-4, 2, 12, 18)) // 4x scaled, left quarter out of canvas
+
+	for _, v := range []struct {
+		x, y     int
+		expected color.RGBA
+	}{{0, 2, red}, {1, 10, red}, {6, 10, blue}, {11, 17, blue}, {12, 10, white}, {5, 1, white}, {5, 18, white}} {
+		if c := canvas.Image.RGBAAt(v.x, v.y); c != v.expected {
+			t.Errorf("Expected %v at %v,%v, got %v", v.expected, v.x, v.y, c)
+		}
+	}
+}
+
+// Test tip of marker is at point and head above it
+func TestMarker(t *testing.T) {
+
+	canvas := NewCanvas(40, 40, white)
+
+	s := canvas.NewShape()
+	s.Marker(Point{20, 30}, 10)
+	canvas.Paint(s, red)
+
+	if c := canvas.Image.RGBAAt(19, 28); c != red {
+		t.Errorf("Expected pin above tip, got %v", c)
+	}
+	if c := canvas.Image.RGBAAt(20, 31); c != white {
+		t.Errorf("Expected nothing below tip, got %v", c)
+	}
+	if c := canvas.Image.RGBAAt(20, 18); c != red {
+		t.Errorf("Expected head at 12 px above tip, got %v", c)
+	}
+}
+
+// Test named, hex and alpha colors
+func TestParseColor(t *testing.T) {
+
+	for _, v := range []struct {
+		text     string
+		expected color.RGBA
+	}{
+		{"red", color.RGBA{220, 38, 38, 255}},
+		{"0x102030", color.RGBA{16, 32, 48, 255}},
+		{"#ff000080", color.RGBA{128, 0, 0, 128}},
+	} {
+		c, err := ParseColor(v.text)
+		if err != nil || c != v.expected {
+			t.Errorf("Expected %v of %q, got %v %v", v.expected, v.text, c, err)
+		}
+	}
+
+	for _, v := range []string{"", "nope", "#12345", "0xgg0000"} {
+		if _, err := ParseColor(v); err == nil {
+			t.Errorf("Expected %q invalid", v)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package shp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type dbfField struct {
+	name     string
+	kind     byte
+	length   int
+	decimals int
+}
+
+// dbfReader dBASE III attribute table reader
+type dbfReader struct {
+	r         *bufio.Reader
+	fields    []dbfField
+	count     int
+	recordLen int
+	index     int
+	utf8      bool // .cpg says UTF-8, otherwise invalid UTF-8 is read as Latin-1
+}
+
+func newDBFReader(r io.Reader, cpg string) (*dbfReader, error) {
+
+	res := &dbfReader{r: bufio.NewReader(r)}
+
+	header := make([]byte, 32)
+	if _, err := io.ReadFull(res.r, header); err != nil {
+		return nil, fmt.Errorf("error on .dbf header: %v", err)
+	}
+
+	res.count = int(binary.LittleEndian.Uint32(header[4:8]))
+	headerLen := int(binary.LittleEndian.Uint16(header[8:10]))
+	res.recordLen = int(binary.LittleEndian.Uint16(header[10:12]))
+
+	cpg = strings.ToUpper(strings.TrimSpace(cpg))
+	res.utf8 = cpg == "UTF-8" || cpg == "UTF8" || cpg == "65001"
+
+	read := 32
+	for read+32 <= headerLen {
+		desc := make([]byte, 32)
+		if _, err := io.ReadFull(res.r, desc[:1]); err != nil {
+			return nil, fmt.Errorf("error on .dbf fields: %v", err)
+		}
+		read++
+		if desc[0] == 0x0D {
+			break
+		}
+		if _, err := io.ReadFull(res.r, desc[1:]); err != nil {
+			return nil, fmt.Errorf("error on .dbf fields: %v", err)
+		}
+		read += 31
+
+		name, _, _ := bytes.Cut(desc[0:11], []byte{0})
+		res.fields = append(res.fields, dbfField{
+			name:     strings.TrimSpace(string(name)),
+			kind:     desc[11],
+			length:   int(desc[16]),
+			decimals: int(desc[17]),
+		})
+	}
+
+	// rest of header
+	if headerLen > read {
+		if _, err := res.r.Discard(headerLen - read); err != nil {
+			return nil, fmt.Errorf("error on .dbf header: %v", err)
+		}
+	}
+
+	return res, nil
+}
+
+// next attributes of next record, deleted records are returned as usual
+// to keep order in sync with .shp
+func (x *dbfReader) next() (map[string]any, error) {
+
+	if x.index >= x.count {
+		return nil, nil
+	}
+
+	record := make([]byte, x.recordLen)
+	if _, err := io.ReadFull(x.r, record); err != nil {
+		return nil, err
+	}
+	x.index++
+
+	res := make(map[string]any, len(x.fields))
+	pos := 1 // deletion flag
+
+	for _, f := range x.fields {
+		if pos+f.length > len(record) {
+			return nil, fmt.Errorf("error record is shorter than fields")
+		}
+		res[f.name] = x.value(f, record[pos:pos+f.length])
+		pos += f.length
+	}
+
+	return res, nil
+}
+
+func (x *dbfReader) value(f dbfField, raw []byte) any {
+
+	text := strings.TrimSpace(x.decode(bytes.TrimRight(raw, "\x00")))
+
+	switch f.kind {
+	case 'N', 'F':
+		if text == "" || strings.Trim(text, "*") == "" {
+			return nil
+		}
+		if f.decimals == 0 {
+			if v, err := strconv.ParseInt(text, 10, 64); err == nil {
+				return v
+			}
+		}
+		if v, err := strconv.ParseFloat(text, 64); err == nil {
+			return v
+		}
+		return nil
+	case 'L':
+		switch text {
+		case "T", "t", "Y", "y":
+			return true
+		case "F", "f", "N", "n":
+			return false
+		}
+		return nil
+	case 'D':
+		if len(text) != 8 {
+			return nil
+		}
+		return text[0:4] + "-" + text[4:6] + "-" + text[6:8]
+	}
+
+	if text == "" {
+		return nil
+	}
+
+	return text
+}
+
+// decode bytes as UTF-8 or Latin-1
+func (x *dbfReader) decode(raw []byte) string {
+
+	if x.utf8 || utf8.Valid(raw) {
+		return string(raw)
+	}
+
+	res := make([]rune, len(raw))
+	for i, b := range raw {
+		res[i] = rune(b)
+	}
+
+	return string(res)
+}
@@ -0,0 +1,67 @@
+package shp
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	reAuthority = regexp.MustCompile(`AUTHORITY\s*\[\s*"EPSG"\s*,\s*"?(\d+)"?\s*\]`)
+	reUTMZone   = regexp.MustCompile(`^(WGS_1984|WGS_84|ETRS_1989|ETRS89|NAD_1983|NAD83)_UTM_ZONE_(\d{1,2})([NS])$`)
+	reCSName    = regexp.MustCompile(`^\s*(PROJCS|GEOGCS)\s*\[\s*"([^"]+)"`)
+)
+
+// known ESRI names without AUTHORITY
+var prjNames = map[string]int{
+	"GCS_WGS_1984":                           4326,
+	"WGS_84":                                 4326,
+	"GCS_ETRS_1989":                          4258,
+	"GCS_NORTH_AMERICAN_1983":                4269,
+	"WGS_1984_WEB_MERCATOR":                  3857,
+	"WGS_1984_WEB_MERCATOR_AUXILIARY_SPHERE": 3857,
+	"BRITISH_NATIONAL_GRID":                  27700,
+	"RGF_1993_LAMBERT_93":                    2154,
+	"RGF93_LAMBERT_93":                       2154,
+}
+
+// ParsePRJ EPSG code of WKT from .prj, 0 if unknown
+func ParsePRJ(wkt string) int {
+
+	// last AUTHORITY belongs to root CRS
+	if m := reAuthority.FindAllStringSubmatch(wkt, -1); len(m) > 0 {
+		if res, err := strconv.Atoi(m[len(m)-1][1]); err == nil {
+			return res
+		}
+	}
+
+	m := reCSName.FindStringSubmatch(wkt)
+	if m == nil {
+		return 0
+	}
+
+	name := strings.ToUpper(strings.ReplaceAll(m[2], " ", "_"))
+
+	if res, ok := prjNames[name]; ok {
+		return res
+	}
+
+	if u := reUTMZone.FindStringSubmatch(name); u != nil {
+		zone, _ := strconv.Atoi(u[2])
+		if zone < 1 || zone > 60 {
+			return 0
+		}
+		switch {
+		case strings.HasPrefix(u[1], "WGS") && u[3] == "N":
+			return 32600 + zone
+		case strings.HasPrefix(u[1], "WGS"):
+			return 32700 + zone
+		case strings.HasPrefix(u[1], "ETRS") && u[3] == "N":
+			return 25800 + zone
+		case strings.HasPrefix(u[1], "NAD") && u[3] == "N":
+			return 26900 + zone
+		}
+	}
+
+	return 0
+}
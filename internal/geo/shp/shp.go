@@ -0,0 +1,634 @@
+// Package shp ESRI Shapefile reader
+package shp
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"go-gis/internal/geo/geojson"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// shape types
+const (
+	TypeNull        = 0
+	TypePoint       = 1
+	TypePolyLine    = 3
+	TypePolygon     = 5
+	TypeMultiPoint  = 8
+	TypePointZ      = 11
+	TypePolyLineZ   = 13
+	TypePolygonZ    = 15
+	TypeMultiPointZ = 18
+	TypePointM      = 21
+	TypePolyLineM   = 23
+	TypePolygonM    = 25
+	TypeMultiPointM = 28
+	TypeMultiPatch  = 31
+)
+
+const (
+	fileCode   = 9994
+	headerSize = 100
+)
+
+// Files content of shapefile parts, SHX DBF PRJ CPG are optional
+type Files struct {
+	SHP io.ReadSeeker
+	SHX io.ReadSeeker
+	DBF io.ReadSeeker
+	PRJ []byte
+	CPG []byte
+
+	MaxSize int64 // bytes of .shx read to memory, 0 = no limit
+}
+
+// Reader read features from shapefile, coordinates are in CRS of PRJ
+type Reader struct {
+	ShapeType int
+	BBox      [4]float64 // minX minY maxX maxY
+	PRJ       string     // WKT
+	SRID      int        // EPSG code from PRJ, 0 if unknown
+
+	shp     io.ReadSeeker
+	size    int64   // of shp in bytes, bound of record length
+	offsets []int64 // from shx, nil = sequential
+	lengths []int64 // content lengths from shx
+	dbf     *dbfReader
+	index   int
+	closers []io.Closer
+}
+
+// NewReader reader of files
+func NewReader(files Files) (*Reader, error) {
+
+	if files.SHP == nil {
+		return nil, fmt.Errorf("error shapefile has no .shp")
+	}
+
+	res := &Reader{shp: files.SHP, PRJ: strings.TrimSpace(string(files.PRJ))}
+
+	size, err := files.SHP.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, fmt.Errorf("error on .shp: %v", err)
+	}
+	if _, err := files.SHP.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("error on .shp: %v", err)
+	}
+	res.size = size
+
+	if err := res.readHeader(); err != nil {
+		return nil, err
+	}
+
+	if files.SHX != nil {
+		offsets, lengths, err := readSHX(files.SHX, files.MaxSize)
+		if err != nil {
+			return nil, err
+		}
+		res.offsets, res.lengths = offsets, lengths
+	}
+
+	if files.DBF != nil {
+		dbf, err := newDBFReader(files.DBF, string(files.CPG))
+		if err != nil {
+			return nil, err
+		}
+		res.dbf = dbf
+	}
+
+	if res.PRJ != "" {
+		res.SRID = ParsePRJ(res.PRJ)
+	}
+
+	return res, nil
+}
+
+// Open .shp with sibling files or .zip with shapefile inside
+func Open(path string) (*Reader, error) {
+
+	path = filepath.Clean(path)
+
+	if strings.EqualFold(filepath.Ext(path), ".zip") {
+		z, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, err
+		}
+		res, err := readZip(&z.Reader, 0)
+		if err != nil {
+			_ = z.Close()
+			return nil, err
+		}
+		res.closers = append(res.closers, z)
+		return res, nil
+	}
+
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	files := Files{}
+	closers := []io.Closer{}
+
+	open := func(ext string) (*os.File, error) {
+		for _, v := range []string{strings.ToLower(ext), strings.ToUpper(ext)} {
+			f, err := os.Open(base + v)
+			if err == nil {
+				closers = append(closers, f)
+				return f, nil
+			}
+			if !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}
+
+	closeAll := func() {
+		for _, c := range closers {
+			_ = c.Close()
+		}
+	}
+
+	var err error
+	var shpF, shxF, dbfF *os.File
+
+	if shpF, err = open(".shp"); err == nil && shpF == nil {
+		err = fmt.Errorf("error file not exists: %v.shp", base)
+	}
+	if err == nil {
+		shxF, err = open(".shx")
+	}
+	if err == nil {
+		dbfF, err = open(".dbf")
+	}
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+
+	files.SHP = shpF
+	if shxF != nil {
+		files.SHX = shxF
+	}
+	if dbfF != nil {
+		files.DBF = dbfF
+	}
+
+	for _, ext := range []string{".prj", ".PRJ"} {
+		if data, err := os.ReadFile(base + ext); err == nil {
+			files.PRJ = data
+			break
+		}
+	}
+	for _, ext := range []string{".cpg", ".CPG"} {
+		if data, err := os.ReadFile(base + ext); err == nil {
+			files.CPG = data
+			break
+		}
+	}
+
+	res, err := NewReader(files)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+
+	res.closers = closers
+
+	return res, nil
+}
+
+// OpenZip zip archive with single shapefile, maxSize bytes of unpacked parts, 0 = no limit
+func OpenZip(r io.ReaderAt, size int64, maxSize int64) (*Reader, error) {
+
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	return readZip(z, maxSize)
+}
+
+// readZip load parts of first .shp in archive to memory, at most maxSize bytes in total, 0 = no limit
+func readZip(z *zip.Reader, maxSize int64) (*Reader, error) {
+
+	base := ""
+	for _, f := range z.File {
+		if strings.EqualFold(filepath.Ext(f.Name), ".shp") && !strings.HasPrefix(filepath.Base(f.Name), ".") {
+			base = strings.TrimSuffix(f.Name, filepath.Ext(f.Name))
+			break
+		}
+	}
+
+	if base == "" {
+		return nil, fmt.Errorf("error zip has no .shp file")
+	}
+
+	parts := map[string][]byte{}
+	remaining := maxSize
+
+	for _, f := range z.File {
+		ext := strings.ToLower(filepath.Ext(f.Name))
+		if !strings.EqualFold(strings.TrimSuffix(f.Name, filepath.Ext(f.Name)), base) {
+			continue
+		}
+
+		// header size is checked before unpacking, read size as header may lie
+		if maxSize > 0 && f.UncompressedSize64 > uint64(remaining) { //nolint:gosec // remaining >= 0
+			return nil, fmt.Errorf("error zip entry %v is larger than %v bytes unpacked", f.Name, maxSize)
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		limit := int64(-1)
+		if maxSize > 0 {
+			limit = remaining
+		}
+		data, err := readAll(rc, limit)
+		_ = rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error on zip entry %v: %v", f.Name, err)
+		}
+		parts[ext] = data
+		remaining -= int64(len(data))
+	}
+
+	files := Files{PRJ: parts[".prj"], CPG: parts[".cpg"], MaxSize: maxSize}
+
+	if v, ok := parts[".shp"]; ok {
+		files.SHP = bytes.NewReader(v)
+	}
+	if v, ok := parts[".shx"]; ok {
+		files.SHX = bytes.NewReader(v)
+	}
+	if v, ok := parts[".dbf"]; ok {
+		files.DBF = bytes.NewReader(v)
+	}
+
+	return NewReader(files)
+}
+
+// Close opened files
+func (x *Reader) Close() error {
+
+	var res error
+	for _, c := range x.closers {
+		if err := c.Close(); err != nil {
+			res = err
+		}
+	}
+
+	x.closers = nil
+
+	return res
+}
+
+// CRS EPSG code of coordinates, WGS84 when .prj is missing
+func (x *Reader) CRS() (int, error) {
+
+	if x.PRJ == "" {
+		return 4326, nil
+	}
+
+	if x.SRID == 0 {
+		return 0, fmt.Errorf("error unknown projection in .prj: %v", x.PRJ)
+	}
+
+	return x.SRID, nil
+}
+
+// Fields dbf attribute names
+func (x *Reader) Fields() []string {
+
+	if x.dbf == nil {
+		return nil
+	}
+
+	res := make([]string, 0, len(x.dbf.fields))
+	for _, f := range x.dbf.fields {
+		res = append(res, f.name)
+	}
+
+	return res
+}
+
+func (x *Reader) readHeader() error {
+
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(x.shp, header); err != nil {
+		return fmt.Errorf("error on .shp header: %v", err)
+	}
+
+	if binary.BigEndian.Uint32(header[0:4]) != fileCode {
+		return fmt.Errorf("error .shp has invalid file code")
+	}
+
+	x.ShapeType = int(int32(binary.LittleEndian.Uint32(header[32:36]))) //nolint:gosec // shape type is small
+
+	for i := range x.BBox {
+		x.BBox[i] = math.Float64frombits(binary.LittleEndian.Uint64(header[36+i*8:]))
+	}
+
+	return nil
+}
+
+// readSHX record offsets and content lengths in bytes, at most maxSize bytes, 0 = no limit
+func readSHX(r io.Reader, maxSize int64) ([]int64, []int64, error) {
+
+	limit := int64(-1)
+	if maxSize > 0 {
+		limit = maxSize
+	}
+
+	data, err := readAll(r, limit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error on .shx: %v", err)
+	}
+
+	if len(data) < headerSize || binary.BigEndian.Uint32(data[0:4]) != fileCode {
+		return nil, nil, fmt.Errorf("error .shx has invalid header")
+	}
+
+	data = data[headerSize:]
+	offsets := make([]int64, 0, len(data)/8)
+	lengths := make([]int64, 0, len(data)/8)
+
+	for i := 0; i+8 <= len(data); i += 8 {
+		offsets = append(offsets, int64(binary.BigEndian.Uint32(data[i:]))*2) // 16-bit words
+		lengths = append(lengths, int64(binary.BigEndian.Uint32(data[i+4:]))*2)
+	}
+
+	return offsets, lengths, nil
+}
+
+// Next next feature, io.EOF at end, *geojson.FeatureError for unsupported record
+func (x *Reader) Next() (*geojson.Feature, error) {
+
+	if x.offsets != nil {
+		if x.index >= len(x.offsets) {
+			return nil, io.EOF
+		}
+		if _, err := x.shp.Seek(x.offsets[x.index], io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+
+	index := x.index
+
+	recHeader := make([]byte, 8)
+	_, err := io.ReadFull(x.shp, recHeader)
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error on .shp record %v: %v", index, err)
+	}
+
+	length := int64(binary.BigEndian.Uint32(recHeader[4:8])) * 2
+
+	pos, err := x.shp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, fmt.Errorf("error on .shp record %v: %v", index, err)
+	}
+	if length > x.size-pos || (x.lengths != nil && length != x.lengths[index]) {
+		return nil, fmt.Errorf("error on .shp record %v: invalid content length %v", index, length)
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(x.shp, content); err != nil {
+		return nil, fmt.Errorf("error on .shp record %v: %v", index, err)
+	}
+
+	x.index++
+
+	var props map[string]any
+	if x.dbf != nil {
+		props, err = x.dbf.next()
+		if err != nil {
+			return nil, fmt.Errorf("error on .dbf record %v: %v", index, err)
+		}
+	}
+
+	geometry, err := parseShape(content)
+	if err != nil {
+		return nil, &geojson.FeatureError{Index: index, Err: err}
+	}
+
+	res := geojson.NewFeature(geometry)
+	res.ID = int(int32(binary.BigEndian.Uint32(recHeader[0:4]))) //nolint:gosec // record number
+	if props != nil {
+		res.Properties = props
+	}
+
+	return res, nil
+}
+
+type shapeBuf struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (x *shapeBuf) int32() int {
+	if x.err != nil || x.pos+4 > len(x.data) {
+		x.err = fmt.Errorf("error shape record is truncated")
+		return 0
+	}
+	v := int32(binary.LittleEndian.Uint32(x.data[x.pos:])) //nolint:gosec // signed field
+	x.pos += 4
+	return int(v)
+}
+
+func (x *shapeBuf) float64() float64 {
+	if x.err != nil || x.pos+8 > len(x.data) {
+		x.err = fmt.Errorf("error shape record is truncated")
+		return 0
+	}
+	v := math.Float64frombits(binary.LittleEndian.Uint64(x.data[x.pos:]))
+	x.pos += 8
+	return v
+}
+
+func (x *shapeBuf) points(n int) []geojson.Position {
+	if n < 0 || x.pos+n*16 > len(x.data) {
+		x.err = fmt.Errorf("error shape record is truncated")
+		return nil
+	}
+	res := make([]geojson.Position, n)
+	for i := range res {
+		res[i] = geojson.Position{x.float64(), x.float64()}
+	}
+	return res
+}
+
+// parseShape record content to geometry, z and m values are dropped
+func parseShape(content []byte) (*geojson.Geometry, error) {
+
+	b := &shapeBuf{data: content}
+	shapeType := b.int32()
+
+	var res *geojson.Geometry
+
+	switch shapeType {
+	case TypeNull:
+		return nil, fmt.Errorf("shape is null")
+	case TypePoint, TypePointZ, TypePointM:
+		res = geojson.NewPoint(b.float64(), b.float64())
+	case TypeMultiPoint, TypeMultiPointZ, TypeMultiPointM:
+		b.pos += 32 // bbox
+		n := b.int32()
+		res = &geojson.Geometry{Type: geojson.TypeMultiPoint, MultiPoint: b.points(n)}
+	case TypePolyLine, TypePolyLineZ, TypePolyLineM, TypePolygon, TypePolygonZ, TypePolygonM:
+		b.pos += 32 // bbox
+		numParts, numPoints := b.int32(), b.int32()
+		if numParts < 0 || numParts > len(content)/4 {
+			return nil, fmt.Errorf("invalid parts count: %v", numParts)
+		}
+		starts := make([]int, numParts)
+		for i := range starts {
+			starts[i] = b.int32()
+		}
+		points := b.points(numPoints)
+		if b.err != nil {
+			return nil, b.err
+		}
+
+		parts := make([][]geojson.Position, 0, numParts)
+		for i, start := range starts {
+			end := numPoints
+			if i+1 < numParts {
+				end = starts[i+1]
+			}
+			if start < 0 || start > end || end > numPoints {
+				return nil, fmt.Errorf("invalid part offset: %v", start)
+			}
+			parts = append(parts, points[start:end])
+		}
+
+		switch shapeType {
+		case TypePolygon, TypePolygonZ, TypePolygonM:
+			res = polygon(parts)
+		default:
+			if len(parts) == 1 {
+				res = geojson.NewLineString(parts[0])
+			} else {
+				res = &geojson.Geometry{Type: geojson.TypeMultiLineString, MultiLineString: parts}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported shape type: %v", shapeType)
+	}
+
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	return res, nil
+}
+
+// polygon group rings, shapefile outer rings are clockwise and holes counterclockwise,
+// result follows RFC 7946: outer counterclockwise, holes clockwise
+func polygon(rings [][]geojson.Position) *geojson.Geometry {
+
+	outers := [][][]geojson.Position{}
+	holes := [][]geojson.Position{}
+
+	for _, ring := range rings {
+		if len(ring) == 0 {
+			continue
+		}
+		if ring[0] != ring[len(ring)-1] {
+			ring = append(slices.Clip(ring), ring[0]) // ring shares points of next part
+		}
+		if signedArea(ring) <= 0 {
+			outers = append(outers, [][]geojson.Position{reverse(ring)})
+		} else {
+			holes = append(holes, ring)
+		}
+	}
+
+	if len(outers) == 0 {
+		// wrong winding, treat all rings as outer
+		for _, ring := range holes {
+			outers = append(outers, [][]geojson.Position{ring})
+		}
+		holes = nil
+	}
+
+	for _, hole := range holes {
+		owner := len(outers) - 1
+		for i, outer := range outers {
+			if pointInRing(hole[0], outer[0]) {
+				owner = i
+				break
+			}
+		}
+		outers[owner] = append(outers[owner], reverse(hole))
+	}
+
+	if len(outers) == 1 {
+		return geojson.NewPolygon(outers[0])
+	}
+
+	return &geojson.Geometry{Type: geojson.TypeMultiPolygon, MultiPolygon: outers}
+}
+
+// signedArea shoelace, > 0 for counterclockwise
+func signedArea(ring []geojson.Position) float64 {
+
+	res := 0.0
+	for i := 0; i+1 < len(ring); i++ {
+		res += ring[i][0]*ring[i+1][1] - ring[i+1][0]*ring[i][1]
+	}
+
+	return res / 2
+}
+
+func reverse(ring []geojson.Position) []geojson.Position {
+
+	res := make([]geojson.Position, len(ring))
+	for i, v := range ring {
+		res[len(ring)-1-i] = v
+	}
+
+	return res
+}
+
+// pointInRing even-odd ray casting
+func pointInRing(p geojson.Position, ring []geojson.Position) bool {
+
+	res := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a[1] > p[1]) != (b[1] > p[1]) && p[0] < (b[0]-a[0])*(p[1]-a[1])/(b[1]-a[1])+a[0] {
+			res = !res
+		}
+	}
+
+	return res
+}
+
+// readAll content of r up to limit bytes, negative = no limit, error if r is longer
+func readAll(r io.Reader, limit int64) ([]byte, error) {
+
+	if limit < 0 {
+		return io.ReadAll(r)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("error size exceeds %v bytes", limit)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,230 @@
+package shp
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"go-gis/internal/geo/geojson"
+	"io"
+	"math"
+	"testing"
+)
+
+// testSHP polygon record with clockwise outer ring and counterclockwise hole, then null record
+func testSHP() []byte {
+
+	outer := [][2]float64{{0, 0}, {0, 10}, {10, 10}, {10, 0}, {0, 0}}
+	hole := [][2]float64{{2, 2}, {4, 2}, {4, 4}, {2, 4}, {2, 2}}
+
+	rec := &bytes.Buffer{}
+	le := func(v any) { _ = binary.Write(rec, binary.LittleEndian, v) }
+	le(int32(TypePolygon))
+	le([4]float64{0, 0, 10, 10})
+	le(int32(2))
+	le(int32(len(outer) + len(hole)))
+	le(int32(0))
+	le(int32(len(outer)))
+	for _, p := range append(outer, hole...) {
+		le(p)
+	}
+
+	null := []byte{0, 0, 0, 0}
+
+	buf := &bytes.Buffer{}
+	be := func(v any) { _ = binary.Write(buf, binary.BigEndian, v) }
+
+	header := make([]byte, headerSize)
+	binary.BigEndian.PutUint32(header[0:], fileCode)
+	binary.LittleEndian.PutUint32(header[28:], 1000)
+	binary.LittleEndian.PutUint32(header[32:], TypePolygon)
+	buf.Write(header)
+
+	be(int32(1))
+	be(int32(rec.Len() / 2))
+	buf.Write(rec.Bytes())
+
+	be(int32(2))
+	be(int32(len(null) / 2))
+	buf.Write(null)
+
+	return buf.Bytes()
+}
+
+// testDBF two records with NAME C(10) and POP N(8,0)
+func testDBF() []byte {
+
+	buf := &bytes.Buffer{}
+
+	header := make([]byte, 32)
+	header[0] = 3
+	binary.LittleEndian.PutUint32(header[4:], 2)
+	binary.LittleEndian.PutUint16(header[8:], 32+2*32+1)
+	binary.LittleEndian.PutUint16(header[10:], 1+10+8)
+	buf.Write(header)
+
+	field := func(name string, kind byte, length int) {
+		desc := make([]byte, 32)
+		copy(desc, name)
+		desc[11] = kind
+		desc[16] = byte(length)
+		buf.Write(desc)
+	}
+	field("NAME", 'C', 10)
+	field("POP", 'N', 8)
+	buf.WriteByte(0x0D)
+
+	buf.WriteString(" " + "Caf\xe9      " + "     120")
+	buf.WriteString(" " + "Second    " + "        ")
+	buf.WriteByte(0x1A)
+
+	return buf.Bytes()
+}
+
+// Test polygon assembly, attributes and null shape
+func TestReader(t *testing.T) {
+
+	r, err := NewReader(Files{
+		SHP: bytes.NewReader(testSHP()),
+		DBF: bytes.NewReader(testDBF()),
+		PRJ: []byte(`PROJCS["WGS_1984_UTM_Zone_33N",GEOGCS["GCS_WGS_1984"]]`),
+	})
+	if err != nil {
+		t.Fatalf("NewReader error: %v", err)
+	}
+
+	if r.SRID != 32633 {
+		t.Errorf("Expected SRID 32633, got %v", r.SRID)
+	}
+
+	f, err := r.Next()
+	if err != nil {
+		t.Fatalf("Next error: %v", err)
+	}
+
+	if f.Geometry.Type != geojson.TypePolygon || len(f.Geometry.Polygon) != 2 {
+		t.Fatalf("Expected polygon with hole, got %+v", f.Geometry)
+	}
+
+	if signedArea(f.Geometry.Polygon[0]) <= 0 || signedArea(f.Geometry.Polygon[1]) >= 0 {
+		t.Error("Expected counterclockwise outer ring and clockwise hole")
+	}
+
+	if f.Properties["NAME"] != "Café" || f.Properties["POP"] != int64(120) {
+		t.Errorf("Unexpected properties %v", f.Properties)
+	}
+
+	_, err = r.Next()
+	fe := &geojson.FeatureError{}
+	if !errors.As(err, &fe) || fe.Index != 1 {
+		t.Errorf("Expected feature error for null shape, got %v", err)
+	}
+
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("Expected EOF, got %v", err)
+	}
+}
+
+// Test record length beyond end of .shp is rejected before allocation
+func TestReaderRecordLength(t *testing.T) {
+
+	data := testSHP()
+	binary.BigEndian.PutUint32(data[headerSize+4:], math.MaxUint32) // 8 GiB content
+
+	r, err := NewReader(Files{SHP: bytes.NewReader(data)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Next(); err == nil {
+		t.Error("Expected error on invalid content length")
+	}
+}
+
+// Test closing unclosed rings keeps first point of next part
+func TestPolygonUnclosedRings(t *testing.T) {
+
+	points := []geojson.Position{{0, 0}, {0, 10}, {10, 10}, {10, 0}, {2, 2}, {4, 2}, {4, 4}, {2, 4}}
+
+	g := polygon([][]geojson.Position{points[0:4], points[4:8]})
+
+	if len(g.Polygon) != 2 || g.Polygon[1][0] != (geojson.Position{2, 2}) || points[4] != (geojson.Position{2, 2}) {
+		t.Errorf("Unexpected rings %v, points %v", g.Polygon, points)
+	}
+
+	for _, ring := range g.Polygon {
+		if len(ring) != 5 || ring[0] != ring[4] {
+			t.Errorf("Expected closed ring, got %v", ring)
+		}
+	}
+}
+
+// Test EPSG detection from prj
+func TestParsePRJ(t *testing.T) {
+
+	items := []struct {
+		wkt  string
+		srid int
+	}{
+		{wkt: `GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]]]`, srid: 4326},
+		{wkt: `PROJCS["OSGB 1936 / British National Grid",GEOGCS["OSGB 1936",AUTHORITY["EPSG","4277"]],AUTHORITY["EPSG","27700"]]`, srid: 27700},
+		{wkt: `PROJCS["British_National_Grid",GEOGCS["GCS_OSGB_1936"]]`, srid: 27700},
+		{wkt: `PROJCS["WGS_1984_UTM_Zone_19S",GEOGCS["GCS_WGS_1984"]]`, srid: 32719},
+		{wkt: `PROJCS["Unknown",GEOGCS["GCS_WGS_1984"]]`, srid: 0},
+	}
+
+	for _, itm := range items {
+		if res := ParsePRJ(itm.wkt); res != itm.srid {
+			t.Errorf("Expected %v, got %v for %v", itm.srid, res, itm.wkt)
+		}
+	}
+
+	if math.Abs(signedArea([]geojson.Position{{0, 0}, {1, 0}, {1, 1}, {0, 0}})-0.5) > 1e-9 {
+		t.Error("Expected counterclockwise area 0.5")
+	}
+}
+
+// Test unpacked size of zip entries is limited, also when header understates it
+func TestOpenZipMaxSize(t *testing.T) {
+
+	shpData := testSHP()
+	padding := make([]byte, 1<<20) // compresses to about 1 KB
+
+	archive := func(lie bool) []byte {
+		buf := &bytes.Buffer{}
+		w := zip.NewWriter(buf)
+
+		f, _ := w.Create("test.shp")
+		_, _ = f.Write(shpData)
+
+		if !lie {
+			f, _ = w.Create("test.dbf")
+			_, _ = f.Write(padding)
+		} else {
+			packed := &bytes.Buffer{}
+			fw, _ := flate.NewWriter(packed, flate.BestCompression)
+			_, _ = fw.Write(padding)
+			_ = fw.Close()
+
+			f, _ = w.CreateRaw(&zip.FileHeader{Name: "test.dbf", Method: zip.Deflate,
+				CompressedSize64: uint64(packed.Len()), UncompressedSize64: 10})
+			_, _ = f.Write(packed.Bytes())
+		}
+
+		_ = w.Close()
+		return buf.Bytes()
+	}
+
+	for _, lie := range []bool{false, true} {
+		data := archive(lie)
+		if _, err := OpenZip(bytes.NewReader(data), int64(len(data)), 64*1024); err == nil {
+			t.Errorf("Expected error of entry over limit, header understates size: %v", lie)
+		}
+	}
+
+	data := archive(false)
+	if _, err := OpenZip(bytes.NewReader(data), int64(len(data)), 2<<20); err != nil {
+		t.Errorf("Expected archive under limit, got %v", err)
+	}
+}
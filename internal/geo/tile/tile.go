@@ -0,0 +1,110 @@
+// Package tile web mercator z/x/y tile math
+package tile
+
+import (
+	"fmt"
+	"go-gis/internal/geo/geojson"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// MaxZoom max supported zoom level
+const MaxZoom = 24
+
+// MaxLat web mercator lat limit
+const MaxLat = 85.0511287798066
+
+// Tile z/x/y, y counted from north (XYZ scheme)
+type Tile struct {
+	Z int
+	X int
+	Y int
+}
+
+func (x Tile) String() string { return fmt.Sprintf("%d/%d/%d", x.Z, x.X, x.Y) }
+
+// Parse z, x, y path parts, y may have extension like "3.pbf"
+func Parse(z, x, y string) (res Tile, err error) {
+
+	y, _, _ = strings.Cut(y, ".")
+	if y == "" {
+		return res, fmt.Errorf("error tile y is empty")
+	}
+
+	values := [3]int{}
+	for i, v := range []string{z, x, y} {
+		values[i], err = strconv.Atoi(v)
+		if err != nil {
+			return res, fmt.Errorf("error tile value %q: %v", v, err)
+		}
+	}
+
+	res = Tile{Z: values[0], X: values[1], Y: values[2]}
+
+	if !res.Valid() {
+		return res, fmt.Errorf("error tile out of range: %v", res)
+	}
+
+	return res, nil
+}
+
+// Valid z in range and x, y inside zoom level
+func (x Tile) Valid() bool {
+
+	if x.Z < 0 || x.Z > MaxZoom {
+		return false
+	}
+
+	n := 1 << x.Z
+
+	return x.X >= 0 && x.X < n && x.Y >= 0 && x.Y < n
+}
+
+// Bound tile bbox in lng/lat
+func (x Tile) Bound() geojson.BBox {
+
+	minLng, maxLat := PixelToLngLat(float64(x.X), float64(x.Y), x.Z, 1)
+	maxLng, minLat := PixelToLngLat(float64(x.X+1), float64(x.Y+1), x.Z, 1)
+
+	return geojson.BBox{minLng, minLat, maxLng, maxLat}
+}
+
+// FromLngLat tile containing position at zoom
+func FromLngLat(lng, lat float64, z int) Tile {
+
+	px, py := LngLatToPixel(lng, lat, z, 1)
+	n := 1 << z
+
+	clamp := func(v float64) int {
+		return min(max(int(math.Floor(v)), 0), n-1)
+	}
+
+	return Tile{Z: z, X: clamp(px), Y: clamp(py)}
+}
+
+// LngLatToPixel world pixel coordinates at zoom for given tile size
+func LngLatToPixel(lng, lat float64, z int, tileSize float64) (px, py float64) {
+
+	lat = min(max(lat, -MaxLat), MaxLat)
+	scale := tileSize * float64(uint64(1)<<z)
+
+	sin := math.Sin(lat * math.Pi / 180)
+
+	px = (lng + 180) / 360 * scale
+	py = (0.5 - math.Log((1+sin)/(1-sin))/(4*math.Pi)) * scale
+
+	return px, py
+}
+
+// PixelToLngLat inverse of LngLatToPixel
+func PixelToLngLat(px, py float64, z int, tileSize float64) (lng, lat float64) {
+
+	scale := tileSize * float64(uint64(1)<<z)
+
+	lng = px/scale*360 - 180
+	n := math.Pi - 2*math.Pi*py/scale
+	lat = 180 / math.Pi * math.Atan(math.Sinh(n))
+
+	return lng, lat
+}
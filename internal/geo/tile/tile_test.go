@@ -0,0 +1,45 @@
+package tile
+
+import (
+	"math"
+	"testing"
+)
+
+// Test parsing of path parts
+func TestParse(t *testing.T) {
+
+	res, err := Parse("3", "4", "2.pbf")
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	if res != (Tile{Z: 3, X: 4, Y: 2}) {
+		t.Errorf("Expected 3/4/2, got %v", res)
+	}
+
+	for _, v := range [][3]string{{"3", "8", "0"}, {"-1", "0", "0"}, {"25", "0", "0"}, {"1", "a", "0"}, {"1", "0", ".png"}} {
+		if _, err := Parse(v[0], v[1], v[2]); err == nil {
+			t.Errorf("Expected error for %v", v)
+		}
+	}
+}
+
+// Test tile of position and its bound
+func TestFromLngLat(t *testing.T) {
+
+	// London
+	res := FromLngLat(-0.12848, 51.50814, 10)
+	if res != (Tile{Z: 10, X: 511, Y: 340}) {
+		t.Errorf("Expected 10/511/340, got %v", res)
+	}
+
+	b := res.Bound()
+	if b[0] > -0.12848 || b[2] < -0.12848 || b[1] > 51.50814 || b[3] < 51.50814 {
+		t.Errorf("Expected bound %v to contain position", b)
+	}
+
+	world := Tile{}.Bound()
+	if math.Abs(world[3]-MaxLat) > 1e-9 || world[0] != -180 || world[2] != 180 {
+		t.Errorf("Unexpected world bound %v", world)
+	}
+}
@@ -0,0 +1,84 @@
+package track
+
+import (
+	"go-gis/internal/geo/geojson"
+	"time"
+)
+
+// ProcessFeature LineString or MultiLineString of feature in place, "coordTimes" property follows kept points,
+// other geometries are not changed.
+// Lines left with less than 2 points are removed, false if no line is left and feature should be dropped.
+func ProcessFeature(f *geojson.Feature, opts Options) bool {
+
+	if f.Geometry == nil || !opts.Enabled() {
+		return true
+	}
+
+	times := geojson.CoordTimes(f.Properties["coordTimes"])
+
+	lineTimes := func(i int) []string {
+		if i < len(times) {
+			return times[i]
+		}
+		return nil
+	}
+
+	switch f.Geometry.Type {
+	case geojson.TypeLineString:
+		coords, t := processLine(f.Geometry.LineString, lineTimes(0), opts)
+		if len(coords) < 2 {
+			return false
+		}
+		f.Geometry.LineString = coords
+		if times != nil {
+			f.Properties["coordTimes"] = t
+		}
+	case geojson.TypeMultiLineString:
+		lines := make([][]geojson.Position, 0, len(f.Geometry.MultiLineString))
+		newTimes := make([][]string, 0, len(f.Geometry.MultiLineString))
+		for i, line := range f.Geometry.MultiLineString {
+			coords, t := processLine(line, lineTimes(i), opts)
+			if len(coords) >= 2 {
+				lines, newTimes = append(lines, coords), append(newTimes, t)
+			}
+		}
+		if len(lines) == 0 {
+			return false
+		}
+		f.Geometry.MultiLineString = lines
+		if times != nil {
+			f.Properties["coordTimes"] = newTimes
+		}
+	}
+
+	return true
+}
+
+// processLine line with times of same length or nil
+func processLine(coords []geojson.Position, times []string, opts Options) ([]geojson.Position, []string) {
+
+	points := make([]Point, len(coords))
+	for i, p := range coords {
+		points[i].Pos = p
+		if i < len(times) {
+			points[i].Time, _ = time.Parse(time.RFC3339, times[i]) // zero if empty or invalid
+		}
+	}
+
+	points = Process(points, opts)
+
+	resCoords := make([]geojson.Position, len(points))
+	var resTimes []string
+	if times != nil {
+		resTimes = make([]string, len(points))
+	}
+
+	for i, p := range points {
+		resCoords[i] = p.Pos
+		if resTimes != nil && !p.Time.IsZero() {
+			resTimes[i] = p.Time.UTC().Format(time.RFC3339)
+		}
+	}
+
+	return resCoords, resTimes
+}
@@ -0,0 +1,168 @@
+// Package track GPS track cleanup: outlier removal, Kalman smoothing, simplification in meters
+package track
+
+import (
+	"go-gis/internal/geo/geojson"
+	"go-gis/internal/geo/geom"
+	"math"
+	"time"
+)
+
+// Point track position with optional time
+type Point struct {
+	Pos  geojson.Position
+	Time time.Time // zero if unknown
+}
+
+// earthRadius mean radius, meters
+const earthRadius = 6371008.8
+
+// defaultInterval step of points without time
+const defaultInterval = time.Second
+
+// Options of Process, zero value disables the step
+type Options struct {
+	MaxSpeed  float64 // m/s, points reached faster from previous point are removed
+	Smooth    bool    // Kalman filter
+	Accuracy  float64 // m, measurement noise of smoothing, default 10
+	Noise     float64 // m/s, process noise of smoothing, default 3
+	Tolerance float64 // m, Douglas-Peucker
+}
+
+// Enabled any step is enabled
+func (x Options) Enabled() bool {
+	return x.MaxSpeed > 0 || x.Smooth || x.Tolerance > 0
+}
+
+// Process steps in order: outliers, smoothing, simplification
+func Process(points []Point, opts Options) []Point {
+
+	if opts.MaxSpeed > 0 {
+		points = RemoveOutliers(points, opts.MaxSpeed)
+	}
+
+	if opts.Smooth {
+		points = Smooth(points, opts.Accuracy, opts.Noise)
+	}
+
+	if opts.Tolerance > 0 {
+		points = Simplify(points, opts.Tolerance)
+	}
+
+	return points
+}
+
+// RemoveOutliers drop points with impossible speed from last kept point, points without time are kept
+func RemoveOutliers(points []Point, maxSpeed float64) []Point {
+
+	if len(points) < 2 {
+		return points
+	}
+
+	res := make([]Point, 0, len(points))
+	res = append(res, points[0])
+
+	for _, p := range points[1:] {
+
+		last := res[len(res)-1]
+
+		if !p.Time.IsZero() && !last.Time.IsZero() {
+			dt := p.Time.Sub(last.Time).Seconds()
+			d := geom.Distance(last.Pos, p.Pos)
+			if dt <= 0 && d > 0 || dt > 0 && d/dt > maxSpeed {
+				continue
+			}
+		}
+
+		res = append(res, p)
+	}
+
+	return res
+}
+
+// Smooth Kalman filter of position with variance growing by noise (m/s) over time,
+// accuracy is measurement error (m)
+func Smooth(points []Point, accuracy, noise float64) []Point {
+
+	if len(points) < 2 {
+		return points
+	}
+
+	if accuracy <= 0 {
+		accuracy = 10
+	}
+	if noise <= 0 {
+		noise = 3
+	}
+
+	res := make([]Point, len(points))
+	res[0] = points[0]
+
+	// state in meters of local plane at first point
+	origin := points[0].Pos
+	x, y := toMeters(origin, points[0].Pos)
+	variance := accuracy * accuracy
+
+	for i := 1; i < len(points); i++ {
+
+		p := points[i]
+
+		dt := defaultInterval.Seconds()
+		if !p.Time.IsZero() && !points[i-1].Time.IsZero() {
+			dt = max(p.Time.Sub(points[i-1].Time).Seconds(), 0)
+		}
+
+		// predict
+		variance += dt * noise * noise
+
+		// update
+		mx, my := toMeters(origin, p.Pos)
+		gain := variance / (variance + accuracy*accuracy)
+		x += gain * (mx - x)
+		y += gain * (my - y)
+		variance = (1 - gain) * variance
+
+		res[i] = Point{Pos: fromMeters(origin, x, y), Time: p.Time}
+	}
+
+	return res
+}
+
+// Simplify Douglas-Peucker with tolerance in meters, times of kept points are kept
+func Simplify(points []Point, tolerance float64) []Point {
+
+	if len(points) < 3 {
+		return points
+	}
+
+	origin := points[0].Pos
+
+	projected := make([]geojson.Position, len(points))
+	for i, p := range points {
+		projected[i][0], projected[i][1] = toMeters(origin, p.Pos)
+	}
+
+	kept := geom.SimplifyDPIndex(projected, tolerance)
+
+	res := make([]Point, len(kept))
+	for i, v := range kept {
+		res[i] = points[v]
+	}
+
+	return res
+}
+
+// toMeters equirectangular plane at origin, good for track extents
+func toMeters(origin, p geojson.Position) (float64, float64) {
+
+	k := math.Pi / 180 * earthRadius
+
+	return (p[0] - origin[0]) * k * math.Cos(origin[1]*math.Pi/180), (p[1] - origin[1]) * k
+}
+
+func fromMeters(origin geojson.Position, x, y float64) geojson.Position {
+
+	k := math.Pi / 180 * earthRadius
+
+	return geojson.Position{origin[0] + x/(k*math.Cos(origin[1]*math.Pi/180)), origin[1] + y/k}
+}
@@ -0,0 +1,136 @@
+package track
+
+import (
+	"go-gis/internal/geo/geojson"
+	"math"
+	"testing"
+	"time"
+)
+
+// line along equator, step meters per second
+func line(n int, step float64) []Point {
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	res := make([]Point, n)
+	for i := range res {
+		res[i] = Point{Pos: fromMeters(geojson.Position{0, 0}, float64(i)*step, 0), Time: start.Add(time.Duration(i) * time.Second)}
+	}
+
+	return res
+}
+
+// Test jump of 1 km in 1 s is removed
+func TestRemoveOutliers(t *testing.T) {
+
+	points := line(5, 10)
+	points[2].Pos = fromMeters(geojson.Position{0, 0}, 20, 1000)
+
+	res := RemoveOutliers(points, 50)
+	if len(res) != 4 || res[2].Time != points[3].Time {
+		t.Errorf("Expected outlier removed, got %v", res)
+	}
+}
+
+// Test smoothing reduces zigzag noise
+func TestSmooth(t *testing.T) {
+
+	points := line(50, 5)
+	for i := range points {
+		_, y := toMeters(geojson.Position{0, 0}, points[i].Pos)
+		points[i].Pos = fromMeters(geojson.Position{0, 0}, float64(i)*5, y+float64(i%2*2-1)*8)
+	}
+
+	deviation := func(points []Point) float64 {
+		sum := 0.0
+		for _, p := range points[10:] {
+			_, y := toMeters(geojson.Position{0, 0}, p.Pos)
+			sum += math.Abs(y)
+		}
+		return sum / float64(len(points)-10)
+	}
+
+	res := Smooth(points, 10, 1)
+	if before, after := deviation(points), deviation(res); after >= before/2 {
+		t.Errorf("Expected deviation below %.2f, got %.2f", before/2, after)
+	}
+}
+
+// Test straight line keeps ends only, times follow kept points
+func TestProcessFeature(t *testing.T) {
+
+	points := line(10, 10)
+	coords := make([]geojson.Position, len(points))
+	times := make([]any, len(points))
+	for i, p := range points {
+		coords[i] = p.Pos
+		times[i] = p.Time.Format(time.RFC3339)
+	}
+
+	f := geojson.NewFeature(geojson.NewLineString(coords))
+	f.Properties["coordTimes"] = times
+
+	ProcessFeature(f, Options{Tolerance: 1})
+
+	resTimes, _ := f.Properties["coordTimes"].([]string)
+	if len(f.Geometry.LineString) != 2 || len(resTimes) != 2 || resTimes[1] != times[9] {
+		t.Errorf("Unexpected result %v %v", f.Geometry.LineString, resTimes)
+	}
+}
+
+// Test time of end stays with end when a dropped point has same position
+func TestSimplifyTimes(t *testing.T) {
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	points := make([]Point, 0, 5)
+	for i, v := range [][2]float64{{0, 0}, {100, 100}, {150, 50}, {200, 0}, {200, 0}} { // waits at end
+		points = append(points, Point{Pos: fromMeters(geojson.Position{0, 0}, v[0], v[1]), Time: start.Add(time.Duration(i) * time.Second)})
+	}
+
+	res := Simplify(points, 1)
+	if len(res) != 3 || res[1].Time != points[1].Time || res[2].Time != points[4].Time {
+		t.Errorf("Expected points 0, 1 and 4, got %v", res)
+	}
+}
+
+// Test lines without 2 points left are removed, feature without lines is dropped
+func TestProcessFeatureDrop(t *testing.T) {
+
+	points := line(3, 10)
+	points[1].Pos = fromMeters(geojson.Position{0, 0}, 10, 1000)
+	points[2].Pos = fromMeters(geojson.Position{0, 0}, 20, 2000)
+
+	coords := func(points []Point) []geojson.Position {
+		res := make([]geojson.Position, len(points))
+		for i, p := range points {
+			res[i] = p.Pos
+		}
+		return res
+	}
+	times := func(points []Point) []any {
+		res := make([]any, len(points))
+		for i, p := range points {
+			res[i] = p.Time.Format(time.RFC3339)
+		}
+		return res
+	}
+
+	f := geojson.NewFeature(geojson.NewLineString(coords(points)))
+	f.Properties["coordTimes"] = times(points)
+	if ProcessFeature(f, Options{MaxSpeed: 50}) {
+		t.Errorf("Expected feature dropped, got %v", f.Geometry.LineString)
+	}
+
+	good := line(4, 10)
+	f = geojson.NewFeature(&geojson.Geometry{Type: geojson.TypeMultiLineString, MultiLineString: [][]geojson.Position{coords(points), coords(good)}})
+	f.Properties["coordTimes"] = []any{times(points), times(good)}
+
+	if !ProcessFeature(f, Options{MaxSpeed: 50}) {
+		t.Fatalf("Expected feature kept")
+	}
+
+	resTimes, _ := f.Properties["coordTimes"].([][]string)
+	if len(f.Geometry.MultiLineString) != 1 || len(f.Geometry.MultiLineString[0]) != 4 || len(resTimes) != 1 || resTimes[0][3] != times(good)[3] {
+		t.Errorf("Expected second line only, got %v %v", f.Geometry.MultiLineString, resTimes)
+	}
+}
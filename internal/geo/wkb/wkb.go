@@ -0,0 +1,380 @@
+// Package wkb PostGIS extended well-known binary of geojson geometries, 2D
+package wkb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"go-gis/internal/geo/geojson"
+	"math"
+)
+
+// wkb geometry type codes
+const (
+	wkbPoint              = 1
+	wkbLineString         = 2
+	wkbPolygon            = 3
+	wkbMultiPoint         = 4
+	wkbMultiLineString    = 5
+	wkbMultiPolygon       = 6
+	wkbGeometryCollection = 7
+)
+
+// EWKB flags of type
+const (
+	flagZ    = 0x80000000
+	flagM    = 0x40000000
+	flagSRID = 0x20000000
+)
+
+// maxCount limit of items, guards allocation on broken data
+const maxCount = 1 << 26
+
+var typeCodes = map[string]uint32{
+	geojson.TypePoint:              wkbPoint,
+	geojson.TypeLineString:         wkbLineString,
+	geojson.TypePolygon:            wkbPolygon,
+	geojson.TypeMultiPoint:         wkbMultiPoint,
+	geojson.TypeMultiLineString:    wkbMultiLineString,
+	geojson.TypeMultiPolygon:       wkbMultiPolygon,
+	geojson.TypeGeometryCollection: wkbGeometryCollection,
+}
+
+// Encode little endian EWKB, SRID is written if positive
+func Encode(g *geojson.Geometry, srid int) ([]byte, error) {
+
+	w := &writer{}
+	if err := w.geometry(g, srid); err != nil {
+		return nil, err
+	}
+
+	return w.data, nil
+}
+
+type writer struct {
+	data []byte
+}
+
+func (x *writer) uint32(v uint32) { x.data = binary.LittleEndian.AppendUint32(x.data, v) }
+
+func (x *writer) position(p geojson.Position) {
+	x.data = binary.LittleEndian.AppendUint64(x.data, math.Float64bits(p[0]))
+	x.data = binary.LittleEndian.AppendUint64(x.data, math.Float64bits(p[1]))
+}
+
+func (x *writer) positions(v []geojson.Position) {
+	x.uint32(uint32(len(v))) //nolint:gosec // count is small
+	for _, p := range v {
+		x.position(p)
+	}
+}
+
+func (x *writer) rings(v [][]geojson.Position) {
+	x.uint32(uint32(len(v))) //nolint:gosec // count is small
+	for _, ring := range v {
+		x.positions(ring)
+	}
+}
+
+func (x *writer) header(code uint32, srid int) {
+
+	x.data = append(x.data, 1) // little endian
+
+	if srid > 0 {
+		x.uint32(code | flagSRID)
+		x.uint32(uint32(srid))
+		return
+	}
+
+	x.uint32(code)
+}
+
+func (x *writer) geometry(g *geojson.Geometry, srid int) error {
+
+	if g == nil {
+		return fmt.Errorf("error geometry is nil")
+	}
+
+	code, ok := typeCodes[g.Type]
+	if !ok {
+		return fmt.Errorf("error unknown geometry type: %q", g.Type)
+	}
+
+	x.header(code, srid)
+
+	switch g.Type {
+	case geojson.TypePoint:
+		x.position(g.Point)
+	case geojson.TypeLineString:
+		x.positions(g.LineString)
+	case geojson.TypePolygon:
+		x.rings(g.Polygon)
+	case geojson.TypeMultiPoint:
+		x.uint32(uint32(len(g.MultiPoint))) //nolint:gosec // count is small
+		for _, p := range g.MultiPoint {
+			x.header(wkbPoint, 0)
+			x.position(p)
+		}
+	case geojson.TypeMultiLineString:
+		x.uint32(uint32(len(g.MultiLineString))) //nolint:gosec // count is small
+		for _, line := range g.MultiLineString {
+			x.header(wkbLineString, 0)
+			x.positions(line)
+		}
+	case geojson.TypeMultiPolygon:
+		x.uint32(uint32(len(g.MultiPolygon))) //nolint:gosec // count is small
+		for _, polygon := range g.MultiPolygon {
+			x.header(wkbPolygon, 0)
+			x.rings(polygon)
+		}
+	case geojson.TypeGeometryCollection:
+		x.uint32(uint32(len(g.Geometries))) //nolint:gosec // count is small
+		for _, v := range g.Geometries {
+			if err := x.geometry(v, 0); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Decode EWKB or ISO WKB of either byte order, returns SRID or 0, Z and M are dropped
+func Decode(data []byte) (*geojson.Geometry, int, error) {
+
+	r := &reader{data: data}
+
+	g, srid, err := r.geometry()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if r.pos != len(data) {
+		return nil, 0, fmt.Errorf("error wkb has %v trailing bytes", len(data)-r.pos)
+	}
+
+	return g, srid, nil
+}
+
+type reader struct {
+	data  []byte
+	pos   int
+	order binary.ByteOrder
+	dims  int // coordinates per position
+}
+
+func (x *reader) need(n int) error {
+	if x.pos+n > len(x.data) {
+		return fmt.Errorf("error wkb is truncated at %v", x.pos)
+	}
+	return nil
+}
+
+func (x *reader) uint32() (uint32, error) {
+
+	if err := x.need(4); err != nil {
+		return 0, err
+	}
+
+	v := x.order.Uint32(x.data[x.pos:])
+	x.pos += 4
+
+	return v, nil
+}
+
+func (x *reader) count() (int, error) {
+
+	v, err := x.uint32()
+	if err != nil {
+		return 0, err
+	}
+
+	if v > maxCount || int(v)*8 > len(x.data)-x.pos {
+		return 0, fmt.Errorf("error wkb count %v is out of data", v)
+	}
+
+	return int(v), nil
+}
+
+func (x *reader) position() (geojson.Position, error) {
+
+	if err := x.need(x.dims * 8); err != nil {
+		return geojson.Position{}, err
+	}
+
+	res := geojson.Position{
+		math.Float64frombits(x.order.Uint64(x.data[x.pos:])),
+		math.Float64frombits(x.order.Uint64(x.data[x.pos+8:])),
+	}
+	x.pos += x.dims * 8
+
+	return res, nil
+}
+
+func (x *reader) positions() ([]geojson.Position, error) {
+
+	n, err := x.count()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]geojson.Position, n)
+	for i := range res {
+		if res[i], err = x.position(); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+func (x *reader) rings() ([][]geojson.Position, error) {
+
+	n, err := x.count()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([][]geojson.Position, n)
+	for i := range res {
+		if res[i], err = x.positions(); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// header byte order, type code without dimensions and SRID
+func (x *reader) header() (uint32, int, error) {
+
+	if err := x.need(1); err != nil {
+		return 0, 0, err
+	}
+
+	switch x.data[x.pos] {
+	case 0:
+		x.order = binary.BigEndian
+	case 1:
+		x.order = binary.LittleEndian
+	default:
+		return 0, 0, fmt.Errorf("error wkb byte order %v", x.data[x.pos])
+	}
+	x.pos++
+
+	code, err := x.uint32()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	srid := 0
+	if code&flagSRID != 0 {
+		v, err := x.uint32()
+		if err != nil {
+			return 0, 0, err
+		}
+		srid = int(v)
+	}
+
+	x.dims = 2
+	if code&flagZ != 0 {
+		x.dims++
+	}
+	if code&flagM != 0 {
+		x.dims++
+	}
+
+	code &^= flagZ | flagM | flagSRID
+
+	// ISO: 1000 Z, 2000 M, 3000 ZM
+	switch code / 1000 {
+	case 1, 2:
+		x.dims++
+	case 3:
+		x.dims += 2
+	}
+
+	return code % 1000, srid, nil
+}
+
+func (x *reader) geometry() (*geojson.Geometry, int, error) {
+
+	code, srid, err := x.header()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	res := &geojson.Geometry{}
+
+	switch code {
+	case wkbPoint:
+		res.Type = geojson.TypePoint
+		res.Point, err = x.position()
+	case wkbLineString:
+		res.Type = geojson.TypeLineString
+		res.LineString, err = x.positions()
+	case wkbPolygon:
+		res.Type = geojson.TypePolygon
+		res.Polygon, err = x.rings()
+	case wkbMultiPoint, wkbMultiLineString, wkbMultiPolygon, wkbGeometryCollection:
+		err = x.multi(res, code)
+	default:
+		return nil, 0, fmt.Errorf("error wkb geometry type %v", code)
+	}
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return res, srid, nil
+}
+
+// multi parts are geometries with own headers
+func (x *reader) multi(res *geojson.Geometry, code uint32) error {
+
+	n, err := x.count()
+	if err != nil {
+		return err
+	}
+
+	parts := make([]*geojson.Geometry, n)
+	for i := range parts {
+		if parts[i], _, err = x.geometry(); err != nil {
+			return err
+		}
+	}
+
+	expected := map[uint32]string{
+		wkbMultiPoint:      geojson.TypePoint,
+		wkbMultiLineString: geojson.TypeLineString,
+		wkbMultiPolygon:    geojson.TypePolygon,
+	}[code]
+
+	for _, v := range parts {
+		if expected != "" && v.Type != expected {
+			return fmt.Errorf("error wkb multi geometry %v has %v", code, v.Type)
+		}
+	}
+
+	switch code {
+	case wkbMultiPoint:
+		res.Type = geojson.TypeMultiPoint
+		for _, v := range parts {
+			res.MultiPoint = append(res.MultiPoint, v.Point)
+		}
+	case wkbMultiLineString:
+		res.Type = geojson.TypeMultiLineString
+		for _, v := range parts {
+			res.MultiLineString = append(res.MultiLineString, v.LineString)
+		}
+	case wkbMultiPolygon:
+		res.Type = geojson.TypeMultiPolygon
+		for _, v := range parts {
+			res.MultiPolygon = append(res.MultiPolygon, v.Polygon)
+		}
+	default:
+		res.Type = geojson.TypeGeometryCollection
+		res.Geometries = parts
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package wkb
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"go-gis/internal/geo/geojson"
+	"testing"
+)
+
+// Test encode, decode round trip of all types
+func TestRoundTrip(t *testing.T) {
+
+	texts := []string{
+		`{"type":"Point","coordinates":[1,2]}`,
+		`{"type":"LineString","coordinates":[[1,2],[3,4]]}`,
+		`{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}`,
+		`{"type":"MultiPoint","coordinates":[[1,2],[3,4]]}`,
+		`{"type":"MultiLineString","coordinates":[[[1,2],[3,4]],[[5,6],[7,8]]]}`,
+		`{"type":"MultiPolygon","coordinates":[[[[0,0],[1,0],[1,1],[0,0]]]]}`,
+		`{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[1,2]},{"type":"LineString","coordinates":[[1,2],[3,4]]}]}`,
+	}
+
+	for _, text := range texts {
+
+		g := &geojson.Geometry{}
+		if err := json.Unmarshal([]byte(text), g); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := Encode(g, 4326)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, srid, err := Decode(data)
+		if err != nil {
+			t.Fatalf("%v: %v", text, err)
+		}
+
+		out, _ := json.Marshal(res)
+		if string(out) != text || srid != 4326 {
+			t.Errorf("Expected %v, got %s %v", text, out, srid)
+		}
+	}
+}
+
+// Test PostGIS hex output: SRID=4326;POINT Z (1 2 3) and big endian POINT(1 2)
+func TestDecode(t *testing.T) {
+
+	tests := []struct {
+		hex  string
+		text string
+		srid int
+	}{
+		{"01010000a0e6100000000000000000f03f00000000000000400000000000000840", `{"type":"Point","coordinates":[1,2]}`, 4326},
+		{"00000000013ff00000000000004000000000000000", `{"type":"Point","coordinates":[1,2]}`, 0},
+	}
+
+	for _, v := range tests {
+
+		data, _ := hex.DecodeString(v.hex)
+
+		g, srid, err := Decode(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out, _ := json.Marshal(g)
+		if string(out) != v.text || srid != v.srid {
+			t.Errorf("Expected %v %v, got %s %v", v.text, v.srid, out, srid)
+		}
+	}
+
+	for _, v := range []string{"", "02", "0101000000", "01020000000000ffff"} {
+		data, _ := hex.DecodeString(v)
+		if _, _, err := Decode(data); err == nil {
+			t.Errorf("Expected error on %q", v)
+		}
+	}
+}
@@ -0,0 +1,385 @@
+// Package wkt well-known text of geojson geometries, 2D, EWKT "SRID=n;" prefix
+package wkt
+
+import (
+	"fmt"
+	"go-gis/internal/geo/geojson"
+	"strconv"
+	"strings"
+)
+
+var keywords = map[string]string{
+	geojson.TypePoint:              "POINT",
+	geojson.TypeLineString:         "LINESTRING",
+	geojson.TypePolygon:            "POLYGON",
+	geojson.TypeMultiPoint:         "MULTIPOINT",
+	geojson.TypeMultiLineString:    "MULTILINESTRING",
+	geojson.TypeMultiPolygon:       "MULTIPOLYGON",
+	geojson.TypeGeometryCollection: "GEOMETRYCOLLECTION",
+}
+
+// Marshal WKT, EWKT if SRID is positive
+func Marshal(g *geojson.Geometry, srid int) (string, error) {
+
+	b := &strings.Builder{}
+
+	if srid > 0 {
+		fmt.Fprintf(b, "SRID=%d;", srid)
+	}
+
+	if err := write(b, g); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+func write(b *strings.Builder, g *geojson.Geometry) error {
+
+	if g == nil {
+		return fmt.Errorf("error geometry is nil")
+	}
+
+	keyword, ok := keywords[g.Type]
+	if !ok {
+		return fmt.Errorf("error unknown geometry type: %q", g.Type)
+	}
+
+	b.WriteString(keyword)
+
+	switch g.Type {
+	case geojson.TypePoint:
+		b.WriteByte('(')
+		position(b, g.Point)
+		b.WriteByte(')')
+	case geojson.TypeLineString:
+		positions(b, g.LineString)
+	case geojson.TypePolygon:
+		rings(b, g.Polygon)
+	case geojson.TypeMultiPoint:
+		positions(b, g.MultiPoint)
+	case geojson.TypeMultiLineString:
+		rings(b, g.MultiLineString)
+	case geojson.TypeMultiPolygon:
+		if len(g.MultiPolygon) == 0 {
+			b.WriteString(" EMPTY")
+			return nil
+		}
+		b.WriteByte('(')
+		for i, v := range g.MultiPolygon {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			rings(b, v)
+		}
+		b.WriteByte(')')
+	case geojson.TypeGeometryCollection:
+		if len(g.Geometries) == 0 {
+			b.WriteString(" EMPTY")
+			return nil
+		}
+		b.WriteByte('(')
+		for i, v := range g.Geometries {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := write(b, v); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(')')
+	}
+
+	return nil
+}
+
+func position(b *strings.Builder, p geojson.Position) {
+	b.WriteString(strconv.FormatFloat(p[0], 'f', -1, 64))
+	b.WriteByte(' ')
+	b.WriteString(strconv.FormatFloat(p[1], 'f', -1, 64))
+}
+
+func positions(b *strings.Builder, v []geojson.Position) {
+
+	if len(v) == 0 {
+		b.WriteString(" EMPTY")
+		return
+	}
+
+	b.WriteByte('(')
+	for i, p := range v {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		position(b, p)
+	}
+	b.WriteByte(')')
+}
+
+func rings(b *strings.Builder, v [][]geojson.Position) {
+
+	if len(v) == 0 {
+		b.WriteString(" EMPTY")
+		return
+	}
+
+	b.WriteByte('(')
+	for i, ring := range v {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		positions(b, ring)
+	}
+	b.WriteByte(')')
+}
+
+// Parse WKT or EWKT, returns SRID or 0, Z and M are dropped, POINT EMPTY is not supported
+func Parse(text string) (*geojson.Geometry, int, error) {
+
+	srid := 0
+
+	if head, rest, ok := strings.Cut(text, ";"); ok && strings.HasPrefix(strings.ToUpper(strings.TrimSpace(head)), "SRID=") {
+		v, err := strconv.Atoi(strings.TrimSpace(head)[5:])
+		if err != nil {
+			return nil, 0, fmt.Errorf("error wkt srid: %q", head)
+		}
+		srid, text = v, rest
+	}
+
+	p := &parser{tokens: tokenize(text)}
+
+	g, err := p.geometry()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if p.pos != len(p.tokens) {
+		return nil, 0, fmt.Errorf("error wkt unexpected %q", p.tokens[p.pos])
+	}
+
+	return g, srid, nil
+}
+
+// tokenize words, numbers and punctuation
+func tokenize(text string) []string {
+
+	res := []string{}
+	start := -1
+
+	flush := func(i int) {
+		if start >= 0 {
+			res = append(res, text[start:i])
+			start = -1
+		}
+	}
+
+	for i, r := range text {
+		switch r {
+		case '(', ')', ',':
+			flush(i)
+			res = append(res, string(r))
+		case ' ', '\t', '\n', '\r':
+			flush(i)
+		default:
+			if start < 0 {
+				start = i
+			}
+		}
+	}
+	flush(len(text))
+
+	return res
+}
+
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+func (x *parser) peek() string {
+	if x.pos < len(x.tokens) {
+		return x.tokens[x.pos]
+	}
+	return ""
+}
+
+func (x *parser) next() string {
+	v := x.peek()
+	x.pos++
+	return v
+}
+
+func (x *parser) expect(token string) error {
+	if v := x.next(); v != token {
+		return fmt.Errorf("error wkt expected %q, got %q", token, v)
+	}
+	return nil
+}
+
+// empty consume EMPTY
+func (x *parser) empty() bool {
+	if strings.EqualFold(x.peek(), "EMPTY") {
+		x.pos++
+		return true
+	}
+	return false
+}
+
+func (x *parser) geometry() (*geojson.Geometry, error) {
+
+	keyword := strings.ToUpper(x.next())
+
+	// dimension suffix as separate word or attached: POINT Z, POINTZ, POINTM
+	dims := 2
+	if v := strings.ToUpper(x.peek()); v == "Z" || v == "M" || v == "ZM" {
+		dims += len(v)
+		x.pos++
+	}
+
+	res := &geojson.Geometry{}
+	for k, v := range keywords {
+		switch keyword {
+		case v:
+		case v + "Z", v + "M":
+			dims = 3
+		case v + "ZM":
+			dims = 4
+		default:
+			continue
+		}
+		res.Type = k
+	}
+
+	if res.Type == "" {
+		return nil, fmt.Errorf("error wkt unknown geometry: %q", keyword)
+	}
+
+	var err error
+
+	switch res.Type {
+	case geojson.TypePoint:
+		if err = x.expect("("); err == nil {
+			if res.Point, err = x.position(dims); err == nil {
+				err = x.expect(")")
+			}
+		}
+	case geojson.TypeLineString:
+		res.LineString, err = x.positions(dims)
+	case geojson.TypePolygon:
+		res.Polygon, err = x.rings(dims)
+	case geojson.TypeMultiPoint:
+		res.MultiPoint, err = x.multiPoint(dims)
+	case geojson.TypeMultiLineString:
+		res.MultiLineString, err = x.rings(dims)
+	case geojson.TypeMultiPolygon:
+		err = x.list(func() error {
+			v, err := x.rings(dims)
+			res.MultiPolygon = append(res.MultiPolygon, v)
+			return err
+		})
+	case geojson.TypeGeometryCollection:
+		err = x.list(func() error {
+			v, err := x.geometry()
+			res.Geometries = append(res.Geometries, v)
+			return err
+		})
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// list "(item, item)" or EMPTY
+func (x *parser) list(item func() error) error {
+
+	if x.empty() {
+		return nil
+	}
+
+	if err := x.expect("("); err != nil {
+		return err
+	}
+
+	for {
+		if err := item(); err != nil {
+			return err
+		}
+		if x.peek() != "," {
+			break
+		}
+		x.pos++
+	}
+
+	return x.expect(")")
+}
+
+func (x *parser) position(dims int) (geojson.Position, error) {
+
+	res := geojson.Position{}
+
+	for i := range dims {
+		token := x.next()
+		v, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return res, fmt.Errorf("error wkt number: %q", token)
+		}
+		if i < 2 {
+			res[i] = v
+		}
+	}
+
+	return res, nil
+}
+
+func (x *parser) positions(dims int) ([]geojson.Position, error) {
+
+	res := []geojson.Position{}
+
+	err := x.list(func() error {
+		p, err := x.position(dims)
+		res = append(res, p)
+		return err
+	})
+
+	return res, err
+}
+
+func (x *parser) rings(dims int) ([][]geojson.Position, error) {
+
+	res := [][]geojson.Position{}
+
+	err := x.list(func() error {
+		v, err := x.positions(dims)
+		res = append(res, v)
+		return err
+	})
+
+	return res, err
+}
+
+// multiPoint "(1 2, 3 4)" or "((1 2), (3 4))"
+func (x *parser) multiPoint(dims int) ([]geojson.Position, error) {
+
+	res := []geojson.Position{}
+
+	err := x.list(func() error {
+		paren := x.peek() == "("
+		if paren {
+			x.pos++
+		}
+		p, err := x.position(dims)
+		if err != nil {
+			return err
+		}
+		res = append(res, p)
+		if paren {
+			return x.expect(")")
+		}
+		return nil
+	})
+
+	return res, err
+}
@@ -0,0 +1,54 @@
+package wkt
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// Test parse and marshal of all types
+func TestParse(t *testing.T) {
+
+	tests := []struct {
+		text     string
+		expected string // json
+		wkt      string
+		srid     int
+	}{
+		{"POINT (1 2)", `{"type":"Point","coordinates":[1,2]}`, "POINT(1 2)", 0},
+		{"SRID=4326;POINT Z (1 2 3)", `{"type":"Point","coordinates":[1,2]}`, "SRID=4326;POINT(1 2)", 4326},
+		{"linestring(1 2, 3.5 -4)", `{"type":"LineString","coordinates":[[1,2],[3.5,-4]]}`, "LINESTRING(1 2,3.5 -4)", 0},
+		{"POLYGON((0 0,1 0,1 1,0 0),(0.2 0.2,0.3 0.2,0.3 0.3,0.2 0.2))", `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]],[[0.2,0.2],[0.3,0.2],[0.3,0.3],[0.2,0.2]]]}`, "", 0},
+		{"MULTIPOINT((1 2),(3 4))", `{"type":"MultiPoint","coordinates":[[1,2],[3,4]]}`, "MULTIPOINT(1 2,3 4)", 0},
+		{"MULTILINESTRING((1 2,3 4))", `{"type":"MultiLineString","coordinates":[[[1,2],[3,4]]]}`, "", 0},
+		{"MULTIPOLYGON(((0 0,1 0,1 1,0 0)))", `{"type":"MultiPolygon","coordinates":[[[[0,0],[1,0],[1,1],[0,0]]]]}`, "", 0},
+		{"GEOMETRYCOLLECTION(POINT(1 2),LINESTRING(1 2,3 4))", `{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[1,2]},{"type":"LineString","coordinates":[[1,2],[3,4]]}]}`, "", 0},
+		{"LINESTRING EMPTY", `{"type":"LineString","coordinates":[]}`, "", 0},
+	}
+
+	for _, v := range tests {
+
+		g, srid, err := Parse(v.text)
+		if err != nil {
+			t.Fatalf("%v: %v", v.text, err)
+		}
+
+		out, _ := json.Marshal(g)
+		if string(out) != v.expected || srid != v.srid {
+			t.Errorf("Expected %v %v, got %s %v", v.expected, v.srid, out, srid)
+		}
+
+		expected := v.wkt
+		if expected == "" {
+			expected = v.text
+		}
+		if text, _ := Marshal(g, srid); text != expected {
+			t.Errorf("Expected %v, got %v", expected, text)
+		}
+	}
+
+	for _, v := range []string{"", "POINT", "POINT(1)", "CIRCLE(1 2)", "POINT(1 2))", "SRID=x;POINT(1 2)"} {
+		if _, _, err := Parse(v); err == nil {
+			t.Errorf("Expected error on %q", v)
+		}
+	}
+}
@@ -20,6 +20,10 @@ func Init(e *echo.Echo, appService service.AppService) {
 		e.Use(middleware.Logger())
 	}
 
+	if appConfig.Tenants.Enabled {
+		e.Use(newTenantMW(appService))
+	}
+
 }
 func newHTTPErrorHandler(_ service.AppService) echo.HTTPErrorHandler {
 
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"errors"
+	"go-gis/internal/config/consts"
+	"go-gis/internal/service"
+	xlog "go-gis/internal/util/utillog"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// newTenantMW select tenant services of gis routes by api key or tenant header,
+// header alone selects tenant only without api keys or behind trusted proxy, else it must match tenant of key,
+// requests without tenant use default services unless tenant is required
+func newTenantMW(appService service.AppService) echo.MiddlewareFunc {
+
+	cfg := appService.Config().Tenants
+
+	if cfg.Header != "" && len(cfg.APIKeys) == 0 {
+		xlog.Warn("tenant header %v selects tenant without api keys, set it by trusted proxy only", cfg.Header)
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+
+			path := c.Request().URL.Path
+			if !strings.HasPrefix(path, consts.PathGisPrefix) || path == consts.PathGisPingDebugAPI {
+				return next(c)
+			}
+
+			name := ""
+			header := ""
+			if cfg.Header != "" {
+				header = c.Request().Header.Get(cfg.Header)
+			}
+
+			key := c.QueryParam("api-key")
+			if key == "" {
+				key = c.Request().Header.Get(consts.HeaderAPIKey)
+			}
+
+			switch {
+			case key != "":
+				v, ok := cfg.APIKeys[key]
+				if !ok {
+					return echo.NewHTTPError(http.StatusUnauthorized, "invalid api key")
+				}
+				if header != "" && header != v {
+					return echo.NewHTTPError(http.StatusForbidden, "tenant of api key differs")
+				}
+				name = v
+			case header != "" && !cfg.HeaderSelects():
+				return echo.NewHTTPError(http.StatusUnauthorized, "api key is required")
+			default:
+				name = header
+			}
+
+			if name == "" {
+				if cfg.Required {
+					return echo.NewHTTPError(http.StatusUnauthorized, "tenant is required")
+				}
+				return next(c)
+			}
+
+			tenant, err := appService.Tenant(name)
+			if errors.Is(err, service.ErrUnknownTenant) {
+				return echo.NewHTTPError(http.StatusNotFound, "unknown tenant")
+			}
+			if err != nil {
+				xlog.Error("%v", err)
+				return echo.NewHTTPError(http.StatusServiceUnavailable)
+			}
+
+			c.Set(consts.ContextKeyAppService, tenant)
+
+			return next(c)
+		}
+	}
+}
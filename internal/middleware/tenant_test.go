@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"go-gis/internal/config"
+	"go-gis/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// tenantService services of config with tenant lookup only
+type tenantService struct {
+	service.AppService
+	cfg *config.AppConfig
+}
+
+func (x *tenantService) Config() *config.AppConfig { return x.cfg }
+
+func (x *tenantService) Tenant(name string) (service.AppService, error) {
+	if _, ok := x.cfg.Tenants.Schemas[name]; !ok {
+		return nil, service.ErrUnknownTenant
+	}
+	return x, nil
+}
+
+// Test tenant header is used only without api keys or with trusted header
+func TestTenantMW(t *testing.T) {
+
+	cfg := config.NewAppConfig()
+	cfg.Tenants.Enabled = true
+	cfg.Tenants.Schemas = map[string]string{"a": "tenant_a", "b": "tenant_b"}
+	cfg.Tenants.APIKeys = map[string]string{"key-a": "a"}
+
+	tests := []struct {
+		trust  bool
+		keys   bool
+		key    string
+		header string
+		code   int
+	}{
+		{keys: true, key: "key-a", code: http.StatusOK},
+		{keys: true, key: "key-a", header: "a", code: http.StatusOK},
+		{keys: true, key: "key-a", header: "b", code: http.StatusForbidden},
+		{keys: true, key: "wrong", code: http.StatusUnauthorized},
+		{keys: true, header: "b", code: http.StatusUnauthorized},
+		{keys: true, trust: true, header: "b", code: http.StatusOK},
+		{keys: false, header: "b", code: http.StatusOK},
+		{keys: false, header: "c", code: http.StatusNotFound},
+		{keys: true, code: http.StatusOK},
+	}
+
+	for _, v := range tests {
+
+		cfg.Tenants.TrustHeader = v.trust
+		cfg.Tenants.APIKeys = nil
+		if v.keys {
+			cfg.Tenants.APIKeys = map[string]string{"key-a": "a"}
+		}
+
+		e := echo.New()
+		e.Use(newTenantMW(&tenantService{cfg: cfg}))
+		e.GET("/gis/api/features", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+		req := httptest.NewRequest(http.MethodGet, "/gis/api/features", nil)
+		if v.key != "" {
+			req.Header.Set("X-API-Key", v.key)
+		}
+		if v.header != "" {
+			req.Header.Set("X-Tenant", v.header)
+		}
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		if rec.Code != v.code {
+			t.Errorf("%+v expected %v, got %v", v, v.code, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+// Package migrate versioned schema migrations of ordered up/down sql files
+package migrate
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// sql/{dialect}/*.sql are "{version}_{name}.up.sql" and "{version}_{name}.down.sql"
+//
+//go:embed sql
+var embedded embed.FS
+
+// Table of applied versions
+const Table = "schema_migrations"
+
+// lockKey advisory lock of migrations, "go-gis" in ascii
+const lockKey int64 = 0x676f2d676973
+
+// Migration version with up and down sql
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status of migration, Missing if applied version has no file
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time // nil if pending
+	Missing   bool
+}
+
+var reFile = regexp.MustCompile(`^(\d+)_([A-Za-z0-9_]+)\.(up|down)\.sql$`)
+
+// Embedded migrations of dialect in binary
+func Embedded(dialect string) ([]Migration, error) {
+
+	if _, err := fs.Stat(embedded, "sql/"+dialect); err != nil {
+		return nil, fmt.Errorf("no migrations of dialect %q", dialect)
+	}
+
+	sub, err := fs.Sub(embedded, "sql/"+dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	return Load(sub)
+}
+
+// Load migrations of dir ordered by version, both up and down files are required
+func Load(fsys fs.FS) ([]Migration, error) {
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+
+	for _, e := range entries {
+
+		if e.IsDir() || path.Ext(e.Name()) != ".sql" {
+			continue
+		}
+
+		m := reFile.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name: %v", e.Name())
+		}
+
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version: %v", e.Name())
+		}
+
+		data, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		v := byVersion[version]
+		if v == nil {
+			v = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = v
+		}
+		if v.Name != m[2] {
+			return nil, fmt.Errorf("migration %v has names %v and %v", version, v.Name, m[2])
+		}
+
+		if m[3] == "up" {
+			v.Up = string(data)
+		} else {
+			v.Down = string(data)
+		}
+	}
+
+	res := make([]Migration, 0, len(byVersion))
+	for _, v := range byVersion {
+		if v.Up == "" || v.Down == "" {
+			return nil, fmt.Errorf("migration %v_%v has no up or down file", v.Version, v.Name)
+		}
+		res = append(res, *v)
+	}
+
+	slices.SortFunc(res, func(a, b Migration) int { return int(a.Version - b.Version) })
+
+	return res, nil
+}
+
+type appliedRow struct {
+	Version   int64
+	Name      string
+	AppliedAt time.Time
+}
+
+// Migrator runs migrations under postgres advisory lock, each migration in own transaction
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// New migrator of ordered migrations
+func New(db *gorm.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+// Up apply pending migrations, returns applied
+func (x *Migrator) Up() ([]Migration, error) {
+
+	res := []Migration{}
+
+	err := x.locked(func(conn *gorm.DB) error {
+
+		applied, err := x.applied(conn)
+		if err != nil {
+			return err
+		}
+
+		last := int64(0)
+		if len(applied) > 0 {
+			last = applied[len(applied)-1].Version
+		}
+
+		done := map[int64]bool{}
+		for _, v := range applied {
+			done[v.Version] = true
+		}
+
+		for _, m := range x.migrations {
+
+			if done[m.Version] {
+				continue
+			}
+
+			if m.Version < last {
+				return fmt.Errorf("migration %v_%v is older than applied version %v", m.Version, m.Name, last)
+			}
+
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := exec(tx, m.Up); err != nil {
+					return err
+				}
+				return tx.Exec("INSERT INTO "+Table+" (version, name) VALUES (?, ?)", m.Version, m.Name).Error
+			})
+			if err != nil {
+				return fmt.Errorf("error on migration %v_%v up: %v", m.Version, m.Name, err)
+			}
+
+			res = append(res, m)
+		}
+
+		return nil
+	})
+
+	return res, err
+}
+
+// Down revert last n applied migrations, returns reverted
+func (x *Migrator) Down(n int) ([]Migration, error) {
+
+	res := []Migration{}
+
+	err := x.locked(func(conn *gorm.DB) error {
+
+		applied, err := x.applied(conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(applied) - 1; i >= 0 && len(res) < n; i-- {
+
+			v := applied[i]
+
+			k := slices.IndexFunc(x.migrations, func(m Migration) bool { return m.Version == v.Version })
+			if k < 0 {
+				return fmt.Errorf("applied migration %v_%v has no file", v.Version, v.Name)
+			}
+			m := x.migrations[k]
+
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := exec(tx, m.Down); err != nil {
+					return err
+				}
+				return tx.Exec("DELETE FROM "+Table+" WHERE version = ?", m.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("error on migration %v_%v down: %v", m.Version, m.Name, err)
+			}
+
+			res = append(res, m)
+		}
+
+		return nil
+	})
+
+	return res, err
+}
+
+// Status of known and applied migrations ordered by version
+func (x *Migrator) Status() ([]Status, error) {
+
+	res := []Status{}
+
+	err := x.locked(func(conn *gorm.DB) error {
+
+		applied, err := x.applied(conn)
+		if err != nil {
+			return err
+		}
+
+		byVersion := map[int64]*appliedRow{}
+		for i := range applied {
+			byVersion[applied[i].Version] = &applied[i]
+		}
+
+		for _, m := range x.migrations {
+			s := Status{Version: m.Version, Name: m.Name}
+			if v := byVersion[m.Version]; v != nil {
+				s.AppliedAt = &v.AppliedAt
+				delete(byVersion, m.Version)
+			}
+			res = append(res, s)
+		}
+
+		for _, v := range byVersion {
+			res = append(res, Status{Version: v.Version, Name: v.Name, AppliedAt: &v.AppliedAt, Missing: true})
+		}
+
+		slices.SortFunc(res, func(a, b Status) int { return int(a.Version - b.Version) })
+
+		return nil
+	})
+
+	return res, err
+}
+
+// locked run on one connection holding advisory lock, table is created if not exists,
+// sqlite has no lock, concurrent writers wait on database lock
+func (x *Migrator) locked(fn func(conn *gorm.DB) error) error {
+
+	postgres := x.db.Dialector.Name() == "postgres"
+
+	return x.db.Connection(func(conn *gorm.DB) error {
+
+		if postgres {
+			if err := conn.Exec("SELECT pg_advisory_lock(?)", lockKey).Error; err != nil {
+				return fmt.Errorf("error on migrations lock: %v", err)
+			}
+			defer conn.Exec("SELECT pg_advisory_unlock(?)", lockKey)
+		}
+
+		appliedAt := "timestamptz"
+		if !postgres {
+			appliedAt = "datetime" // parsed to time by sqlite driver
+		}
+
+		err := conn.Exec(`CREATE TABLE IF NOT EXISTS ` + Table + ` (
+			version bigint PRIMARY KEY,
+			name text NOT NULL,
+			applied_at ` + appliedAt + ` NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`).Error
+		if err != nil {
+			return fmt.Errorf("error on %v: %v", Table, err)
+		}
+
+		return fn(conn)
+	})
+}
+
+func (x *Migrator) applied(conn *gorm.DB) ([]appliedRow, error) {
+
+	rows := []appliedRow{}
+
+	err := conn.Raw("SELECT version, name, applied_at FROM " + Table + " ORDER BY version").Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("error on %v: %v", Table, err)
+	}
+
+	return rows, nil
+}
+
+// exec sql of file, statements without args run in one simple query
+func exec(tx *gorm.DB, sql string) error {
+
+	if isEmpty(sql) {
+		return nil
+	}
+
+	return tx.Exec(sql).Error
+}
+
+// isEmpty sql has comments only
+func isEmpty(sql string) bool {
+
+	for _, line := range strings.Split(sql, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,103 @@
+package migrate
+
+import (
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// Test files are paired and ordered by version
+func TestLoad(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"0010_b.up.sql":   {Data: []byte("CREATE TABLE b (id int);")},
+		"0010_b.down.sql": {Data: []byte("DROP TABLE b;")},
+		"0002_a.up.sql":   {Data: []byte("CREATE TABLE a (id int);")},
+		"0002_a.down.sql": {Data: []byte("DROP TABLE a;")},
+		"README.md":       {Data: []byte("skipped")},
+	}
+
+	res, err := Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 2 || res[0].Version != 2 || res[1].Name != "b" || res[1].Down != "DROP TABLE b;" {
+		t.Errorf("Unexpected migrations %+v", res)
+	}
+
+	for name, fsys := range map[string]fstest.MapFS{
+		"no down":  {"0001_a.up.sql": {Data: []byte("SELECT 1;")}},
+		"bad name": {"a.up.sql": {Data: []byte("SELECT 1;")}},
+		"two names": {
+			"0001_a.up.sql":   {Data: []byte("SELECT 1;")},
+			"0001_b.down.sql": {Data: []byte("SELECT 1;")},
+		},
+	} {
+		if _, err := Load(fsys); err == nil {
+			t.Errorf("Expected error on %v", name)
+		}
+	}
+}
+
+// Test embedded migrations load, comment only sql is empty
+func TestEmbedded(t *testing.T) {
+
+	for _, dialect := range []string{"postgres", "sqlite"} {
+		res, err := Embedded(dialect)
+		if err != nil || len(res) == 0 {
+			t.Fatalf("Expected embedded migrations of %v, got %v %v", dialect, res, err)
+		}
+	}
+
+	if _, err := Embedded("oracle"); err == nil {
+		t.Error("Expected error on unknown dialect")
+	}
+
+	if !isEmpty("-- comment\n\n") || isEmpty("-- comment\nSELECT 1;") {
+		t.Error("Unexpected isEmpty")
+	}
+}
+
+// Test up, status, down on sqlite
+func TestMigrator(t *testing.T) {
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrations, err := Load(fstest.MapFS{
+		"0001_a.up.sql":   {Data: []byte("CREATE TABLE a (id int); CREATE TABLE a2 (id int);")},
+		"0001_a.down.sql": {Data: []byte("DROP TABLE a2; DROP TABLE a;")},
+		"0002_b.up.sql":   {Data: []byte("CREATE TABLE b (id int);")},
+		"0002_b.down.sql": {Data: []byte("DROP TABLE b;")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	x := New(db, migrations)
+
+	applied, err := x.Up()
+	if err != nil || len(applied) != 2 || !db.Migrator().HasTable("a2") {
+		t.Fatalf("Expected 2 applied, got %v %v", applied, err)
+	}
+
+	if applied, err := x.Up(); err != nil || len(applied) != 0 {
+		t.Errorf("Expected nothing to apply, got %v %v", applied, err)
+	}
+
+	reverted, err := x.Down(1)
+	if err != nil || len(reverted) != 1 || reverted[0].Version != 2 || db.Migrator().HasTable("b") {
+		t.Fatalf("Expected 0002 reverted, got %v %v", reverted, err)
+	}
+
+	status, err := x.Status()
+	if err != nil || len(status) != 2 || status[0].AppliedAt == nil || status[1].AppliedAt != nil {
+		t.Errorf("Unexpected status %+v %v", status, err)
+	}
+}
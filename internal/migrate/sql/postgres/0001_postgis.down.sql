@@ -0,0 +1 @@
+-- postgis is kept, layer tables depend on it
@@ -0,0 +1 @@
+-- no spatial extension, geometry columns are EWKB hex text
@@ -0,0 +1,236 @@
+package repository
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go-gis/internal/geo/geojson"
+	"go-gis/internal/geo/wkb"
+	"go-gis/internal/geo/wkt"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// DefaultSRID of geometries without SRID
+const DefaultSRID = 4326
+
+// Geometry PostGIS geometry column of models, nil Geometry is NULL.
+// Database values are EWKB, json is GeoJSON geometry
+type Geometry struct {
+	*geojson.Geometry
+	SRID int // 0 = DefaultSRID
+}
+
+// NewGeometry geometry in DefaultSRID
+func NewGeometry(g *geojson.Geometry) Geometry {
+	return Geometry{Geometry: g}
+}
+
+// ParseWKT WKT or EWKT
+func ParseWKT(text string) (Geometry, error) {
+
+	g, srid, err := wkt.Parse(text)
+	if err != nil {
+		return Geometry{}, err
+	}
+
+	return Geometry{Geometry: g, SRID: srid}, nil
+}
+
+// WKT EWKT text, empty if nil
+func (x Geometry) WKT() string {
+
+	if x.Geometry == nil {
+		return ""
+	}
+
+	res, _ := wkt.Marshal(x.Geometry, x.srid())
+
+	return res
+}
+
+func (x Geometry) srid() int {
+	if x.SRID > 0 {
+		return x.SRID
+	}
+	return DefaultSRID
+}
+
+// GormDataType column type of AutoMigrate
+func (Geometry) GormDataType() string { return "geometry" }
+
+// GormDBDataType text of EWKB hex on sqlite
+func (Geometry) GormDBDataType(db *gorm.DB, _ *schema.Field) string {
+
+	if db.Dialector.Name() == SQLITE {
+		return "text"
+	}
+
+	return "geometry"
+}
+
+// Value hex EWKB, PostGIS text input
+func (x Geometry) Value() (driver.Value, error) {
+
+	if x.Geometry == nil {
+		return nil, nil
+	}
+
+	data, err := wkb.Encode(x.Geometry, x.srid())
+	if err != nil {
+		return nil, err
+	}
+
+	return hex.EncodeToString(data), nil
+}
+
+// Scan EWKB, binary or hex text
+func (x *Geometry) Scan(src any) error {
+
+	var data []byte
+
+	switch v := src.(type) {
+	case nil:
+		*x = Geometry{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("error geometry scan of %T", src)
+	}
+
+	// binary starts with byte order 0 or 1, text with hex digits
+	if len(data) > 0 && data[0] != 0 && data[0] != 1 {
+		decoded := make([]byte, hex.DecodedLen(len(data)))
+		if _, err := hex.Decode(decoded, data); err != nil {
+			return fmt.Errorf("error geometry scan: %v", err)
+		}
+		data = decoded
+	}
+
+	g, srid, err := wkb.Decode(data)
+	if err != nil {
+		return err
+	}
+
+	*x = Geometry{Geometry: g, SRID: srid}
+
+	return nil
+}
+
+// MarshalJSON GeoJSON geometry or null
+func (x Geometry) MarshalJSON() ([]byte, error) {
+
+	if x.Geometry == nil {
+		return []byte("null"), nil
+	}
+
+	return x.Geometry.MarshalJSON()
+}
+
+// UnmarshalJSON GeoJSON geometry or null, SRID is DefaultSRID
+func (x *Geometry) UnmarshalJSON(data []byte) error {
+
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		*x = Geometry{}
+		return nil
+	}
+
+	g := &geojson.Geometry{}
+	if err := json.Unmarshal(data, g); err != nil {
+		return err
+	}
+
+	*x = Geometry{Geometry: g}
+
+	return nil
+}
+
+var reIdent = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// QuoteIdent quote "name" or "schema.name", name must be identifier
+func QuoteIdent(name string) (string, error) {
+
+	if !reIdent.MatchString(name) {
+		return "", fmt.Errorf("error invalid identifier: %q", name)
+	}
+
+	parts := strings.Split(name, ".")
+	for i, v := range parts {
+		parts[i] = `"` + v + `"`
+	}
+
+	return strings.Join(parts, "."), nil
+}
+
+// EnsurePostGIS create postgis extension if not exists, nothing on sqlite
+func (rep *repository) EnsurePostGIS() error {
+
+	if rep.Dialect() == SQLITE {
+		return nil
+	}
+
+	// search_path of schema starts with tenant schema, postgis is shared in public,
+	// same as migration 0001_postgis
+	err := rep.db.Exec("CREATE EXTENSION IF NOT EXISTS postgis SCHEMA public").Error
+	if err != nil {
+		return fmt.Errorf("error on postgis extension: %v", err)
+	}
+
+	return nil
+}
+
+// CreateSpatialIndex GiST index "{table}_{column}_gist" if not exists, nothing on sqlite
+func (rep *repository) CreateSpatialIndex(table, column string) error {
+
+	if rep.Dialect() == SQLITE {
+		return nil
+	}
+
+	t, err := QuoteIdent(table)
+	if err != nil {
+		return err
+	}
+
+	c, err := QuoteIdent(column)
+	if err != nil || strings.Contains(column, ".") {
+		return fmt.Errorf("error invalid column: %q", column)
+	}
+
+	name := table[strings.LastIndex(table, ".")+1:] + "_" + column + "_gist"
+
+	err = rep.db.Exec(fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s" ON %s USING GIST (%s)`, name, t, c)).Error
+	if err != nil {
+		return fmt.Errorf("error on index %v: %v", name, err)
+	}
+
+	return nil
+}
+
+// HasTable table or "schema.table" exists
+func (rep *repository) HasTable(table string) (bool, error) {
+
+	if _, err := QuoteIdent(table); err != nil {
+		return false, err
+	}
+
+	if rep.Dialect() == SQLITE {
+		return rep.db.Migrator().HasTable(table), nil
+	}
+
+	exists := false
+
+	err := rep.db.Raw("SELECT to_regclass(?) IS NOT NULL", table).Scan(&exists).Error
+	if err != nil {
+		return false, fmt.Errorf("error on table %v: %v", table, err)
+	}
+
+	return exists, nil
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"encoding/json"
+	"go-gis/internal/config"
+	"go-gis/internal/geo/geojson"
+	"path/filepath"
+	"testing"
+)
+
+// Test Value, Scan round trip of hex and binary EWKB
+func TestGeometryValue(t *testing.T) {
+
+	g := NewGeometry(geojson.NewLineString([]geojson.Position{{1, 2}, {3, 4}}))
+
+	v, err := g.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text, ok := v.(string)
+	if !ok {
+		t.Fatalf("Expected hex string, got %T", v)
+	}
+
+	res := Geometry{}
+	if err := res.Scan(text); err != nil {
+		t.Fatal(err)
+	}
+	if res.WKT() != "SRID=4326;LINESTRING(1 2,3 4)" {
+		t.Errorf("Unexpected %v", res.WKT())
+	}
+
+	if err := res.Scan(nil); err != nil || res.Geometry != nil {
+		t.Errorf("Expected nil geometry, got %v %v", res, err)
+	}
+
+	if v, _ := res.Value(); v != nil {
+		t.Errorf("Expected NULL, got %v", v)
+	}
+}
+
+// Test GeoJSON and WKT of model field
+func TestGeometryJSON(t *testing.T) {
+
+	model := struct {
+		Geom Geometry `json:"geom"`
+	}{}
+
+	if err := json.Unmarshal([]byte(`{"geom":{"type":"Point","coordinates":[1,2]}}`), &model); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := json.Marshal(model)
+	if string(data) != `{"geom":{"type":"Point","coordinates":[1,2]}}` {
+		t.Errorf("Unexpected %s", data)
+	}
+
+	g, err := ParseWKT("SRID=3857;POINT(10 20)")
+	if err != nil || g.SRID != 3857 || g.Point != (geojson.Position{10, 20}) {
+		t.Errorf("Unexpected %+v %v", g, err)
+	}
+}
+
+// Test geometry column on sqlite
+func TestGeometrySQLite(t *testing.T) {
+
+	cfg := config.NewAppConfig()
+	cfg.DB.Dialect = SQLITE
+	cfg.DB.File = filepath.Join(t.TempDir(), "test.db")
+
+	rep := MustNewRepository(cfg)
+	defer func() { _ = rep.Close() }()
+
+	type place struct {
+		ID   int
+		Geom Geometry
+	}
+
+	if err := rep.AutoMigrate(&place{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := rep.EnsurePostGIS(); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := rep.HasTable("places"); !ok || err != nil {
+		t.Fatalf("Expected table places, got %v %v", ok, err)
+	}
+
+	g, _ := ParseWKT("POLYGON((0 0,1 0,1 1,0 0))")
+	if err := rep.Create(&place{ID: 1, Geom: g}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	res := place{}
+	if err := rep.First(&res, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Geom.WKT() != "SRID=4326;POLYGON((0 0,1 0,1 1,0 0))" {
+		t.Errorf("Unexpected %v", res.Geom.WKT())
+	}
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	config "go-gis/internal/config"
+	xlog "go-gis/internal/util/utillog"
+)
+
+var queryDurationMetric = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "gis_db_query_duration_seconds",
+	Help:    "Latency of database queries.",
+	Buckets: prometheus.ExponentialBuckets(0.0005, 4, 9), // 0.5ms..32s
+}, []string{"operation"})
+
+// logLevels of config.LogLevels
+var logLevels = map[string]gormlogger.LogLevel{
+	"silent": gormlogger.Silent,
+	"error":  gormlogger.Error,
+	"warn":   gormlogger.Warn, // errors and slow queries
+	"info":   gormlogger.Info, // all queries
+}
+
+// queryLogger gorm logger of xlog.DefaultLogger, observes duration of every query
+type queryLogger struct {
+	level  gormlogger.LogLevel
+	slow   time.Duration // 0 = no slow query log
+	params bool          // parameter values in logged sql, redacted if false
+}
+
+var _ gormlogger.Interface = (*queryLogger)(nil)
+var _ gorm.ParamsFilter = (*queryLogger)(nil)
+
+// newQueryLogger logger of DB.LogLevel, warn if empty
+func newQueryLogger(cfg *config.Database) *queryLogger {
+
+	level, ok := logLevels[cfg.LogLevel]
+	if !ok {
+		level = gormlogger.Warn
+	}
+
+	return &queryLogger{
+		level:  level,
+		slow:   time.Duration(cfg.SlowQuery) * time.Millisecond,
+		params: cfg.LogParams,
+	}
+}
+
+// LogMode logger of level
+func (l *queryLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	res := *l
+	res.level = level
+	return &res
+}
+
+func (l *queryLogger) Info(ctx context.Context, format string, v ...interface{}) {
+	if l.level >= gormlogger.Info {
+		xlog.Info(format, v...)
+	}
+}
+
+func (l *queryLogger) Warn(ctx context.Context, format string, v ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		xlog.Warn(format, v...)
+	}
+}
+
+func (l *queryLogger) Error(ctx context.Context, format string, v ...interface{}) {
+	if l.level >= gormlogger.Error {
+		xlog.Error(format, v...)
+	}
+}
+
+// ParamsFilter sql is logged with placeholders unless params are enabled
+func (l *queryLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if !l.params {
+		return sql, nil
+	}
+	return sql, params
+}
+
+// register redaction of row queries, Scan logs sql of them before ParamsFilter
+func (l *queryLogger) register(db *gorm.DB) error {
+
+	if l.params {
+		return nil
+	}
+
+	return db.Callback().Row().After("gorm:row").Register("repository:redact", func(db *gorm.DB) {
+		if !db.DryRun {
+			db.Statement.Vars = nil // query is sent, vars are needed by log only
+		}
+	})
+}
+
+// Trace log errors, slow queries or all queries by level
+func (l *queryLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+
+	elapsed := time.Since(begin)
+
+	sql, rows := fc()
+
+	queryDurationMetric.WithLabelValues(operation(sql)).Observe(elapsed.Seconds())
+
+	var (
+		level = slog.LevelDebug
+		msg   string
+	)
+
+	switch {
+	case err != nil && l.level >= gormlogger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		level, msg = slog.LevelError, "db query error"
+	case l.slow > 0 && elapsed > l.slow && l.level >= gormlogger.Warn:
+		level, msg = slog.LevelWarn, "db slow query"
+	case l.level >= gormlogger.Info:
+		level, msg = slog.LevelInfo, "db query"
+	default:
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("sql", sql),
+		slog.Float64("duration_ms", float64(elapsed.Microseconds())/1000),
+		slog.Int64("rows", rows),
+	}
+	if level == slog.LevelError {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+
+	xlog.DefaultLogger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// operations of query duration metric, others are "other"
+var operations = []string{"select", "insert", "update", "delete", "with", "create", "alter", "drop"}
+
+// operation lower first keyword of sql
+func operation(sql string) string {
+
+	word, _, _ := strings.Cut(strings.TrimSpace(sql), " ")
+	word = strings.ToLower(word)
+
+	for _, v := range operations {
+		if word == v {
+			return v
+		}
+	}
+
+	return "other"
+}
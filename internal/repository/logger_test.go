@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	config "go-gis/internal/config"
+	xlog "go-gis/internal/util/utillog"
+)
+
+func captureLog(t *testing.T) *bytes.Buffer {
+
+	buf := &bytes.Buffer{}
+	xlog.SetOutput(buf)
+	t.Cleanup(func() { xlog.SetOutput(os.Stdout) })
+
+	return buf
+}
+
+// Test sql log of levels, slow queries and redacted params
+func TestQueryLogger(t *testing.T) {
+
+	buf := captureLog(t)
+
+	l := newQueryLogger(&config.Database{LogLevel: "warn", SlowQuery: 100})
+	query := func() (string, int64) { return "SELECT * FROM items WHERE name = 'secret'", 2 }
+
+	l.Trace(context.Background(), time.Now(), query, nil)
+	if buf.Len() != 0 {
+		t.Errorf("Expected no log of fast query, got %s", buf)
+	}
+
+	l.Trace(context.Background(), time.Now().Add(-time.Second), query, nil)
+	if s := buf.String(); !strings.Contains(s, `"msg":"db slow query"`) || !strings.Contains(s, `"rows":2`) {
+		t.Errorf("Expected slow query, got %s", s)
+	}
+
+	buf.Reset()
+	l.Trace(context.Background(), time.Now(), query, errors.New("boom"))
+	if s := buf.String(); !strings.Contains(s, `"level":"ERROR"`) || !strings.Contains(s, `"error":"boom"`) {
+		t.Errorf("Expected error, got %s", s)
+	}
+
+	buf.Reset()
+	l.LogMode(0).Trace(context.Background(), time.Now(), query, errors.New("boom"))
+	newQueryLogger(&config.Database{LogLevel: "silent"}).Trace(context.Background(), time.Now(), query, errors.New("boom"))
+	if buf.Len() != 0 {
+		t.Errorf("Expected silent, got %s", buf)
+	}
+
+	if sql, params := l.ParamsFilter(context.Background(), "x = ?", "secret"); sql != "x = ?" || params != nil {
+		t.Errorf("Expected redacted params, got %v", params)
+	}
+}
+
+// Test logged sql and duration metric of repository queries
+func TestQueryLoggerRepository(t *testing.T) {
+
+	cfg := config.NewAppConfig()
+	cfg.DB.Dialect = SQLITE
+	cfg.DB.File = filepath.Join(t.TempDir(), "test.db")
+	cfg.DB.LogLevel = "info"
+
+	rep := MustNewRepository(cfg)
+	defer func() { _ = rep.Close() }()
+
+	buf := captureLog(t)
+
+	n := 0
+	if err := rep.Raw("SELECT ?", 918273645).Scan(&n).Error; err != nil || n != 918273645 {
+		t.Fatalf("Unexpected %v %v", n, err)
+	}
+
+	if err := rep.Exec("SELECT ?", 546372819).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if s := buf.String(); !strings.Contains(s, `"sql":"SELECT ?"`) || strings.Contains(s, "918273645") || strings.Contains(s, "546372819") {
+		t.Errorf("Expected redacted sql, got %s", s)
+	}
+
+	if operation("  select 1") != "select" || operation("VACUUM") != "other" {
+		t.Errorf("Unexpected operations")
+	}
+
+	if testutil.CollectAndCount(queryDurationMetric) == 0 {
+		t.Errorf("Expected select duration")
+	}
+
+	cfg.DB.File = filepath.Join(t.TempDir(), "params.db")
+	cfg.DB.LogParams = true
+
+	rep2 := MustNewRepository(cfg)
+	defer func() { _ = rep2.Close() }()
+
+	buf.Reset()
+	_ = rep2.Raw("SELECT ?", 42).Scan(&n)
+
+	if s := buf.String(); !strings.Contains(s, `"sql":"SELECT 42"`) {
+		t.Errorf("Expected sql with params, got %s", s)
+	}
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	config "go-gis/internal/config"
+)
+
+// postgresDSN key=value connection string, cfg.DSN is used as is with runtime params.
+// Relative ssl files are resolved against cert dir
+func postgresDSN(cfg *config.Database) string {
+
+	if cfg.DSN != "" {
+		return withRuntimeParams(cfg.DSN, runtimeParams(cfg))
+	}
+
+	params := [][2]string{
+		{"host", cfg.Host},
+		{"port", cfg.Port},
+		{"user", cfg.User},
+		{"dbname", cfg.Name},
+		{"password", cfg.Password},
+		{"sslmode", cfg.PostgresSSLMode()},
+		{"sslrootcert", cfg.SSLFile(cfg.SSLRootCert)},
+		{"sslcert", cfg.SSLFile(cfg.SSLCert)},
+		{"sslkey", cfg.SSLFile(cfg.SSLKey)},
+	}
+
+	parts := []string{}
+	for _, v := range slices.Concat(params, runtimeParams(cfg)) {
+		if v[1] != "" {
+			parts = append(parts, v[0]+"="+quoteDSNValue(v[1]))
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// quoteDSNValue single quoted if value has spaces or quotes, libpq escaping
+func quoteDSNValue(v string) string {
+
+	if !strings.ContainsAny(v, ` '\`) {
+		return v
+	}
+
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+
+	return "'" + v + "'"
+}
+
+// searchPath schema first, public keeps postgis types and functions
+func searchPath(schema string) string {
+
+	if schema == "" {
+		return ""
+	}
+
+	return schema + ",public"
+}
+
+// runtimeParams settings of connections, empty if default
+func runtimeParams(cfg *config.Database) [][2]string {
+
+	res := [][2]string{}
+
+	if cfg.Schema != "" {
+		res = append(res, [2]string{"search_path", searchPath(cfg.Schema)})
+	}
+
+	if cfg.QueryTimeout > 0 {
+		res = append(res, [2]string{"statement_timeout", strconv.Itoa(cfg.QueryTimeout * 1000)}) // ms
+	}
+
+	return res
+}
+
+// withRuntimeParams add params to url or key=value dsn
+func withRuntimeParams(dsn string, params [][2]string) string {
+
+	isURL := strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://")
+
+	for _, v := range params {
+		switch {
+		case !isURL:
+			dsn += " " + v[0] + "=" + quoteDSNValue(v[1])
+		case strings.Contains(dsn, "?"):
+			dsn += "&" + v[0] + "=" + url.QueryEscape(v[1])
+		default:
+			dsn += "?" + v[0] + "=" + url.QueryEscape(v[1])
+		}
+	}
+
+	return dsn
+}
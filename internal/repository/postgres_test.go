@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	config "go-gis/internal/config"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestPostgresDSN(t *testing.T) {
+
+	base := config.Database{Host: "db", Port: "5432", User: "gis", Name: "gis", Password: "it's secret"}
+
+	cases := []struct {
+		name string
+		cfg  func(x *config.Database)
+		dsn  string
+	}{
+		{"disable", func(x *config.Database) {},
+			`host=db port=5432 user=gis dbname=gis password='it\'s secret' sslmode=disable`},
+		{"ssl", func(x *config.Database) { x.SSL = true },
+			`host=db port=5432 user=gis dbname=gis password='it\'s secret' sslmode=require`},
+		{"files", func(x *config.Database) {
+			x.SSLMode, x.CertDir = "verify-full", "/certs"
+			x.SSLRootCert, x.SSLCert, x.SSLKey = "ca.crt", "/etc/client.crt", "client.key"
+		}, `host=db port=5432 user=gis dbname=gis password='it\'s secret' sslmode=verify-full ` +
+			`sslrootcert=/certs/ca.crt sslcert=/etc/client.crt sslkey=/certs/client.key`},
+		{"dsn", func(x *config.Database) { x.DSN, x.SSL = "postgres://u@h/db?sslmode=verify-ca", true },
+			"postgres://u@h/db?sslmode=verify-ca"},
+		{"schema", func(x *config.Database) { x.Schema = "tenant_a" },
+			`host=db port=5432 user=gis dbname=gis password='it\'s secret' sslmode=disable search_path=tenant_a,public`},
+		{"dsn schema", func(x *config.Database) { x.DSN, x.Schema = "postgres://u@h/db?sslmode=require", "tenant_a" },
+			"postgres://u@h/db?sslmode=require&search_path=tenant_a%2Cpublic"},
+		{"timeout", func(x *config.Database) { x.QueryTimeout = 30 },
+			`host=db port=5432 user=gis dbname=gis password='it\'s secret' sslmode=disable statement_timeout=30000`},
+		{"dsn timeout", func(x *config.Database) { x.DSN, x.Schema, x.QueryTimeout = "postgres://u@h/db", "tenant_a", 5 },
+			"postgres://u@h/db?search_path=tenant_a%2Cpublic&statement_timeout=5000"},
+		{"keyword dsn schema", func(x *config.Database) { x.DSN, x.Schema = "host=h dbname=db", "tenant_a" },
+			"host=h dbname=db search_path=tenant_a,public"},
+	}
+
+	for _, c := range cases {
+		cfg := base
+		c.cfg(&cfg)
+		if v := postgresDSN(&cfg); v != c.dsn {
+			t.Errorf("%v: %v, expected %v", c.name, v, c.dsn)
+		}
+	}
+
+	tenant := base
+	tenant.Schema = "tenant_a"
+
+	parsed, err := pgconn.ParseConfig(postgresDSN(&tenant))
+	if err != nil || parsed.Password != "it's secret" || parsed.RuntimeParams["search_path"] != "tenant_a,public" {
+		t.Fatalf("parse: %v %q %v", err, parsed.Password, parsed.RuntimeParams)
+	}
+}
+
+// TestPostgresTLS handshake of verify-full with client certificate against postgres SSLRequest of fake server
+func TestPostgresTLS(t *testing.T) {
+
+	dir := t.TempDir()
+	ca := writeTestCerts(t, dir)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	serverCert, err := tls.LoadX509KeyPair(filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clients := make(chan string, 1)
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			clients <- err.Error()
+			return
+		}
+		defer conn.Close()
+
+		// SSLRequest: length 8, code 80877103
+		req := make([]byte, 8)
+		if _, err := io.ReadFull(conn, req); err != nil || binary.BigEndian.Uint32(req[4:]) != 80877103 {
+			clients <- "no ssl request"
+			return
+		}
+		_, _ = conn.Write([]byte{'S'})
+
+		pool := x509.NewCertPool()
+		pool.AddCert(ca)
+
+		s := tls.Server(conn, &tls.Config{
+			Certificates: []tls.Certificate{serverCert},
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    pool,
+			MinVersion:   tls.VersionTLS12,
+		})
+		if err := s.Handshake(); err != nil {
+			clients <- err.Error()
+			return
+		}
+		clients <- s.ConnectionState().PeerCertificates[0].Subject.CommonName
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	cfg := &config.Database{
+		Host: host, Port: port, User: "gis", Name: "gis", Password: "gis",
+		SSLMode: "verify-full", CertDir: dir,
+		SSLRootCert: "ca.crt", SSLCert: "client.crt", SSLKey: "client.key",
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// server closes after handshake, connect fails on startup
+	_, _ = pgconn.Connect(ctx, postgresDSN(cfg))
+
+	if v := <-clients; v != "gis-client" {
+		t.Fatalf("handshake: %v", v)
+	}
+}
+
+// writeTestCerts self-signed ca, server cert of 127.0.0.1 and client cert, returns ca
+func writeTestCerts(t *testing.T, dir string) *x509.Certificate {
+
+	t.Helper()
+
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "gis-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+	writePEM(t, filepath.Join(dir, "ca.crt"), "CERTIFICATE", caDER)
+
+	issue := func(name string, serial int64, usage x509.ExtKeyUsage, ips []net.IP) {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "gis-" + name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  ips,
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyDER, _ := x509.MarshalECPrivateKey(key)
+		writePEM(t, filepath.Join(dir, name+".crt"), "CERTIFICATE", der)
+		writePEM(t, filepath.Join(dir, name+".key"), "EC PRIVATE KEY", keyDER)
+	}
+
+	issue("server", 2, x509.ExtKeyUsageServerAuth, []net.IP{net.IPv4(127, 0, 0, 1)})
+	issue("client", 3, x509.ExtKeyUsageClientAuth, nil)
+
+	return ca
+}
+
+func writePEM(t *testing.T, name, kind string, der []byte) {
+	if err := os.WriteFile(name, pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTablePrefix(t *testing.T) {
+
+	cfg := &config.Database{Dialect: POSTGRES, Schema: "tenant_a"}
+	if v := tablePrefix(cfg).TableName("Track"); v != "tenant_a.tracks" {
+		t.Errorf("table: %v", v)
+	}
+
+	cfg.Dialect = SQLITE
+	if v := tablePrefix(cfg).TableName("Track"); v != "tracks" {
+		t.Errorf("sqlite table: %v", v)
+	}
+}
+
+// Test DB.MaxOpen split across default and tenant pools
+func TestPoolShare(t *testing.T) {
+
+	cfg := config.NewAppConfig()
+	if v := poolShare(cfg); v.MaxOpen != 0 {
+		t.Errorf("Expected unlimited pool without tenants, got %v", v.MaxOpen)
+	}
+
+	cfg.Tenants.Enabled = true
+	cfg.Tenants.Schemas = map[string]string{"a": "tenant_a", "b": "tenant_b", "c": "tenant_c", "d": "tenant_d"}
+
+	if v := poolShare(cfg); v.MaxOpen != tenantPoolBudget/5 || v.MaxIdle != v.MaxOpen {
+		t.Errorf("Expected share of budget, got %v %v", v.MaxOpen, v.MaxIdle)
+	}
+
+	cfg.DB.MaxOpen, cfg.DB.MaxIdle = 3, 2
+	if v := poolShare(cfg); v.MaxOpen != 1 || v.MaxIdle != 1 {
+		t.Errorf("Expected one connection per pool, got %v %v", v.MaxOpen, v.MaxIdle)
+	}
+}
@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	config "go-gis/internal/config"
+	xlog "go-gis/internal/util/utillog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+)
+
+// replicaLagSQL seconds behind primary, 0 if replay is caught up or not in recovery
+const replicaLagSQL = `SELECT CASE
+	WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0) END`
+
+// replica read replica pool, healthy if reachable and lag within limit
+type replica struct {
+	name    string
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// replicaSet routes reads of primary pool to healthy replicas, round-robin, primary if none is healthy
+type replicaSet struct {
+	primary  *sql.DB
+	replicas []*replica
+	maxLag   float64 // seconds
+	next     atomic.Uint64
+
+	stop context.CancelFunc
+	done chan struct{}
+}
+
+// primaryKey statement setting of reads on primary
+const primaryKey = "repository:primary"
+
+// reWrite sql which writes or locks, routed to primary
+var reWrite = regexp.MustCompile(`(?i)\b(insert|update|delete|merge|create|alter|drop|truncate|share|nextval|setval|set_config|pg_advisory\w*)\b`)
+
+// useReplicas route reads of db to replicas of cfg, writes, transactions and dedicated connections stay on primary.
+// Replicas are checked before return and every cfg.ReplicaCheck seconds
+func useReplicas(db *gorm.DB, cfg config.Database) (*replicaSet, error) {
+
+	primary, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	res := &replicaSet{primary: primary, maxLag: float64(cfg.ReplicaLag)}
+
+	for _, v := range cfg.Replicas {
+		conn, err := openPostgres(replicaDSN(cfg, v))
+		if err != nil {
+			res.close()
+			return nil, err
+		}
+		configurePool(conn, cfg)
+		res.replicas = append(res.replicas, &replica{name: v, db: conn})
+	}
+
+	if err := res.register(db); err != nil {
+		res.close()
+		return nil, err
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	res.stop, res.done = stop, make(chan struct{})
+
+	res.check(ctx)
+
+	go func() {
+		defer close(res.done)
+
+		ticker := time.NewTicker(time.Duration(cfg.ReplicaCheck) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				res.check(ctx)
+			}
+		}
+	}()
+
+	return res, nil
+}
+
+// replicaDSN dsn of replica, "host" and "host:port" share user, name and ssl of primary
+func replicaDSN(cfg config.Database, replica string) string {
+
+	if strings.Contains(replica, "=") || strings.Contains(replica, "://") {
+		cfg.DSN = replica
+		return postgresDSN(&cfg)
+	}
+
+	cfg.DSN, cfg.Host = "", replica
+	if host, port, err := net.SplitHostPort(replica); err == nil {
+		cfg.Host, cfg.Port = host, port
+	}
+
+	return postgresDSN(&cfg)
+}
+
+// openPostgres pool of dsn without connecting
+func openPostgres(dsn string) (*sql.DB, error) {
+
+	cfg, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return stdlib.OpenDB(*cfg), nil
+}
+
+// register routing before queries of db
+func (x *replicaSet) register(db *gorm.DB) error {
+
+	for _, err := range []error{
+		db.Callback().Query().Before("*").Register("repository:replica", x.route),
+		db.Callback().Row().Before("*").Register("repository:replica", x.route),
+		db.Callback().Raw().Before("*").Register("repository:replica", x.route),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// route read of primary pool to replica, transactions and dedicated connections are kept
+func (x *replicaSet) route(db *gorm.DB) {
+
+	stmt := db.Statement
+
+	if stmt.ConnPool != gorm.ConnPool(x.primary) {
+		return
+	}
+
+	if _, ok := stmt.Settings.Load(primaryKey); ok {
+		return
+	}
+
+	if sql := strings.TrimSpace(stmt.SQL.String()); sql != "" {
+		// raw sql
+		head := strings.ToUpper(sql[:min(len(sql), 4)])
+		if (head != "SELE" && head != "WITH") || reWrite.MatchString(sql) {
+			return
+		}
+	} else if _, locking := stmt.Clauses["FOR"]; locking {
+		return
+	}
+
+	if v := x.pick(); v != nil {
+		stmt.ConnPool = v
+	}
+}
+
+// pick healthy replica in turn, nil if none is healthy
+func (x *replicaSet) pick() *sql.DB {
+
+	n := uint64(len(x.replicas))
+	start := x.next.Add(1)
+
+	for i := range n {
+		if v := x.replicas[(start+i)%n]; v.healthy.Load() {
+			return v.db
+		}
+	}
+
+	return nil
+}
+
+// check lag of replicas, changes of health are logged
+func (x *replicaSet) check(ctx context.Context) {
+
+	for _, v := range x.replicas {
+
+		lag := 0.0
+
+		checkCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		err := v.db.QueryRowContext(checkCtx, replicaLagSQL).Scan(&lag)
+		cancel()
+
+		healthy := err == nil && lag <= x.maxLag
+
+		if healthy == v.healthy.Swap(healthy) {
+			continue
+		}
+
+		switch {
+		case healthy:
+			xlog.Info("db replica is healthy: %v", v.name)
+		case err != nil:
+			xlog.Warn("db replica is excluded: %v: %v", v.name, err)
+		default:
+			xlog.Warn("db replica is excluded: %v: lag %.1fs", v.name, lag)
+		}
+	}
+}
+
+// close stop checks and close replica pools
+func (x *replicaSet) close() {
+
+	if x.stop != nil {
+		x.stop()
+		<-x.done
+	}
+
+	for _, v := range x.replicas {
+		_ = v.db.Close()
+	}
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	config "go-gis/internal/config"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// Test routing of reads with sqlite databases named by their "name" table
+func TestReplicaRoute(t *testing.T) {
+
+	dir := t.TempDir()
+
+	open := func(name string) *sql.DB {
+		db, err := sql.Open("sqlite", "file:"+filepath.Join(dir, name+".db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec("CREATE TABLE name (v text); INSERT INTO name VALUES ('" + name + "')"); err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}
+
+	primary := open("primary")
+	defer primary.Close()
+
+	db, err := gorm.Open(&sqlite.Dialector{Conn: primary}, &gorm.Config{SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	set := &replicaSet{primary: primary, maxLag: 10}
+	for _, v := range []string{"a", "b"} {
+		r := &replica{name: v, db: open(v)}
+		r.healthy.Store(true)
+		set.replicas = append(set.replicas, r)
+	}
+	defer set.close()
+
+	if err := set.register(db); err != nil {
+		t.Fatal(err)
+	}
+
+	rep := &mainRepository{repository: &repository{db: db}, cfg: config.Database{Dialect: SQLITE}}
+
+	name := func(r AppRepository, sql string) string {
+		v := ""
+		if err := r.Raw(sql).Scan(&v).Error; err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	if a, b := name(rep, "SELECT v FROM name"), name(rep, "SELECT v FROM name"); a == b || a == "primary" || b == "primary" {
+		t.Errorf("Expected round-robin of replicas, got %v %v", a, b)
+	}
+
+	if v := name(rep, "WITH n AS (SELECT v FROM name) SELECT v FROM n"); v == "primary" {
+		t.Errorf("Expected replica of WITH, got %v", v)
+	}
+
+	if v := name(rep.Primary(), "SELECT v FROM name"); v != "primary" {
+		t.Errorf("Expected primary, got %v", v)
+	}
+
+	for sql, write := range map[string]bool{
+		"WITH u AS (UPDATE t SET v = 1 RETURNING v) SELECT v FROM u": true,
+		"SELECT pg_advisory_lock(1)":                                 true,
+		"SELECT * FROM t FOR UPDATE":                                 true,
+		"SELECT created_at, updated_by FROM t":                       false,
+	} {
+		if reWrite.MatchString(sql) != write {
+			t.Errorf("Expected write %v of %v", write, sql)
+		}
+	}
+
+	_ = rep.Transaction(func(tx AppRepository) error {
+		if v := name(tx, "SELECT v FROM name"); v != "primary" {
+			t.Errorf("Expected primary in transaction, got %v", v)
+		}
+		return nil
+	})
+
+	if err := rep.Exec("UPDATE name SET v = 'primary2'").Error; err != nil {
+		t.Fatal(err)
+	}
+	if v := name(rep.Primary(), "SELECT v FROM name"); v != "primary2" {
+		t.Errorf("Expected update on primary, got %v", v)
+	}
+
+	rows := []struct{ V string }{}
+	if err := rep.Driver().Table("name").Find(&rows).Error; err != nil || len(rows) != 1 || rows[0].V == "primary2" {
+		t.Errorf("Expected find on replica, got %v %v", rows, err)
+	}
+
+	for _, v := range set.replicas {
+		v.healthy.Store(false)
+	}
+	if v := name(rep, "SELECT v FROM name"); v != "primary2" {
+		t.Errorf("Expected primary without healthy replicas, got %v", v)
+	}
+}
+
+func TestReplicaDSN(t *testing.T) {
+
+	cfg := config.Database{Host: "db", Port: "5432", User: "gis", Name: "gis", SSL: true}
+
+	for replica, dsn := range map[string]string{
+		"replica1":                  "host=replica1 port=5432 user=gis dbname=gis sslmode=require",
+		"replica2:6432":             "host=replica2 port=6432 user=gis dbname=gis sslmode=require",
+		"postgres://u@replica3/gis": "postgres://u@replica3/gis",
+	} {
+		if v := replicaDSN(cfg, replica); v != dsn {
+			t.Errorf("%v: %v, expected %v", replica, v, dsn)
+		}
+	}
+}
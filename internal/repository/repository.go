@@ -2,14 +2,21 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/glebarez/sqlite"
+	"golang.org/x/sync/singleflight"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
 	config "go-gis/internal/config"
+	xlog "go-gis/internal/util/utillog"
 )
 
 // AppRepository defines a interface for access the database.
@@ -32,55 +39,162 @@ type AppRepository interface {
 	Scopes(funcs ...func(*gorm.DB) *gorm.DB) *gorm.DB
 	ScanRows(rows *sql.Rows, result interface{}) error
 	Transaction(fc func(tx AppRepository) error) (err error)
+	// TransactionWith transaction of options, nested calls are savepoints of outer transaction
+	TransactionWith(opts TxOptions, fc func(tx AppRepository) error) (err error)
+	// WithContext repository of queries and transactions cancelled with ctx, same connection pool
+	WithContext(ctx context.Context) AppRepository
+	// Primary repository of reads on primary, read-your-writes outside of transaction
+	Primary() AppRepository
+	// Ping database of primary
+	Ping(ctx context.Context) error
 	Close() error
 	DropTableIfExists(value interface{}) error
 	AutoMigrate(value interface{}) error
+
+	// EnsurePostGIS create postgis extension if not exists
+	EnsurePostGIS() error
+	// CreateSpatialIndex GiST index of geometry column if not exists
+	CreateSpatialIndex(table, column string) error
+	// HasTable table or "schema.table" exists
+	HasTable(table string) (bool, error)
+	// Dialect POSTGRES or SQLITE
+	Dialect() string
+
+	// Schema postgres schema of tables, empty if default
+	Schema() string
+	// EnsureSchema create schema if not exists, nothing if default
+	EnsureSchema() error
+	// ForSchema repository of tenant schema on own connection pool, opened once
+	ForSchema(schema string) (AppRepository, error)
 }
 
 // repository defines a repository for access the database.
 type repository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	schema string
+
+	txDepth   int // 0 outside of transaction, savepoint level inside
+	txRetries int // default retries of transactions
 }
 
+// mainRepository owns connection pools of db and tenant schemas
 type mainRepository struct {
 	*repository
+
+	cfg      config.Database
+	replicas *replicaSet // nil if no replicas
+
+	mu      sync.RWMutex
+	schemas map[string]*mainRepository
+	opening singleflight.Group // pools of schemas by name, opened outside of mu
+	closed  atomic.Bool
 }
 
+// ErrClosed repository is closed
+var ErrClosed = errors.New("repository is closed")
+
 func MustNewRepository(config *config.AppConfig,
 
 // logger loggerX.AppLogger
 ) AppRepository {
 
-	db, err := connectDatabase(&config.DB) // logger
+	res, err := OpenRepository(config)
 
 	if err != nil {
 		panic(err)
 	}
 
-	dbSQL, _ := db.DB()
+	wait := time.Duration(config.DB.ConnectWait) * time.Second
 
-	err = dbSQL.Ping()
-	if err != nil {
+	if err := WaitConnected(context.Background(), res, wait); err != nil {
+		_ = res.Close()
 		panic(err)
 	}
 
-	if config.DB.MaxOpen > 0 {
-		dbSQL.SetMaxOpenConns(config.DB.MaxOpen)
+	return res
+}
+
+// OpenRepository repository without connecting, queries fail until database is reachable
+func OpenRepository(config *config.AppConfig) (AppRepository, error) {
+	return newRepository(poolShare(config))
+}
+
+// WaitConnected ping with exponential backoff until database is reachable,
+// last error after wait, no limit if wait is 0, ErrClosed if repository is closed
+func WaitConnected(ctx context.Context, rep AppRepository, wait time.Duration) error {
+
+	if wait > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, wait)
+		defer cancel()
+	}
+
+	backoff := 500 * time.Millisecond
+
+	for attempt := 1; ; attempt++ {
+
+		err := rep.Ping(ctx)
+		if err == nil || errors.Is(err, ErrClosed) {
+			return err
+		}
+
+		xlog.Warn("db is not reachable: [attempt: %v] [retry: %v] %v", attempt, backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("error db is not reachable after %v attempts: %v", attempt, err)
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, maxBackoff)
 	}
+}
+
+// maxBackoff of connect retries
+const maxBackoff = 10 * time.Second
 
-	if config.DB.MaxIdle > 0 {
-		dbSQL.SetMaxIdleConns(config.DB.MaxIdle)
+func newRepository(cfg config.Database) (*mainRepository, error) {
+
+	db, err := connectDatabase(&cfg)
+
+	if err != nil {
+		return nil, err
 	}
 
-	if config.DB.IdleTime > 0 {
-		dbSQL.SetConnMaxIdleTime(time.Duration(config.DB.IdleTime) * time.Second)
+	dbSQL, _ := db.DB()
+
+	configurePool(dbSQL, cfg)
+
+	res := &mainRepository{
+		repository: &repository{db: db, schema: cfg.Schema, txRetries: cfg.TxRetries},
+		cfg:        cfg,
+		schemas:    map[string]*mainRepository{},
 	}
 
-	res := &mainRepository{&repository{
-		db: db,
-	}}
+	if len(cfg.Replicas) > 0 && cfg.Dialect == POSTGRES {
+		if res.replicas, err = useReplicas(db, cfg); err != nil {
+			_ = dbSQL.Close()
+			return nil, err
+		}
+	}
 
-	return res
+	return res, nil
+}
+
+// configurePool limits of connections
+func configurePool(dbSQL *sql.DB, cfg config.Database) {
+
+	if cfg.MaxOpen > 0 {
+		dbSQL.SetMaxOpenConns(cfg.MaxOpen)
+	}
+
+	if cfg.MaxIdle > 0 {
+		dbSQL.SetMaxIdleConns(cfg.MaxIdle)
+	}
+
+	if cfg.IdleTime > 0 {
+		dbSQL.SetConnMaxIdleTime(time.Duration(cfg.IdleTime) * time.Second)
+	}
 }
 
 // db dialect type
@@ -89,40 +203,44 @@ const (
 	POSTGRES = "postgres"
 )
 
-func connectDatabase(
-	cfg *config.Database,
-	// logger loggerX.AppLogger,
-) (*gorm.DB, error) {
-	var dsn string
+func connectDatabase(cfg *config.Database) (*gorm.DB, error) {
+	logger := newQueryLogger(cfg)
+
 	gormConfig := &gorm.Config{
 		SkipDefaultTransaction: true,
-		// Logger:                 logger,
-		// NamingStrategy: schema.NamingStrategy{
-		// 	//SingularTable: true, //not *s as table suffix // use singular table name, table for `User` would be `user` with this option enabled
-		// 	//NoLowerCase:   true, //as-is // skip the snake_casing of names
-		// },
-		// Logger: loggerGorm.Default.LogMode(logger_gorm.Info),
+		DisableAutomaticPing:   true, // connection is checked by WaitConnected
+		NamingStrategy:         tablePrefix(cfg),
+		Logger:                 logger,
 	}
 
+	var dialector gorm.Dialector
+
 	switch cfg.Dialect {
 	case POSTGRES:
-		sslmode := "disable" // TODO "require" if cfg.SSL
-		dsn = fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s sslmode=%s",
-			cfg.Host, cfg.Port, cfg.User,
-			cfg.Name, cfg.Password, sslmode)
-		return gorm.Open(postgres.Open(dsn), gormConfig)
+		dialector = postgres.Open(postgresDSN(cfg))
 	case SQLITE:
-		panic("sqlite not active")
-		// return gorm.Open(sqlite.Open(config.Host), gormConfig)
+		dialector = sqlite.Open(sqliteDSN(cfg))
+	default:
+		panic("undefined db dialect")
 	}
 
-	panic("undefined db dialect")
+	db, err := gorm.Open(dialector, gormConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return db, logger.register(db)
 }
 
 func (rep *repository) Driver() *gorm.DB {
 	return rep.db
 }
 
+// Dialect POSTGRES or SQLITE
+func (rep *repository) Dialect() string {
+	return rep.db.Dialector.Name()
+}
+
 // Model specify the model you would like to run db operations
 func (rep *repository) Model(value interface{}) *gorm.DB {
 	return rep.db.Model(value)
@@ -194,12 +312,56 @@ func (rep *repository) ScanRows(rows *sql.Rows, result interface{}) error {
 	return rep.db.ScanRows(rows, result)
 }
 
+// Ping database of primary
+func (rep *repository) Ping(ctx context.Context) error {
+
+	dbSQL, err := rep.db.DB()
+	if err != nil {
+		return err
+	}
+
+	return dbSQL.PingContext(ctx)
+}
+
 // Close close current db connection. If database connection is not an io.Closer, returns an error.
 func (rep *repository) Close() error {
-	sqlDB, _ := rep.db.DB()
+	sqlDB, err := rep.db.DB()
+	if err != nil {
+		return err // in transaction
+	}
 	return sqlDB.Close()
 }
 
+// Ping database of primary, ErrClosed after Close
+func (rep *mainRepository) Ping(ctx context.Context) error {
+
+	if rep.closed.Load() {
+		return ErrClosed
+	}
+
+	return rep.repository.Ping(ctx)
+}
+
+// Close close connections of db and tenant schemas
+func (rep *mainRepository) Close() error {
+
+	rep.closed.Store(true)
+
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+
+	for _, v := range rep.schemas {
+		_ = v.Close()
+	}
+	rep.schemas = map[string]*mainRepository{}
+
+	if rep.replicas != nil {
+		rep.replicas.close()
+	}
+
+	return rep.repository.Close()
+}
+
 // DropTableIfExists drop table if it is exist
 func (rep *repository) DropTableIfExists(value interface{}) error {
 	return rep.db.Migrator().DropTable(value)
@@ -210,27 +372,17 @@ func (rep *repository) AutoMigrate(value interface{}) error {
 	return rep.db.AutoMigrate(value)
 }
 
-// Transaction start a transaction as a block.
-// If it is failed, will rollback and return error.
-// If it is sccuessed, will commit.
-// ref: https://github.com/jinzhu/gorm/blob/master/main.go#L533
-func (rep *repository) Transaction(fc func(tx AppRepository) error) (err error) {
-	panicked := true
-	tx := rep.db.Begin()
-	defer func() {
-		if panicked || err != nil {
-			tx.Rollback()
-		}
-	}()
-
-	txrep := &repository{}
-	txrep.db = tx
-	err = fc(txrep)
+// WithContext repository of queries and transactions cancelled with ctx, same connection pool
+func (rep *repository) WithContext(ctx context.Context) AppRepository {
+	return rep.with(rep.db.WithContext(ctx))
+}
 
-	if err == nil {
-		err = tx.Commit().Error
-	}
+// Primary repository of reads on primary, writes and transactions are always on primary
+func (rep *repository) Primary() AppRepository {
+	return rep.with(rep.db.Set(primaryKey, true).Session(&gorm.Session{}))
+}
 
-	panicked = false
-	return
+// with repository of db, same schema and transaction state
+func (rep *repository) with(db *gorm.DB) *repository {
+	return &repository{db: db, schema: rep.schema, txDepth: rep.txDepth, txRetries: rep.txRetries}
 }
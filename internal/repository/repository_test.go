@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	config "go-gis/internal/config"
+)
+
+// Test queries and transactions of cancelled context
+func TestWithContext(t *testing.T) {
+
+	cfg := config.NewAppConfig()
+	cfg.DB.Dialect = SQLITE
+	cfg.DB.File = filepath.Join(t.TempDir(), "test.db")
+
+	rep := MustNewRepository(cfg)
+	defer func() { _ = rep.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	scoped := rep.WithContext(ctx)
+
+	n := 0
+	if err := scoped.Raw("SELECT 1").Scan(&n).Error; err != nil || n != 1 {
+		t.Fatalf("Unexpected %v %v", n, err)
+	}
+
+	cancel()
+
+	if err := scoped.Raw("SELECT 1").Scan(&n).Error; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected canceled, got %v", err)
+	}
+
+	err := scoped.Transaction(func(tx AppRepository) error {
+		return tx.Exec("SELECT 1").Error
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected canceled transaction, got %v", err)
+	}
+
+	if err := rep.Raw("SELECT 1").Scan(&n).Error; err != nil {
+		t.Errorf("Unexpected %v", err)
+	}
+}
+
+// Test retries of unreachable database until wait and stop after close
+func TestWaitConnected(t *testing.T) {
+
+	cfg := config.NewAppConfig()
+	cfg.DB.Host, cfg.DB.Port = "127.0.0.1", "1" // nothing listens
+
+	rep, err := OpenRepository(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if err := WaitConnected(context.Background(), rep, 1200*time.Millisecond); err == nil || time.Since(start) < time.Second {
+		t.Errorf("Expected error after wait, got %v in %v", err, time.Since(start))
+	}
+
+	_ = rep.Close()
+
+	if err := WaitConnected(context.Background(), rep, 0); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected closed, got %v", err)
+	}
+}
+
+// Test concurrent calls of schema share one pool, closed repository opens none
+func TestForSchema(t *testing.T) {
+
+	cfg := config.NewAppConfig()
+	cfg.DB.Dialect = SQLITE
+	cfg.DB.File = filepath.Join(t.TempDir(), "test.db")
+
+	rep := MustNewRepository(cfg)
+
+	results := make(chan AppRepository, 8)
+	for range cap(results) {
+		go func() {
+			v, err := rep.ForSchema("tenant_a")
+			if err != nil {
+				t.Error(err)
+			}
+			results <- v
+		}()
+	}
+
+	first := <-results
+	for range cap(results) - 1 {
+		if v := <-results; v != first {
+			t.Errorf("Expected one pool of schema")
+		}
+	}
+
+	if _, err := rep.ForSchema("bad name;"); err == nil {
+		t.Errorf("Expected invalid schema name")
+	}
+
+	_ = rep.Close()
+
+	if _, err := rep.ForSchema("tenant_b"); err == nil {
+		t.Errorf("Expected error after close")
+	}
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"time"
+
+	config "go-gis/internal/config"
+
+	"gorm.io/gorm/schema"
+)
+
+// tablePrefix "schema." of gorm models, postgres only
+func tablePrefix(cfg *config.Database) schema.Namer {
+
+	if cfg.Schema == "" || cfg.Dialect != POSTGRES {
+		return schema.NamingStrategy{}
+	}
+
+	return schema.NamingStrategy{TablePrefix: cfg.Schema + "."}
+}
+
+// Schema postgres schema of tables, empty if default
+func (rep *repository) Schema() string {
+	return rep.schema
+}
+
+// EnsureSchema create schema if not exists, nothing if default or sqlite
+func (rep *repository) EnsureSchema() error {
+
+	if rep.schema == "" || rep.Dialect() == SQLITE {
+		return nil
+	}
+
+	name, err := QuoteIdent(rep.schema)
+	if err != nil {
+		return err
+	}
+
+	if err := rep.db.Exec("CREATE SCHEMA IF NOT EXISTS " + name).Error; err != nil {
+		return fmt.Errorf("error on schema %v: %v", rep.schema, err)
+	}
+
+	return nil
+}
+
+// ForSchema not supported in transaction
+func (rep *repository) ForSchema(name string) (AppRepository, error) {
+	return nil, fmt.Errorf("error schema %v of transaction", name)
+}
+
+// tenantPoolBudget connections of default and tenant pools if DB.MaxOpen is unlimited,
+// below postgres default max_connections 100
+const tenantPoolBudget = 50
+
+// poolShare DB of one connection pool, with tenants DB.MaxOpen is split across default and tenant pools
+func poolShare(cfg *config.AppConfig) config.Database {
+
+	res := cfg.DB
+
+	if !cfg.Tenants.Enabled {
+		return res
+	}
+
+	pools := 1 + len(cfg.Tenants.Schemas)
+	budget := cmp.Or(res.MaxOpen, tenantPoolBudget)
+
+	res.MaxOpen = max(1, budget/pools)
+	if res.MaxIdle == 0 || res.MaxIdle > res.MaxOpen {
+		res.MaxIdle = res.MaxOpen
+	}
+
+	return res
+}
+
+// schemaPingTimeout first ping of schema pool
+const schemaPingTimeout = 5 * time.Second
+
+// ForSchema repository of schema on own connection pool with search_path of schema, opened once,
+// pool has share of DB.MaxOpen, reads are on primary.
+// Pools are opened outside of lock, a slow schema does not block lookups of others.
+func (rep *mainRepository) ForSchema(name string) (AppRepository, error) {
+
+	if name == rep.schema {
+		return rep, nil
+	}
+
+	if _, err := QuoteIdent(name); err != nil {
+		return nil, err
+	}
+
+	rep.mu.RLock()
+	v, ok := rep.schemas[name]
+	rep.mu.RUnlock()
+
+	if ok {
+		return v, nil
+	}
+
+	// concurrent calls of same schema share one open
+	res, err, _ := rep.opening.Do(name, func() (any, error) {
+
+		rep.mu.RLock()
+		v, ok := rep.schemas[name]
+		rep.mu.RUnlock()
+
+		if ok {
+			return v, nil
+		}
+
+		cfg := rep.cfg
+		cfg.Schema = name
+		cfg.Replicas = nil // no replica pools and health checks per tenant
+
+		res, err := newRepository(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("error on schema %v: %v", name, err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), schemaPingTimeout)
+		defer cancel()
+
+		if err := res.Ping(ctx); err != nil {
+			_ = res.Close()
+			return nil, fmt.Errorf("error on schema %v: %v", name, err)
+		}
+
+		rep.mu.Lock()
+		defer rep.mu.Unlock()
+
+		if rep.closed.Load() {
+			_ = res.Close()
+			return nil, ErrClosed
+		}
+
+		rep.schemas[name] = res
+
+		return res, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*mainRepository), nil
+}
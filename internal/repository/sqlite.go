@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"fmt"
+	config "go-gis/internal/config"
+)
+
+// sqlitePragmas of every connection of pure Go driver
+const sqlitePragmas = "_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)"
+
+// sqliteDSN file or shared in-memory database, in-memory lives while any connection is open.
+// SQLite has no spatial functions: geometry columns hold EWKB hex text, PostGIS queries fail
+func sqliteDSN(cfg *config.Database) string {
+
+	if cfg.File == "" {
+		return "file::memory:?cache=shared&" + sqlitePragmas
+	}
+
+	return fmt.Sprintf("file:%s?%s", cfg.File, sqlitePragmas)
+}
@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	xlog "go-gis/internal/util/utillog"
+)
+
+var (
+	txRetryMetric = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gis_db_transaction_retries_total",
+		Help: "Retries of transactions on serialization failure, deadlock or busy database.",
+	}, []string{"reason"})
+
+	txRollbackMetric = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gis_db_transaction_rollbacks_total",
+		Help: "Rollbacks of transactions and savepoints.",
+	}, []string{"level"})
+)
+
+// TxOptions of outermost transaction, nested transactions are savepoints of it and ignore options
+type TxOptions struct {
+	Isolation sql.IsolationLevel // sql.LevelDefault is database default, read committed on postgres
+	ReadOnly  bool
+	Retries   int // on serialization failure or deadlock, 0 = DB.TxRetries, negative = none
+}
+
+// retry reasons
+const (
+	retrySerialization = "serialization"
+	retryDeadlock      = "deadlock"
+	retryBusy          = "busy"
+)
+
+// sqlite primary result codes, extended codes keep them in low byte
+const (
+	sqliteBusy   = 5
+	sqliteLocked = 6
+)
+
+// sqliteError error of sqlite driver with result code
+type sqliteError interface {
+	error
+	Code() int
+}
+
+// txBackoff first delay between retries of transaction, doubled per retry
+const txBackoff = 20 * time.Millisecond
+
+// Transaction start a transaction as a block, in context of repository.
+// If it is failed, will rollback and return error.
+// If it is sccuessed, will commit.
+// Nested calls are savepoints, see TransactionWith.
+func (rep *repository) Transaction(fc func(tx AppRepository) error) (err error) {
+	return rep.TransactionWith(TxOptions{}, fc)
+}
+
+// TransactionWith transaction of options, retried as a whole on serialization failure or deadlock,
+// fc may run several times and errors of it should be wrapped with %w to be retried.
+// Nested calls are savepoints, rolled back to on error without aborting outer transaction.
+func (rep *repository) TransactionWith(opts TxOptions, fc func(tx AppRepository) error) (err error) {
+
+	if rep.txDepth > 0 {
+		return rep.savepoint(fc)
+	}
+
+	retries := opts.Retries
+	if retries == 0 {
+		retries = rep.txRetries
+	}
+
+	backoff := txBackoff
+
+	for attempt := 0; ; attempt++ {
+
+		err = rep.transaction(opts, fc)
+
+		reason := retryReason(err)
+		if reason == "" || attempt >= retries {
+			return err
+		}
+
+		txRetryMetric.WithLabelValues(reason).Inc()
+		xlog.Warn("db transaction retry: [attempt: %v] [reason: %v] %v", attempt+1, reason, err)
+
+		ctx := rep.db.Statement.Context
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+}
+
+// transaction one attempt of outermost transaction
+func (rep *repository) transaction(opts TxOptions, fc func(tx AppRepository) error) (err error) {
+
+	tx := rep.db.Begin(&sql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly})
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	panicked := true
+	defer func() {
+		if panicked || err != nil {
+			tx.Rollback()
+			txRollbackMetric.WithLabelValues("transaction").Inc()
+		}
+	}()
+
+	txrep := rep.with(tx)
+	txrep.txDepth = 1
+	err = fc(txrep)
+
+	if err == nil {
+		err = tx.Commit().Error
+	}
+
+	panicked = false
+	return
+}
+
+// savepoint nested transaction, released on success
+func (rep *repository) savepoint(fc func(tx AppRepository) error) (err error) {
+
+	name := fmt.Sprintf("sp%d", rep.txDepth)
+
+	if err := rep.db.SavePoint(name).Error; err != nil {
+		return err
+	}
+
+	panicked := true
+	defer func() {
+		if panicked || err != nil {
+			rep.db.RollbackTo(name)
+			txRollbackMetric.WithLabelValues("savepoint").Inc()
+		}
+	}()
+
+	txrep := rep.with(rep.db)
+	txrep.txDepth = rep.txDepth + 1
+	err = fc(txrep)
+
+	if err == nil {
+		err = rep.db.Exec("RELEASE SAVEPOINT " + name).Error
+	}
+
+	panicked = false
+	return
+}
+
+// retryReason reason if transaction may succeed on retry, empty if not
+func retryReason(err error) string {
+
+	if err == nil {
+		return ""
+	}
+
+	pgErr := &pgconn.PgError{}
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "40001":
+			return retrySerialization
+		case "40P01":
+			return retryDeadlock
+		}
+	}
+
+	var liteErr sqliteError
+	if errors.As(err, &liteErr) {
+		switch liteErr.Code() & 0xff {
+		case sqliteBusy, sqliteLocked:
+			return retryBusy
+		}
+	}
+
+	return ""
+}
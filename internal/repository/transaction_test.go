@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	config "go-gis/internal/config"
+)
+
+// busyError sqlite error of result code
+type busyError int
+
+func (x busyError) Error() string { return "database is locked" }
+func (x busyError) Code() int     { return int(x) }
+
+func newTestRepository(t *testing.T) AppRepository {
+
+	cfg := config.NewAppConfig()
+	cfg.DB.Dialect = SQLITE
+	cfg.DB.File = filepath.Join(t.TempDir(), "test.db")
+
+	rep := MustNewRepository(cfg)
+	t.Cleanup(func() { _ = rep.Close() })
+
+	if err := rep.Exec("CREATE TABLE items (name text)").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	return rep
+}
+
+func itemNames(t *testing.T, rep AppRepository) []string {
+
+	res := []string{}
+	if err := rep.Raw("SELECT name FROM items ORDER BY name").Scan(&res).Error; err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+// Test rollback of failed nested transaction keeps outer transaction
+func TestTransactionNested(t *testing.T) {
+
+	rep := newTestRepository(t)
+
+	errInner := errors.New("inner")
+	rollbacks := testutil.ToFloat64(txRollbackMetric.WithLabelValues("savepoint"))
+
+	err := rep.Transaction(func(tx AppRepository) error {
+
+		if err := tx.Exec("INSERT INTO items VALUES ('a')").Error; err != nil {
+			return err
+		}
+
+		err := tx.Transaction(func(tx AppRepository) error {
+			if err := tx.Exec("INSERT INTO items VALUES ('b')").Error; err != nil {
+				return err
+			}
+			return errInner
+		})
+		if !errors.Is(err, errInner) {
+			return fmt.Errorf("expected inner error, got %v", err)
+		}
+
+		return tx.Transaction(func(tx AppRepository) error {
+			return tx.Exec("INSERT INTO items VALUES ('c')").Error
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if names := fmt.Sprint(itemNames(t, rep)); names != "[a c]" {
+		t.Errorf("Unexpected %v", names)
+	}
+
+	if v := testutil.ToFloat64(txRollbackMetric.WithLabelValues("savepoint")); v != rollbacks+1 {
+		t.Errorf("Expected one savepoint rollback, got %v", v-rollbacks)
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r != "nested" {
+				t.Errorf("Expected panic, got %v", r)
+			}
+		}()
+		_ = rep.Transaction(func(tx AppRepository) error {
+			_ = tx.Exec("INSERT INTO items VALUES ('d')")
+			return tx.Transaction(func(tx AppRepository) error { panic("nested") })
+		})
+	}()
+
+	if names := fmt.Sprint(itemNames(t, rep)); names != "[a c]" {
+		t.Errorf("Expected rolled back panic, got %v", names)
+	}
+}
+
+// Test retry of transaction on busy database, other errors are not retried
+func TestTransactionRetry(t *testing.T) {
+
+	rep := newTestRepository(t)
+
+	retries := testutil.ToFloat64(txRetryMetric.WithLabelValues(retryBusy))
+
+	attempts := 0
+	err := rep.TransactionWith(TxOptions{Retries: 2}, func(tx AppRepository) error {
+		attempts++
+		if err := tx.Exec("INSERT INTO items VALUES (?)", fmt.Sprint(attempts)).Error; err != nil {
+			return err
+		}
+		if attempts < 3 {
+			return fmt.Errorf("insert: %w", busyError(sqliteBusy))
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Fatalf("Unexpected %v attempts %v", err, attempts)
+	}
+
+	if names := fmt.Sprint(itemNames(t, rep)); names != "[3]" {
+		t.Errorf("Expected rolled back attempts, got %v", names)
+	}
+
+	if v := testutil.ToFloat64(txRetryMetric.WithLabelValues(retryBusy)); v != retries+2 {
+		t.Errorf("Expected 2 retries, got %v", v-retries)
+	}
+
+	attempts = 0
+	err = rep.TransactionWith(TxOptions{Retries: -1}, func(tx AppRepository) error {
+		attempts++
+		return busyError(sqliteBusy)
+	})
+	if err == nil || attempts != 1 {
+		t.Errorf("Expected no retries, got %v attempts %v", err, attempts)
+	}
+
+	attempts = 0
+	_ = rep.Transaction(func(tx AppRepository) error {
+		attempts++
+		return errors.New("not retried")
+	})
+	if attempts != 1 {
+		t.Errorf("Expected one attempt, got %v", attempts)
+	}
+}
+
+// Test busy error of sqlite driver is retryable
+func TestRetryReasonSQLite(t *testing.T) {
+
+	file := filepath.Join(t.TempDir(), "busy.db")
+
+	open := func() *sql.DB {
+		db, err := sql.Open("sqlite", "file:"+file+"?_pragma=busy_timeout(0)")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = db.Close() })
+		return db
+	}
+
+	writer, other := open(), open()
+
+	if _, err := writer.Exec("CREATE TABLE items (name text)"); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := writer.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec("INSERT INTO items VALUES ('a')"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = other.Exec("INSERT INTO items VALUES ('b')")
+	if reason := retryReason(fmt.Errorf("insert: %w", err)); reason != retryBusy {
+		t.Errorf("Expected busy of %v, got %q", err, reason)
+	}
+
+	if reason := retryReason(busyError(1)); reason != "" {
+		t.Errorf("Expected not retryable, got %q", reason)
+	}
+}
@@ -23,6 +23,26 @@ func Init(e *echo.Echo, appService service.AppService) {
 
 	initGeocodeController(e, appService)
 
+	initPostcodeController(e, appService)
+
+	initCountryController(e, appService)
+
+	initFeatureController(e, appService)
+
+	initClusterController(e, appService)
+
+	initHexController(e, appService)
+
+	initTileController(e, appService)
+
+	initStaticMapController(e, appService)
+
+	initTrackController(e, appService)
+
+	initTransformController(e, appService)
+
+	initGeometryController(e, appService)
+
 	initSys(e, appService)
 }
 
@@ -36,7 +56,8 @@ func initSys(e *echo.Echo, appService service.AppService) {
 	listen := appConfig.HTTPServer.Listen
 	listenSys := appConfig.HTTPServer.ListenSys
 	sysMetrics := appConfig.HTTPServer.SysMetrics
-	hasAnyService := sysMetrics
+	sysImport := appConfig.HTTPServer.SysImport
+	hasAnyService := sysMetrics || sysImport
 	sysAPIKey := appConfig.HTTPServer.SysAPIKey
 	hasAPIKey := sysAPIKey != ""
 	hasListenSys := listenSys != ""
@@ -82,6 +103,22 @@ func initSys(e *echo.Echo, appService service.AppService) {
 
 	}
 
+	if sysImport {
+		e.POST(consts.PathSysImportShapefileAPI, func(c echo.Context) error {
+
+			return controller.NewImportController(requestService(appService, c), c).Shapefile()
+
+		}, sysAPIAccessAuthMW, requireDB(appService))
+
+		if appConfig.Tracks.Layer != "" {
+			e.POST(consts.PathSysImportTracksAPI, func(c echo.Context) error {
+
+				return controller.NewTrackController(requestService(appService, c), c).Import()
+
+			}, sysAPIAccessAuthMW, requireDB(appService))
+		}
+	}
+
 	if startNewListener {
 
 		// start as async task
@@ -103,21 +140,26 @@ func initSys(e *echo.Echo, appService service.AppService) {
 
 }
 
-func initDebugController(e *echo.Echo, _ service.AppService) {
+func initDebugController(e *echo.Echo, appService service.AppService) {
 	e.GET(consts.PathGisPingDebugAPI, func(c echo.Context) error { return c.String(http.StatusOK, "pong") })
 	//
 	// status
 	e.GET("/-/health", func(c echo.Context) error { return c.JSON(http.StatusOK, struct{}{}) })
 	//
 	e.GET("/-/probe/startup", func(c echo.Context) error { return c.String(http.StatusOK, "ok") })
-	e.GET("/-/probe/ready", func(c echo.Context) error { return c.String(http.StatusOK, "ok") })
+	e.GET("/-/probe/ready", func(c echo.Context) error {
+		if !appService.Ready() {
+			return c.String(http.StatusServiceUnavailable, "db is not ready")
+		}
+		return c.String(http.StatusOK, "ok")
+	})
 	e.GET("/-/probe/live", func(c echo.Context) error { return c.String(http.StatusOK, "ok") })
 
 }
 func initGeocodeController(e *echo.Echo, appService service.AppService) {
 
 	factory := func(c echo.Context) *controller.GeocodeController {
-		return controller.NewGeocodeController(appService, c)
+		return controller.NewGeocodeController(requestService(appService, c), c)
 	}
 
 	e.GET(consts.PathGisGeocodeAPI, func(c echo.Context) error {
@@ -126,8 +168,254 @@ func initGeocodeController(e *echo.Echo, appService service.AppService) {
 
 	})
 
+	e.GET(consts.PathGisGeocodeForwardAPI, func(c echo.Context) error {
+
+		return factory(c).Forward()
+
+	})
+
+	e.GET(consts.PathGisAddressAPI, func(c echo.Context) error {
+
+		return controller.NewAddressController(requestService(appService, c), c).Parse()
+
+	})
+
 	//
 
 }
 
+func initPostcodeController(e *echo.Echo, appService service.AppService) {
+
+	if appService.Postcode() == nil {
+		return
+	}
+
+	factory := func(c echo.Context) *controller.PostcodeController {
+		return controller.NewPostcodeController(requestService(appService, c), c)
+	}
+
+	e.GET(consts.PathGisPostcodesAPI, func(c echo.Context) error {
+
+		return factory(c).Lookup()
+
+	}, requireDB(appService))
+
+	e.GET(consts.PathGisPostcodesNearestAPI, func(c echo.Context) error {
+
+		return factory(c).Nearest()
+
+	}, requireDB(appService))
+
+}
+
+func initCountryController(e *echo.Echo, appService service.AppService) {
+
+	factory := func(c echo.Context) *controller.CountryController {
+		return controller.NewCountryController(requestService(appService, c), c)
+	}
+
+	e.GET(consts.PathGisCountryAPI, func(c echo.Context) error {
+
+		return factory(c).Country()
+
+	})
+
+}
+
+func initFeatureController(e *echo.Echo, appService service.AppService) {
+
+	factory := func(c echo.Context) *controller.FeatureController {
+		return controller.NewFeatureController(requestService(appService, c), c)
+	}
+
+	e.GET(consts.PathGisFeaturesAPI, func(c echo.Context) error {
+
+		return factory(c).Features()
+
+	}, requireDB(appService))
+
+}
+
+func initClusterController(e *echo.Echo, appService service.AppService) {
+
+	if appService.Cluster() == nil {
+		return
+	}
+
+	factory := func(c echo.Context) *controller.ClusterController {
+		return controller.NewClusterController(requestService(appService, c), c)
+	}
+
+	e.GET(consts.PathGisClustersAPI, func(c echo.Context) error {
+
+		return factory(c).Clusters()
+
+	}, requireDB(appService))
+
+}
+
+func initHexController(e *echo.Echo, appService service.AppService) {
+
+	factory := func(c echo.Context) *controller.HexController {
+		return controller.NewHexController(requestService(appService, c), c)
+	}
+
+	e.GET(consts.PathGisHexCellAPI, func(c echo.Context) error {
+
+		return factory(c).Cell()
+
+	})
+
+	e.GET(consts.PathGisHexCellByIDAPI, func(c echo.Context) error {
+
+		return factory(c).CellByID()
+
+	})
+
+	e.GET(consts.PathGisHexRingAPI, func(c echo.Context) error {
+
+		return factory(c).Ring()
+
+	})
+
+	e.GET(consts.PathGisHexAggregateAPI, func(c echo.Context) error {
+
+		return factory(c).Aggregate()
+
+	}, requireDB(appService))
+
+}
+
+func initTileController(e *echo.Echo, appService service.AppService) {
+
+	factory := func(c echo.Context) *controller.TileController {
+		return controller.NewTileController(requestService(appService, c), c)
+	}
+
+	e.GET(consts.PathGisVectorTiles, func(c echo.Context) error {
+
+		return factory(c).VectorTile()
+
+	}, requireDB(appService))
+
+	if appService.TileProxy() != nil {
+		e.GET(consts.PathGisRasterTiles, func(c echo.Context) error {
+
+			return factory(c).RasterTile()
+
+		})
+	}
+
+}
+
+func initStaticMapController(e *echo.Echo, appService service.AppService) {
+
+	if appService.StaticMap() == nil {
+		return
+	}
+
+	factory := func(c echo.Context) *controller.StaticMapController {
+		return controller.NewStaticMapController(requestService(appService, c), c)
+	}
+
+	e.GET(consts.PathGisStaticMapAPI, func(c echo.Context) error {
+
+		return factory(c).StaticMap()
+
+	})
+
+}
+
+func initTrackController(e *echo.Echo, appService service.AppService) {
+
+	factory := func(c echo.Context) *controller.TrackController {
+		return controller.NewTrackController(requestService(appService, c), c)
+	}
+
+	e.POST(consts.PathGisTracksConvertAPI, func(c echo.Context) error {
+
+		return factory(c).Convert()
+
+	})
+
+	e.POST(consts.PathGisTracksProcessAPI, func(c echo.Context) error {
+
+		return factory(c).Process()
+
+	})
+
+	if appService.Config().Tracks.Layer == "" {
+		return
+	}
+
+	e.GET(consts.PathGisTracksAPI, func(c echo.Context) error {
+
+		return factory(c).Export()
+
+	}, requireDB(appService))
+
+}
+
+func initTransformController(e *echo.Echo, appService service.AppService) {
+
+	factory := func(c echo.Context) *controller.TransformController {
+		return controller.NewTransformController(requestService(appService, c), c)
+	}
+
+	e.GET(consts.PathGisTransformAPI, func(c echo.Context) error {
+
+		return factory(c).Transform()
+
+	})
+
+	e.POST(consts.PathGisTransformAPI, func(c echo.Context) error {
+
+		return factory(c).Transform()
+
+	})
+
+}
+
+func initGeometryController(e *echo.Echo, appService service.AppService) {
+
+	factory := func(c echo.Context) *controller.GeometryController {
+		return controller.NewGeometryController(requestService(appService, c), c)
+	}
+
+	// go engine works without database
+	mw := []echo.MiddlewareFunc{}
+	if appService.Config().Geometry.Engine == "postgis" {
+		mw = append(mw, requireDB(appService))
+	}
+
+	e.POST(consts.PathGisGeometryAPI, func(c echo.Context) error {
+
+		return factory(c).Operation()
+
+	}, mw...)
+
+}
+
 /////////////////////////////////////////////////////
+
+// requestService services of request context, tenant services selected by tenant middleware
+func requestService(appService service.AppService, c echo.Context) service.AppService {
+
+	if v, ok := c.Get(consts.ContextKeyAppService).(service.AppService); ok {
+		appService = v
+	}
+
+	return appService.WithContext(c.Request().Context())
+}
+
+// requireDB 503 until database is connected in degraded start
+func requireDB(appService service.AppService) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !appService.Ready() {
+				return echo.NewHTTPError(http.StatusServiceUnavailable, "db is not ready")
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go-gis/internal/config"
+	"go-gis/internal/geo/address"
+	"go-gis/internal/util/utilconfig"
+	xlog "go-gis/internal/util/utillog"
+	"os"
+)
+
+// AddressService free text address parsing with per-language rules
+type AddressService interface {
+	// Parse components of text, rules of lang or default lang
+	Parse(text string, lang string) address.Address
+}
+
+type defaultAddressSrv struct {
+	appConfig   *config.AppConfig
+	defaultLang string
+	parsers     map[string]*address.Parser
+}
+
+func (x *defaultAddressSrv) Parse(text string, lang string) address.Address {
+
+	p, ok := x.parsers[lang]
+	if !ok {
+		p = x.parsers[x.defaultLang]
+	}
+
+	return p.Parse(text)
+}
+
+// loadAddressRules address.{lang}.json next to lang.{lang}.json, nil if no file in any dir
+func loadAddressRules(configPath []string, lang string) (*address.Rules, error) {
+
+	var res *address.Rules
+
+	for _, dir := range configPath {
+
+		fileName := fmt.Sprintf("address.%s.json", lang)
+
+		rules := address.Rules{}
+
+		err := utilconfig.LoadConfig(&rules, dir, fileName)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		res = &rules // override
+	}
+
+	return res, nil
+}
+
+// NewAddress parsers of config langs, lang without rules uses default lang rules
+func NewAddress(appConfig *config.AppConfig) (AddressService, error) {
+
+	res := &defaultAddressSrv{
+		appConfig: appConfig,
+		parsers:   map[string]*address.Parser{},
+	}
+
+	if len(appConfig.Lang.Langs) > 0 {
+		res.defaultLang = appConfig.Lang.Langs[0]
+	}
+
+	for _, lang := range appConfig.Lang.Langs {
+
+		rules, err := loadAddressRules(appConfig.ConfigPath, lang)
+		if err != nil {
+			return nil, fmt.Errorf("error address rules %v: %v", lang, err)
+		}
+
+		if rules == nil {
+			if lang == res.defaultLang {
+				xlog.Warn("address rules not found for default lang: %v", lang)
+				rules = &address.Rules{}
+			} else {
+				continue
+			}
+		}
+
+		p, err := address.NewParser(*rules)
+		if err != nil {
+			return nil, fmt.Errorf("error address rules %v: %v", lang, err)
+		}
+
+		res.parsers[lang] = p
+	}
+
+	return res, nil
+}
+
+// MustNewAddress panic on invalid rule files
+func MustNewAddress(appConfig *config.AppConfig) AddressService {
+
+	res, err := NewAddress(appConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	return res
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// maxBindParams bind parameters of one postgres statement
+const maxBindParams = 65535
+
+// batchInsert rows buffered and inserted by multi-row statements
+type batchInsert[T any] struct {
+	size   int // rows per statement
+	insert func(rows []T) error
+	reject func(row T, err error)
+
+	rows []T
+}
+
+// newBatchInsert batch of size rows, at most maxBindParams / params rows per statement
+func newBatchInsert[T any](size int, params int, insert func(rows []T) error, reject func(row T, err error)) *batchInsert[T] {
+
+	size = min(max(size, 1), max(maxBindParams/max(params, 1), 1))
+
+	return &batchInsert[T]{
+		size:   size,
+		insert: insert,
+		reject: reject,
+		rows:   make([]T, 0, size),
+	}
+}
+
+// add row, flushed when batch is full
+func (x *batchInsert[T]) add(row T) (int, error) {
+
+	x.rows = append(x.rows, row)
+
+	if len(x.rows) < x.size {
+		return 0, nil
+	}
+
+	return x.flush()
+}
+
+// flush insert buffered rows, count of inserted.
+// On data error rows are inserted one by one and failing rows rejected,
+// other errors (connection, timeout) stop the import and are returned.
+func (x *batchInsert[T]) flush() (int, error) {
+
+	if len(x.rows) == 0 {
+		return 0, nil
+	}
+
+	defer func() { x.rows = x.rows[:0] }()
+
+	err := x.insert(x.rows)
+	if err == nil {
+		return len(x.rows), nil
+	}
+	if !isDataError(err) {
+		return 0, err
+	}
+
+	// find broken rows
+	count := 0
+	for _, row := range x.rows {
+		err := x.insert([]T{row})
+		switch {
+		case err == nil:
+			count++
+		case isDataError(err):
+			x.reject(row, err)
+		default:
+			return count, err
+		}
+	}
+
+	return count, nil
+}
+
+// isDataError data exception or integrity constraint violation of row values
+func isDataError(err error) bool {
+
+	pgErr := &pgconn.PgError{}
+
+	return errors.As(err, &pgErr) && (strings.HasPrefix(pgErr.Code, "22") || strings.HasPrefix(pgErr.Code, "23"))
+}
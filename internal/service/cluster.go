@@ -0,0 +1,269 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-gis/internal/config"
+	"go-gis/internal/geo/cluster"
+	"go-gis/internal/geo/geojson"
+	"go-gis/internal/repository"
+	xlog "go-gis/internal/util/utillog"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ClusterService point clusters of layers by zoom, layers are kept in memory
+type ClusterService interface {
+	// Query clusters and single points in bbox, layer is loaded on first call
+	// and reloaded in background when older than refresh
+	Query(layer string, bbox geojson.BBox, zoom int) ([]cluster.Cluster, error)
+	// Refresh load rows changed since last refresh into index if layer has updated_at column,
+	// otherwise or if rows were deleted reload layer, changed points only are updated
+	Refresh(layer string) error
+	// HasLayer layer is clustered
+	HasLayer(layer string) bool
+}
+
+type clusterLayer struct {
+	index *cluster.Index
+
+	mu     sync.Mutex
+	loaded time.Time // zero if not loaded
+
+	since time.Time // max updated_at of loaded rows, zero = full load, used in refresh only
+}
+
+// clusterOverlap changed rows are reloaded since max updated_at less overlap,
+// rows of transactions committed later than started are not missed
+const clusterOverlap = time.Minute
+
+type defaultClusterSrv struct {
+	appConfig  *config.AppConfig
+	repository repository.AppRepository
+
+	layers map[string]*clusterLayer
+	group  singleflight.Group
+}
+
+type clusterRow struct {
+	ID         string
+	Lng        float64
+	Lat        float64
+	Properties string
+	UpdatedAt  *time.Time // nil without updated_at column
+}
+
+func (x *defaultClusterSrv) HasLayer(layer string) bool { return x.layers[layer] != nil }
+
+func (x *defaultClusterSrv) Query(layer string, bbox geojson.BBox, zoom int) ([]cluster.Cluster, error) {
+
+	v := x.layers[layer]
+	if v == nil {
+		return nil, fmt.Errorf("%w: layer is not clustered: %v", ErrInvalidArgument, layer)
+	}
+
+	v.mu.Lock()
+	loaded := v.loaded
+	v.mu.Unlock()
+
+	switch {
+	case loaded.IsZero():
+		if err := x.Refresh(layer); err != nil {
+			return nil, err
+		}
+	case time.Since(loaded) > time.Duration(x.appConfig.Clusters.Refresh)*time.Second:
+		go func() {
+			_ = x.Refresh(layer) // errors are logged, index stays as is
+		}()
+	}
+
+	return v.index.Query(bbox, zoom), nil
+}
+
+// Refresh concurrent calls for same layer share one load
+func (x *defaultClusterSrv) Refresh(layer string) error {
+
+	v := x.layers[layer]
+	if v == nil {
+		return fmt.Errorf("%w: layer is not clustered: %v", ErrInvalidArgument, layer)
+	}
+
+	_, err, _ := x.group.Do(layer, func() (any, error) {
+
+		cfg := x.appConfig.Layer(layer)
+
+		err := x.refreshChanged(cfg, v)
+		if err == errClusterReload {
+			err = x.reload(cfg, v)
+		}
+		if err != nil {
+			xlog.Error("%v", err)
+			return nil, err
+		}
+
+		v.mu.Lock()
+		v.loaded = time.Now()
+		v.mu.Unlock()
+
+		return nil, nil
+	})
+
+	return err
+}
+
+// errClusterReload changed rows can not be applied, layer is reloaded
+var errClusterReload = errors.New("cluster reload")
+
+// reload all points of layer, points not in layer are removed
+func (x *defaultClusterSrv) reload(layer *config.AppConfigLayer, v *clusterLayer) error {
+
+	points, since, err := x.load(layer, time.Time{})
+	if err != nil {
+		return err
+	}
+
+	removed := v.index.Sync(points)
+	v.since = since
+
+	xlog.Info("clusters of layer %v: %v points, %v removed", layer.Name, len(points), removed)
+
+	return nil
+}
+
+// refreshChanged points of rows updated since last load,
+// errClusterReload without updated_at column, on first load or if rows were deleted
+func (x *defaultClusterSrv) refreshChanged(layer *config.AppConfigLayer, v *clusterLayer) error {
+
+	if layer.UpdatedAt == "" || v.since.IsZero() {
+		return errClusterReload
+	}
+
+	points, since, err := x.load(layer, v.since.Add(-clusterOverlap))
+	if err != nil {
+		return err
+	}
+
+	for _, p := range points {
+		v.index.Set(p)
+	}
+	if since.After(v.since) {
+		v.since = since
+	}
+
+	table, err := repository.QuoteIdent(layer.Table)
+	if err != nil {
+		return err
+	}
+	geom, err := repository.QuoteIdent(layer.GeometryColumn())
+	if err != nil {
+		return err
+	}
+
+	// deleted rows and points changed to other geometries are not in changed rows
+	var count int
+	err = x.repository.Raw(fmt.Sprintf(`SELECT count(*) FROM %s AS t WHERE GeometryType(t.%s) = 'POINT'`,
+		table, geom)).Row().Scan(&count)
+	if err != nil {
+		return fmt.Errorf("error on layer %v clusters count: %v", layer.Name, err)
+	}
+	if count != v.index.Len() {
+		return errClusterReload
+	}
+
+	xlog.Debug("clusters of layer %v: %v points changed", layer.Name, len(points))
+
+	return nil
+}
+
+// load points of layer updated after since, all if zero, other geometries are skipped,
+// returns max updated_at of rows, zero without updated_at column
+func (x *defaultClusterSrv) load(layer *config.AppConfigLayer, since time.Time) ([]cluster.Point, time.Time, error) {
+
+	table, err := repository.QuoteIdent(layer.Table)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	geom, err := repository.QuoteIdent(layer.GeometryColumn())
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	id, err := repository.QuoteIdent(layer.IDColumn())
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	geom = "t." + geom
+
+	properties, err := propertiesSQL(layer)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	updatedAt := "NULL::timestamptz"
+	if layer.UpdatedAt != "" {
+		column, err := repository.QuoteIdent(layer.UpdatedAt)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		updatedAt = "t." + column
+	}
+
+	where := fmt.Sprintf("GeometryType(%s) = 'POINT'", geom)
+	args := []any{}
+	if !since.IsZero() {
+		where += fmt.Sprintf(" AND %s > ?", updatedAt)
+		args = append(args, since)
+	}
+
+	sql := fmt.Sprintf(`SELECT t.%s::text AS id, ST_X(%s) AS lng, ST_Y(%s) AS lat, %s AS properties, %s AS updated_at FROM %s AS t WHERE %s`,
+		id, geom, geom, properties, updatedAt, table, where)
+
+	rows := []clusterRow{}
+
+	err = x.repository.Raw(sql, args...).Scan(&rows).Error
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("error on layer %v clusters load: %v", layer.Name, err)
+	}
+
+	var maxUpdated time.Time
+
+	res := make([]cluster.Point, 0, len(rows))
+	for _, row := range rows {
+		p := cluster.Point{ID: row.ID, Lng: row.Lng, Lat: row.Lat}
+		err = json.Unmarshal([]byte(row.Properties), &p.Properties)
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("error on feature %v properties: %v", row.ID, err)
+		}
+		if row.UpdatedAt != nil && row.UpdatedAt.After(maxUpdated) {
+			maxUpdated = *row.UpdatedAt
+		}
+		res = append(res, p)
+	}
+
+	return res, maxUpdated, nil
+}
+
+// NewCluster nil if no layers are clustered
+func NewCluster(appConfig *config.AppConfig, repository repository.AppRepository) ClusterService {
+
+	cfg := appConfig.Clusters
+	if len(cfg.Layers) == 0 {
+		return nil
+	}
+
+	res := &defaultClusterSrv{
+		appConfig:  appConfig,
+		repository: repository,
+		layers:     map[string]*clusterLayer{},
+	}
+
+	for _, name := range cfg.Layers {
+		res.layers[name] = &clusterLayer{
+			index: cluster.NewIndex(cluster.Options{Radius: float64(cfg.Radius), MaxZoom: cfg.MaxZoom}),
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"fmt"
+	"go-gis/internal/config"
+	"go-gis/internal/geo/country"
+	xlog "go-gis/internal/util/utillog"
+	"os"
+	"path/filepath"
+)
+
+// CountryResult ISO 3166 codes of point, empty if not found
+type CountryResult struct {
+	Country         string `json:"country"`
+	CountryName     string `json:"country_name,omitempty"`
+	Subdivision     string `json:"subdivision,omitempty"`
+	SubdivisionName string `json:"subdivision_name,omitempty"`
+}
+
+// CountryService country and subdivision of point without external calls
+type CountryService interface {
+	Lookup(lat, lng float64) CountryResult
+}
+
+type defaultCountrySrv struct {
+	appConfig *config.AppConfig
+	index     *country.Index
+}
+
+func (x *defaultCountrySrv) Lookup(lat, lng float64) CountryResult {
+
+	res := CountryResult{}
+
+	found := x.index.Lookup(lng, lat)
+
+	if v := found.Country; v != nil {
+		res.Country, res.CountryName = v.Code, v.Name
+	}
+
+	if v := found.Subdivision; v != nil {
+		res.Subdivision, res.SubdivisionName = v.Code, v.Name
+	}
+
+	return res
+}
+
+// NewCountry boundaries of config file or embedded in binary
+func NewCountry(appConfig *config.AppConfig) (CountryService, error) {
+
+	var index *country.Index
+	var err error
+
+	if file := appConfig.Country.File; file != "" {
+		f, openErr := os.Open(filepath.Clean(file))
+		if openErr != nil {
+			return nil, fmt.Errorf("error on boundaries file: %v", openErr)
+		}
+		defer func() { _ = f.Close() }()
+		index, err = country.Read(f)
+	} else {
+		index, err = country.Embedded()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	xlog.Info("country boundaries loaded: [regions: %v]", index.Len())
+
+	return &defaultCountrySrv{appConfig: appConfig, index: index}, nil
+}
+
+// MustNewCountry panic on broken boundaries
+func MustNewCountry(appConfig *config.AppConfig) CountryService {
+
+	res, err := NewCountry(appConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	return res
+}
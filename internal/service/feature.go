@@ -0,0 +1,278 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"go-gis/internal/config"
+	"go-gis/internal/geo/geojson"
+	"go-gis/internal/repository"
+	"strings"
+)
+
+// FeatureQuery bbox query over one or more layers
+type FeatureQuery struct {
+	BBox   geojson.BBox
+	Layers []string
+	Filter map[string]string // column:value, column must be in layer columns
+	Limit  int
+	Cursor string // from previous page
+}
+
+// FeaturePage page of features, NextCursor is empty on last page
+type FeaturePage struct {
+	Collection *geojson.FeatureCollection
+	NextCursor string
+}
+
+type FeatureService interface {
+	Query(q FeatureQuery) (*FeaturePage, error)
+
+	// Import insert features into layer, broken features are rejected
+	Import(layer string, r FeatureReader, opts ImportOptions) (ImportResult, error)
+	// Export write features of layer ordered by id, at most limit, 0 = all
+	Export(layer string, w FeatureWriter, limit int) (int, error)
+}
+
+type defaultFeatureSrv struct {
+	appConfig  *config.AppConfig
+	repository repository.AppRepository
+}
+
+// featureCursor position after last returned feature
+type featureCursor struct {
+	Layer string `json:"l"`
+	After string `json:"a,omitempty"` // id of last feature, empty from start of layer
+}
+
+func (x featureCursor) encode() string {
+	data, _ := json.Marshal(x)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeFeatureCursor(text string) (res featureCursor, err error) {
+
+	data, err := base64.RawURLEncoding.DecodeString(text)
+	if err != nil {
+		return res, fmt.Errorf("%w: cursor: %v", ErrInvalidArgument, err)
+	}
+
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		return res, fmt.Errorf("%w: cursor: %v", ErrInvalidArgument, err)
+	}
+
+	return res, nil
+}
+
+type featureRow struct {
+	ID         string
+	Geometry   string
+	Properties string
+}
+
+func (x *defaultFeatureSrv) Query(q FeatureQuery) (*FeaturePage, error) {
+
+	layers := make([]*config.AppConfigLayer, 0, len(q.Layers))
+	for _, name := range q.Layers {
+		layer := x.appConfig.Layer(name)
+		if layer == nil {
+			return nil, fmt.Errorf("%w: unknown layer: %v", ErrInvalidArgument, name)
+		}
+		for k := range q.Filter {
+			if !layer.HasColumn(k) {
+				return nil, fmt.Errorf("%w: layer %v has no column: %v", ErrInvalidArgument, name, k)
+			}
+		}
+		layers = append(layers, layer)
+	}
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = x.appConfig.Features.DefaultLimit
+	}
+	if maxLimit := x.appConfig.Features.MaxLimit; maxLimit > 0 {
+		limit = min(limit, maxLimit)
+	}
+
+	cursor := featureCursor{}
+	start := 0
+
+	if q.Cursor != "" {
+		var err error
+		cursor, err = decodeFeatureCursor(q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		start = -1
+		for i, v := range layers {
+			if v.Name == cursor.Layer {
+				start = i
+			}
+		}
+		if start < 0 {
+			return nil, fmt.Errorf("%w: cursor layer is not in query: %v", ErrInvalidArgument, cursor.Layer)
+		}
+	}
+
+	res := &FeaturePage{Collection: geojson.NewFeatureCollection()}
+
+	for i := start; i < len(layers); i++ {
+
+		layer := layers[i]
+
+		after := ""
+		if i == start {
+			after = cursor.After
+		}
+
+		rows, err := x.queryLayer(layer, q, after, limit+1) // +1 to detect next page
+		if err != nil {
+			return nil, err
+		}
+
+		hasMore := len(rows) > limit
+		if hasMore {
+			rows = rows[:limit]
+		}
+
+		for _, row := range rows {
+			f, err := row.feature()
+			if err != nil {
+				return nil, err
+			}
+			res.Collection.Features = append(res.Collection.Features, f)
+		}
+
+		limit -= len(rows)
+
+		if hasMore {
+			res.NextCursor = featureCursor{Layer: layer.Name, After: rows[len(rows)-1].ID}.encode()
+			break
+		}
+
+		if limit == 0 {
+			if i+1 < len(layers) {
+				res.NextCursor = featureCursor{Layer: layers[i+1].Name}.encode()
+			}
+			break
+		}
+	}
+
+	return res, nil
+}
+
+func (x *defaultFeatureSrv) queryLayer(layer *config.AppConfigLayer, q FeatureQuery, after string, limit int) ([]featureRow, error) {
+
+	table, err := repository.QuoteIdent(layer.Table)
+	if err != nil {
+		return nil, err
+	}
+	geom, err := repository.QuoteIdent(layer.GeometryColumn())
+	if err != nil {
+		return nil, err
+	}
+	id, err := repository.QuoteIdent(layer.IDColumn())
+	if err != nil {
+		return nil, err
+	}
+	geom, id = "t."+geom, "t."+id
+
+	properties, err := propertiesSQL(layer)
+	if err != nil {
+		return nil, err
+	}
+
+	where := []string{}
+	args := []any{}
+
+	bboxWhere, bboxArgs := bboxSQL(geom, q.BBox)
+	where = append(where, bboxWhere)
+	args = append(args, bboxArgs...)
+
+	if after != "" {
+		where = append(where, id+" > ?")
+		args = append(args, after)
+	}
+
+	for k, v := range q.Filter {
+		column, err := repository.QuoteIdent(k)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, fmt.Sprintf("t.%s::text = ?", column))
+		args = append(args, v)
+	}
+
+	args = append(args, limit)
+
+	sql := fmt.Sprintf(`SELECT %s::text AS id, ST_AsGeoJSON(%s) AS geometry, %s AS properties FROM %s AS t WHERE %s ORDER BY %s LIMIT ?`,
+		id, geom, properties, table, strings.Join(where, " AND "), id)
+
+	rows := []featureRow{}
+
+	err = x.repository.Raw(sql, args...).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("error on layer %v query: %v", layer.Name, err)
+	}
+
+	return rows, nil
+}
+
+// bboxSQL condition of geometry intersecting bbox, split at antimeridian
+func bboxSQL(geom string, bbox geojson.BBox) (string, []any) {
+
+	if bbox.CrossesAntimeridian() {
+		return fmt.Sprintf("(ST_Intersects(%[1]s, ST_MakeEnvelope(?, ?, 180, ?, 4326)) OR ST_Intersects(%[1]s, ST_MakeEnvelope(-180, ?, ?, ?, 4326)))", geom),
+			[]any{bbox[0], bbox[1], bbox[3], bbox[1], bbox[2], bbox[3]}
+	}
+
+	return fmt.Sprintf("ST_Intersects(%s, ST_MakeEnvelope(?, ?, ?, ?, 4326))", geom), []any{bbox[0], bbox[1], bbox[2], bbox[3]}
+}
+
+// propertiesSQL json object of layer columns as text
+func propertiesSQL(layer *config.AppConfigLayer) (string, error) {
+
+	if len(layer.Columns) == 0 {
+		return "'{}'", nil
+	}
+
+	parts := make([]string, 0, len(layer.Columns))
+	for _, c := range layer.Columns {
+		column, err := repository.QuoteIdent(c)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, fmt.Sprintf("'%s', t.%s", c, column))
+	}
+
+	return fmt.Sprintf("json_build_object(%s)::text", strings.Join(parts, ", ")), nil
+}
+
+func (x featureRow) feature() (*geojson.Feature, error) {
+
+	geometry := &geojson.Geometry{}
+	err := json.Unmarshal([]byte(x.Geometry), geometry)
+	if err != nil {
+		return nil, fmt.Errorf("error on feature %v geometry: %v", x.ID, err)
+	}
+
+	res := geojson.NewFeature(geometry)
+	res.ID = x.ID
+
+	err = json.Unmarshal([]byte(x.Properties), &res.Properties)
+	if err != nil {
+		return nil, fmt.Errorf("error on feature %v properties: %v", x.ID, err)
+	}
+
+	return res, nil
+}
+
+func NewFeature(appConfig *config.AppConfig, repository repository.AppRepository) FeatureService {
+
+	return &defaultFeatureSrv{
+		appConfig:  appConfig,
+		repository: repository,
+	}
+
+}
@@ -0,0 +1,312 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-gis/internal/config"
+	"go-gis/internal/geo/geojson"
+	"go-gis/internal/repository"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// FeatureReader stream of features, io.EOF at end,
+// *geojson.FeatureError is rejected and reading continues
+type FeatureReader interface {
+	Next() (*geojson.Feature, error)
+}
+
+// sliceFeatureReader FeatureReader of features in memory
+type sliceFeatureReader struct {
+	items []*geojson.Feature
+	index int
+}
+
+// NewSliceFeatureReader reader of decoded features
+func NewSliceFeatureReader(items []*geojson.Feature) FeatureReader {
+	return &sliceFeatureReader{items: items}
+}
+
+func (x *sliceFeatureReader) Next() (*geojson.Feature, error) {
+
+	if x.index >= len(x.items) {
+		return nil, io.EOF
+	}
+
+	x.index++
+
+	return x.items[x.index-1], nil
+}
+
+// FeatureWriter stream of features
+type FeatureWriter interface {
+	Write(f *geojson.Feature) error
+}
+
+// ImportResult counters of import
+type ImportResult struct {
+	Total    int
+	Imported int
+	Rejected int
+}
+
+// ImportReject rejected feature
+type ImportReject struct {
+	Index  int    `json:"index"`
+	ID     any    `json:"id,omitempty"`
+	Reason string `json:"reason"`
+}
+
+type ImportOptions struct {
+	BatchSize  int
+	SRID       int                    // EPSG code of source coordinates, 0 is WGS84
+	OnProgress func(res ImportResult) // after each batch
+	OnReject   func(rej ImportReject)
+}
+
+type importRow struct {
+	index   int
+	feature *geojson.Feature
+}
+
+func (x *defaultFeatureSrv) Import(layerName string, r FeatureReader, opts ImportOptions) (res ImportResult, err error) {
+
+	layer := x.appConfig.Layer(layerName)
+	if layer == nil {
+		return res, fmt.Errorf("%w: unknown layer: %v", ErrInvalidArgument, layerName)
+	}
+
+	opts.BatchSize = max(opts.BatchSize, 1)
+	if opts.SRID == 0 {
+		opts.SRID = 4326
+	}
+
+	validate := (*geojson.Geometry).Validate
+	if opts.SRID != 4326 {
+		validate = (*geojson.Geometry).ValidateStructure // reprojected by database
+	}
+
+	reject := func(index int, id any, reason string) {
+		res.Rejected++
+		if opts.OnReject != nil {
+			opts.OnReject(ImportReject{Index: index, ID: id, Reason: reason})
+		}
+	}
+
+	columns, propColumns, err := featureColumns(layer)
+	if err != nil {
+		return res, err
+	}
+
+	batch := newBatchInsert(opts.BatchSize, len(columns), func(rows []importRow) error {
+		return x.insertFeatures(layer, columns, propColumns, opts.SRID, rows)
+	}, func(row importRow, err error) {
+		reject(row.index, row.feature.ID, err.Error())
+	})
+
+	flush := func(n int, err error) error {
+		res.Imported += n
+		if opts.OnProgress != nil && n > 0 {
+			opts.OnProgress(res)
+		}
+		if err != nil {
+			return fmt.Errorf("error on layer %v import: %w", layer.Name, err)
+		}
+		return nil
+	}
+
+	for index := 0; ; index++ {
+
+		f, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+
+		fe := &geojson.FeatureError{}
+		if errors.As(err, &fe) {
+			res.Total++
+			reject(index, nil, fe.Err.Error())
+			continue
+		}
+
+		if err != nil {
+			if flushErr := flush(batch.flush()); flushErr != nil {
+				return res, flushErr
+			}
+			return res, err
+		}
+
+		res.Total++
+
+		if f.Geometry == nil {
+			reject(index, f.ID, "geometry is null")
+			continue
+		}
+
+		if err := validate(f.Geometry); err != nil {
+			reject(index, f.ID, err.Error())
+			continue
+		}
+
+		if err := flush(batch.add(importRow{index: index, feature: f})); err != nil {
+			return res, err
+		}
+	}
+
+	return res, flush(batch.flush())
+}
+
+// featureColumns quoted insert columns of layer: geometry, id, then property columns
+func featureColumns(layer *config.AppConfigLayer) (columns []string, propColumns []string, err error) {
+
+	names := []string{layer.GeometryColumn(), layer.IDColumn()}
+	for _, c := range layer.Columns {
+		if c != layer.IDColumn() && c != layer.GeometryColumn() {
+			names = append(names, c)
+			propColumns = append(propColumns, c)
+		}
+	}
+
+	for _, c := range names {
+		column, err := repository.QuoteIdent(c)
+		if err != nil {
+			return nil, nil, err
+		}
+		columns = append(columns, column)
+	}
+
+	return columns, propColumns, nil
+}
+
+// insertFeatures multi-row insert, properties not in layer columns are ignored
+func (x *defaultFeatureSrv) insertFeatures(layer *config.AppConfigLayer, columns, propColumns []string, srid int, rows []importRow) error {
+
+	table, err := repository.QuoteIdent(layer.Table)
+	if err != nil {
+		return err
+	}
+
+	geomSQL := "ST_SetSRID(ST_GeomFromGeoJSON(?), 4326)"
+	if srid != 4326 {
+		geomSQL = fmt.Sprintf("ST_Transform(ST_SetSRID(ST_GeomFromGeoJSON(?), %d), 4326)", srid)
+	}
+
+	values := make([]string, 0, len(rows))
+	args := make([]any, 0, len(rows)*len(columns))
+
+	for _, row := range rows {
+		f := row.feature
+
+		geometry, err := json.Marshal(f.Geometry)
+		if err != nil {
+			return err
+		}
+
+		items := []string{geomSQL}
+		args = append(args, string(geometry))
+
+		if f.ID != nil {
+			items = append(items, "?")
+			args = append(args, propertyValue(f.ID))
+		} else {
+			items = append(items, "DEFAULT")
+		}
+
+		for _, c := range propColumns {
+			v, ok := f.Properties[c]
+			if !ok {
+				items = append(items, "DEFAULT")
+				continue
+			}
+			items = append(items, "?")
+			args = append(args, propertyValue(v))
+		}
+
+		values = append(values, "("+strings.Join(items, ", ")+")")
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s",
+		table, strings.Join(columns, ", "), strings.Join(values, ", "))
+
+	return x.repository.Exec(query, args...).Error
+}
+
+// propertyValue json value as text, database casts text to column type
+func propertyValue(v any) any {
+
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func (x *defaultFeatureSrv) Export(layerName string, w FeatureWriter, limit int) (count int, err error) {
+
+	layer := x.appConfig.Layer(layerName)
+	if layer == nil {
+		return 0, fmt.Errorf("%w: unknown layer: %v", ErrInvalidArgument, layerName)
+	}
+
+	table, err := repository.QuoteIdent(layer.Table)
+	if err != nil {
+		return 0, err
+	}
+	geom, err := repository.QuoteIdent(layer.GeometryColumn())
+	if err != nil {
+		return 0, err
+	}
+	id, err := repository.QuoteIdent(layer.IDColumn())
+	if err != nil {
+		return 0, err
+	}
+	id = "t." + id
+
+	properties, err := propertiesSQL(layer)
+	if err != nil {
+		return 0, err
+	}
+
+	query := fmt.Sprintf(`SELECT %s::text AS id, ST_AsGeoJSON(t.%s) AS geometry, %s AS properties FROM %s AS t WHERE t.%s IS NOT NULL ORDER BY %s`,
+		id, geom, properties, table, geom, id)
+
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	rows, err := x.repository.Raw(query).Rows()
+	if err != nil {
+		return 0, fmt.Errorf("error on layer %v export: %v", layer.Name, err)
+	}
+	defer func(rows *sql.Rows) { _ = rows.Close() }(rows)
+
+	for rows.Next() {
+		row := featureRow{}
+		if err := rows.Scan(&row.ID, &row.Geometry, &row.Properties); err != nil {
+			return count, fmt.Errorf("error on layer %v export: %v", layer.Name, err)
+		}
+
+		f, err := row.feature()
+		if err != nil {
+			return count, err
+		}
+
+		if err := w.Write(f); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, rows.Err()
+}
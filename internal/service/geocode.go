@@ -7,16 +7,25 @@ import (
 	"go-gis/internal/util/utilhttp"
 	xlog "go-gis/internal/util/utillog"
 	"net/url"
+	"strconv"
 	"strings"
 )
 
 // respItemGeocodeOSM use as array
 type respItemGeocodeOSM struct {
 	DisplayName string `json:"display_name"`
+	Lat         string `json:"lat"`
+	Lon         string `json:"lon"`
 }
 
 type respItemGeocodeGMAPS struct {
 	FormattedAddress string `json:"formatted_address"`
+	Geometry         struct {
+		Location struct {
+			Lat float64 `json:"lat"`
+			Lng float64 `json:"lng"`
+		} `json:"location"`
+	} `json:"geometry"`
 }
 type respGeocodeGMAPS struct {
 	Results []respItemGeocodeGMAPS `json:"results"`
@@ -24,10 +33,15 @@ type respGeocodeGMAPS struct {
 
 type GeocodeService interface {
 	LocationToAddress(latLng string, lang string) (address string, err error)
+	// AddressToLocation forward geocoding, address is parsed and normalized before search
+	AddressToLocation(address string, lang string) (latLng string, normalized string, err error)
 }
 
 type defaultGeocodeSrv struct {
 	appConfig *config.AppConfig
+	address   AddressService
+	country   CountryService
+	postcode  PostcodeService // nil if disabled
 	Debug     bool
 }
 
@@ -42,7 +56,7 @@ func (x *defaultGeocodeSrv) LocationToAddress(latLng string, lang string) (addre
 		return "", err
 	}
 	if address != "" {
-		return address, nil
+		return x.withPostcode(address, latLng, lang), nil
 	}
 
 	address, err = x.locationToAddressGMAPS(latLng, lang)
@@ -50,7 +64,7 @@ func (x *defaultGeocodeSrv) LocationToAddress(latLng string, lang string) (addre
 		return "", err
 	}
 	if address != "" {
-		return address, nil
+		return x.withPostcode(address, latLng, lang), nil
 	}
 
 	return "", fmt.Errorf("error no any geocode service")
@@ -71,9 +85,7 @@ func (x *defaultGeocodeSrv) locationToAddressOSM(latLng string, lang string) (ad
 	baseURL = strings.ReplaceAll(baseURL, "{lang}", lang)
 	baseURL = strings.ReplaceAll(baseURL, "{api_key}", apiKey)
 
-	data, err := utilhttp.GetBytes(baseURL, nil, map[string]string{
-		"User-Agent": "Mozilla/5.0 (compatible; AcmeInc/1.0)",
-	})
+	data, err := utilhttp.GetBytes(baseURL, nil, requestHeaders(x.appConfig))
 
 	if err != nil {
 		return "", fmt.Errorf("error on OSM connect: %v", err)
@@ -113,9 +125,7 @@ func (x *defaultGeocodeSrv) locationToAddressGMAPS(latLng string, lang string) (
 	baseURL = strings.ReplaceAll(baseURL, "{lang}", lang)
 	baseURL = strings.ReplaceAll(baseURL, "{api_key}", apiKey)
 
-	data, err := utilhttp.GetBytes(baseURL, nil, map[string]string{
-		"User-Agent": "Mozilla/5.0 (compatible; AcmeInc/1.0)",
-	})
+	data, err := utilhttp.GetBytes(baseURL, nil, requestHeaders(x.appConfig))
 
 	if err != nil {
 		return "", fmt.Errorf("error on GMAPS connect: %v", err)
@@ -142,11 +152,179 @@ func (x *defaultGeocodeSrv) locationToAddressGMAPS(latLng string, lang string) (
 	return address, err
 }
 
-func NewGeocode(appConfig *config.AppConfig) GeocodeService {
+func (x *defaultGeocodeSrv) AddressToLocation(address string, lang string) (latLng string, normalized string, err error) {
+
+	if lang == "" {
+		lang = "en"
+	}
+
+	parsed := x.address.Parse(address, lang)
+	normalized = parsed.String()
+	if normalized == "" {
+		return "", "", fmt.Errorf("%w: empty address", ErrInvalidArgument)
+	}
+
+	latLng, err = x.addressToLocationOSM(normalized, lang)
+	if err != nil {
+		return "", "", err
+	}
+
+	if latLng == "" {
+		latLng, err = x.addressToLocationGMAPS(normalized, lang)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	if latLng == "" {
+		return "", normalized, nil // not found
+	}
+
+	if parsed.Postcode == "" {
+		if parsed.Postcode = x.nearestPostcode(latLng); parsed.Postcode != "" {
+			normalized = parsed.String()
+		}
+	}
+
+	return latLng, normalized, nil
+}
+
+// withPostcode append nearest postcode if address has no postcode
+func (x *defaultGeocodeSrv) withPostcode(address string, latLng string, lang string) string {
+
+	if x.postcode == nil || x.address.Parse(address, lang).Postcode != "" {
+		return address
+	}
+
+	if postcode := x.nearestPostcode(latLng); postcode != "" {
+		return address + ", " + postcode
+	}
+
+	return address
+}
+
+// nearestPostcode of postcode table in country of point, empty if disabled or not found
+func (x *defaultGeocodeSrv) nearestPostcode(latLng string) string {
+
+	if x.postcode == nil {
+		return ""
+	}
+
+	latText, lngText, _ := strings.Cut(latLng, ",")
+
+	lat, err := strconv.ParseFloat(strings.TrimSpace(latText), 64)
+	if err != nil {
+		return ""
+	}
+	lng, err := strconv.ParseFloat(strings.TrimSpace(lngText), 64)
+	if err != nil {
+		return ""
+	}
+
+	// postcode of neighbour country is wrong near borders
+	code := x.country.Lookup(lat, lng).Country
+	if code == "" {
+		return ""
+	}
+
+	res, err := x.postcode.Nearest(lat, lng, code)
+	if err != nil {
+		xlog.Warn("geocode postcode error: %v", err)
+		return ""
+	}
+	if res == nil {
+		return ""
+	}
+
+	return res.PostalCode
+}
+
+// searchURL gateway search url of template with {address} {lang} {api_key}
+func searchURL(cfg *config.AppConfigMapsGateway, address string, lang string) string {
+
+	res := cfg.SearchURL
+
+	res = strings.ReplaceAll(res, "{address}", url.QueryEscape(address))
+	res = strings.ReplaceAll(res, "{lang}", url.QueryEscape(lang))
+	res = strings.ReplaceAll(res, "{api_key}", url.QueryEscape(cfg.APIKey))
+
+	return res
+}
+
+func (x *defaultGeocodeSrv) addressToLocationOSM(address string, lang string) (latLng string, err error) {
+	cfg := &x.appConfig.OsmGateway
+	if !cfg.Enabled || cfg.SearchURL == "" {
+		return "", nil
+	}
+
+	data, err := utilhttp.GetBytes(searchURL(cfg, address, lang), nil, requestHeaders(x.appConfig))
+
+	if err != nil {
+		return "", fmt.Errorf("error on OSM connect: %v", err)
+	}
+
+	respObj := []respItemGeocodeOSM{} // array
+	err = json.Unmarshal(data, &respObj)
+	if err != nil {
+		return "", fmt.Errorf("error on OSM resp: %v", err)
+	}
+
+	if len(respObj) == 0 {
+		return "", nil // undef
+	}
+
+	latLng = respObj[0].Lat + "," + respObj[0].Lon
+	if cfg.Stdout {
+		xlog.Info("geocode: [Address: %v] [LatLng: %v]", address, latLng)
+	}
+
+	return latLng, nil
+}
+
+func (x *defaultGeocodeSrv) addressToLocationGMAPS(address string, lang string) (latLng string, err error) {
+	cfg := &x.appConfig.GmapsGateway
+	if !cfg.Enabled || cfg.SearchURL == "" {
+		return "", nil
+	}
+
+	data, err := utilhttp.GetBytes(searchURL(cfg, address, lang), nil, requestHeaders(x.appConfig))
+
+	if err != nil {
+		return "", fmt.Errorf("error on GMAPS connect: %v", err)
+	}
+
+	respObj := respGeocodeGMAPS{}
+	err = json.Unmarshal(data, &respObj)
+	if err != nil {
+		return "", fmt.Errorf("error on GMAPS resp: %v", err)
+	}
+
+	if len(respObj.Results) == 0 {
+		return "", nil // undef
+	}
+
+	loc := respObj.Results[0].Geometry.Location
+	latLng = fmt.Sprintf("%v,%v", loc.Lat, loc.Lng)
+	if cfg.Stdout {
+		xlog.Info("geocode: [Address: %v] [LatLng: %v]", address, latLng)
+	}
+
+	return latLng, nil
+}
+
+// NewGeocode postcode is used to fill in missing postcodes, nil or postcodes.geocode=false disables
+func NewGeocode(appConfig *config.AppConfig, address AddressService, country CountryService, postcode PostcodeService) GeocodeService {
+
+	if !appConfig.Postcodes.Geocode {
+		postcode = nil
+	}
 
 	return &defaultGeocodeSrv{
 		Debug:     appConfig.Debug,
 		appConfig: appConfig,
+		address:   address,
+		country:   country,
+		postcode:  postcode,
 	}
 
 }
@@ -0,0 +1,297 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-gis/internal/config"
+	"go-gis/internal/geo/geojson"
+	"go-gis/internal/geo/geom"
+	"go-gis/internal/repository"
+	xlog "go-gis/internal/util/utillog"
+	"math"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// geometry operations
+const (
+	GeometryOpBuffer       = "buffer"
+	GeometryOpSimplify     = "simplify"
+	GeometryOpConvexHull   = "hull"
+	GeometryOpCentroid     = "centroid"
+	GeometryOpArea         = "area"
+	GeometryOpLength       = "length"
+	GeometryOpUnion        = "union"
+	GeometryOpIntersection = "intersection"
+)
+
+// geometry engines
+const (
+	GeometryEngineAuto    = "auto"
+	GeometryEnginePostGIS = "postgis"
+	GeometryEngineGo      = "go"
+)
+
+// GeometryOpRequest input of operation, coordinates in WGS84
+type GeometryOpRequest struct {
+	Geometry   *geojson.Geometry   // single geometry ops
+	Geometries []*geojson.Geometry // union, intersection
+	Distance   float64             // buffer, meters
+	Segments   int                 // buffer, segments per quarter circle
+	Tolerance  float64             // simplify, degrees (dp) or square degrees (vw)
+	Method     string              // simplify, dp or vw
+}
+
+// GeometryOpResult geometry or measure
+type GeometryOpResult struct {
+	Geometry *geojson.Geometry
+	Value    *float64
+	Unit     string // m, m2
+	Engine   string
+}
+
+// GeometryService geometry operations in PostGIS with pure-Go fallback
+type GeometryService interface {
+	Apply(op string, req GeometryOpRequest) (*GeometryOpResult, error)
+}
+
+type defaultGeometrySrv struct {
+	appConfig  *config.AppConfig
+	repository repository.AppRepository
+}
+
+// geometryGoOps ops with pure-Go implementation
+var geometryGoOps = map[string]bool{
+	GeometryOpSimplify:   true,
+	GeometryOpConvexHull: true,
+	GeometryOpCentroid:   true,
+	GeometryOpArea:       true,
+	GeometryOpLength:     true,
+}
+
+func (x *defaultGeometrySrv) Apply(op string, req GeometryOpRequest) (*GeometryOpResult, error) {
+
+	if err := x.validate(op, &req); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	}
+
+	engine := x.appConfig.Geometry.Engine
+
+	if engine == GeometryEngineGo {
+		if !geometryGoOps[op] {
+			return nil, fmt.Errorf("%w: operation requires postgis: %v", ErrInvalidArgument, op)
+		}
+		return applyGo(op, req), nil
+	}
+
+	res, err := x.applyPostGIS(op, req)
+	if err == nil {
+		return res, nil
+	}
+
+	pgErr := &pgconn.PgError{}
+	if errors.As(err, &pgErr) && isGeometryInputError(pgErr) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArgument, pgErr.Message)
+	}
+
+	if engine == GeometryEngineAuto && geometryGoOps[op] {
+		xlog.Warn("geometry op %v fallback to go: %v", op, err)
+		return applyGo(op, req), nil
+	}
+
+	return nil, fmt.Errorf("error on geometry op %v: %v", op, err)
+}
+
+// geometryInputMessages internal_error (XX000) messages of PostGIS caused by input geometry
+var geometryInputMessages = []string{
+	"TopologyException",    // GEOS, invalid geometry like self-intersecting polygon
+	"invalid GeoJSON",      // ST_GeomFromGeoJSON
+	"Unknown GeoJSON type", // ST_GeomFromGeoJSON
+	"Unable to find 'coordinates'",
+}
+
+// isGeometryInputError data exception or known PostGIS error of input geometry,
+// other internal errors, timeouts, resource and connection errors are server faults
+func isGeometryInputError(pgErr *pgconn.PgError) bool {
+
+	if strings.HasPrefix(pgErr.Code, "22") {
+		return true
+	}
+
+	if pgErr.Code == "XX000" {
+		for _, v := range geometryInputMessages {
+			if strings.Contains(pgErr.Message, v) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (x *defaultGeometrySrv) validate(op string, req *GeometryOpRequest) error {
+
+	switch op {
+	case GeometryOpUnion, GeometryOpIntersection:
+		if len(req.Geometries) < 2 {
+			return fmt.Errorf("operation needs at least 2 geometries")
+		}
+	case GeometryOpBuffer, GeometryOpSimplify, GeometryOpConvexHull, GeometryOpCentroid, GeometryOpArea, GeometryOpLength:
+		if req.Geometry == nil {
+			return fmt.Errorf("geometry is required")
+		}
+		req.Geometries = []*geojson.Geometry{req.Geometry}
+	default:
+		return fmt.Errorf("unknown operation: %q", op)
+	}
+
+	vertices := 0
+	for _, g := range req.Geometries {
+		if g == nil {
+			return fmt.Errorf("geometry is null")
+		}
+		if err := g.Validate(); err != nil {
+			return err
+		}
+		g.Positions(func(*geojson.Position) { vertices++ })
+	}
+
+	if vertices > x.appConfig.Geometry.MaxVertices {
+		return fmt.Errorf("too many vertices: %v", vertices)
+	}
+
+	switch op {
+	case GeometryOpBuffer:
+		if math.IsNaN(req.Distance) || math.Abs(req.Distance) > x.appConfig.Geometry.MaxBufferDistance {
+			return fmt.Errorf("buffer distance out of range: %v", req.Distance)
+		}
+		if req.Segments == 0 {
+			req.Segments = 8
+		}
+		if req.Segments < 1 || req.Segments > 64 {
+			return fmt.Errorf("buffer segments out of range: %v", req.Segments)
+		}
+	case GeometryOpSimplify:
+		if req.Method == "" {
+			req.Method = geom.MethodDouglasPeucker
+		}
+		if req.Method != geom.MethodDouglasPeucker && req.Method != geom.MethodVisvalingam {
+			return fmt.Errorf("unknown simplify method: %q", req.Method)
+		}
+		if !(req.Tolerance >= 0) {
+			return fmt.Errorf("tolerance must be >= 0")
+		}
+	}
+
+	return nil
+}
+
+func measure(v float64, unit, engine string) *GeometryOpResult {
+	return &GeometryOpResult{Value: &v, Unit: unit, Engine: engine}
+}
+
+// applyGo ops of geometryGoOps
+func applyGo(op string, req GeometryOpRequest) *GeometryOpResult {
+
+	g := req.Geometry
+	res := &GeometryOpResult{Engine: GeometryEngineGo}
+
+	switch op {
+	case GeometryOpSimplify:
+		res.Geometry = geom.Simplify(g, req.Tolerance, req.Method)
+	case GeometryOpConvexHull:
+		res.Geometry = geom.ConvexHull(g)
+	case GeometryOpCentroid:
+		if c, ok := geom.Centroid(g); ok {
+			res.Geometry = geojson.NewPoint(c[0], c[1])
+		} else {
+			res.Geometry = &geojson.Geometry{Type: geojson.TypeGeometryCollection}
+		}
+	case GeometryOpArea:
+		return measure(geom.Area(g), "m2", GeometryEngineGo)
+	case GeometryOpLength:
+		return measure(geom.Length(g), "m", GeometryEngineGo)
+	}
+
+	return res
+}
+
+// applyPostGIS op as single select
+func (x *defaultGeometrySrv) applyPostGIS(op string, req GeometryOpRequest) (*GeometryOpResult, error) {
+
+	const g = "ST_SetSRID(ST_GeomFromGeoJSON(?), 4326)"
+
+	args := []any{}
+	for _, v := range req.Geometries {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, string(data))
+	}
+
+	var expr string
+	isMeasure := false
+	unit := ""
+
+	switch op {
+	case GeometryOpBuffer:
+		expr = "ST_Buffer(" + g + "::geography, ?, ?)::geometry"
+		args = append(args, req.Distance, fmt.Sprintf("quad_segs=%d", req.Segments))
+	case GeometryOpSimplify:
+		if req.Method == geom.MethodVisvalingam {
+			expr = "ST_SimplifyVW(" + g + ", ?)"
+		} else {
+			expr = "ST_Simplify(" + g + ", ?, true)"
+		}
+		args = append(args, req.Tolerance)
+	case GeometryOpConvexHull:
+		expr = "ST_ConvexHull(" + g + ")"
+	case GeometryOpCentroid:
+		expr = "ST_Centroid(" + g + ")"
+	case GeometryOpArea:
+		expr, isMeasure, unit = "ST_Area("+g+"::geography)", true, "m2"
+	case GeometryOpLength:
+		// length of lines, perimeter of polygons, each is 0 for other types
+		expr, isMeasure, unit = "ST_Length(g::geography) + ST_Perimeter(g::geography)", true, "m"
+		expr = "(SELECT " + expr + " FROM (SELECT " + g + " AS g) AS t)"
+	case GeometryOpUnion:
+		expr = "ST_Union(ARRAY[" + strings.TrimSuffix(strings.Repeat(g+", ", len(args)), ", ") + "])"
+	case GeometryOpIntersection:
+		expr = g
+		for range args[1:] {
+			expr = "ST_Intersection(" + expr + ", " + g + ")"
+		}
+	}
+
+	if isMeasure {
+		var value float64
+		if err := x.repository.Raw("SELECT "+expr, args...).Row().Scan(&value); err != nil {
+			return nil, err
+		}
+		return measure(value, unit, GeometryEnginePostGIS), nil
+	}
+
+	var data string
+	if err := x.repository.Raw("SELECT ST_AsGeoJSON("+expr+")", args...).Row().Scan(&data); err != nil {
+		return nil, err
+	}
+
+	res := &GeometryOpResult{Geometry: &geojson.Geometry{}, Engine: GeometryEnginePostGIS}
+	if err := json.Unmarshal([]byte(data), res.Geometry); err != nil {
+		return nil, fmt.Errorf("error on postgis geometry: %v", err)
+	}
+
+	return res, nil
+}
+
+// NewGeometry service
+func NewGeometry(appConfig *config.AppConfig, repository repository.AppRepository) GeometryService {
+
+	return &defaultGeometrySrv{
+		appConfig:  appConfig,
+		repository: repository,
+	}
+}
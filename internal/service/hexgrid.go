@@ -0,0 +1,156 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"go-gis/internal/config"
+	"go-gis/internal/geo/geojson"
+	"go-gis/internal/geo/hexgrid"
+	"go-gis/internal/repository"
+	"slices"
+	"strings"
+)
+
+// HexAggregate count points of layer in bbox by cells of resolution,
+// Sum column values are summed if set
+type HexAggregate struct {
+	Layer      string
+	BBox       geojson.BBox
+	Resolution int
+	Sum        string            // column of layer, optional
+	Filter     map[string]string // column:value, column must be in layer columns
+}
+
+// HexBin aggregate of cell
+type HexBin struct {
+	Cell  hexgrid.Cell
+	Count int
+	Sum   float64
+}
+
+// HexService hexagonal grid aggregation of stored points
+type HexService interface {
+	// Aggregate bins ordered by cell, ErrInvalidArgument if more than max cells or max rows
+	Aggregate(q HexAggregate) ([]HexBin, error)
+	// MaxCells limit of cells per request
+	MaxCells() int
+}
+
+type defaultHexSrv struct {
+	appConfig  *config.AppConfig
+	repository repository.AppRepository
+}
+
+func (x *defaultHexSrv) MaxCells() int { return x.appConfig.Hex.MaxCells }
+
+func (x *defaultHexSrv) Aggregate(q HexAggregate) ([]HexBin, error) {
+
+	layer := x.appConfig.Layer(q.Layer)
+	if layer == nil {
+		return nil, fmt.Errorf("%w: unknown layer: %v", ErrInvalidArgument, q.Layer)
+	}
+
+	if q.Resolution < 0 || q.Resolution > hexgrid.MaxResolution {
+		return nil, fmt.Errorf("%w: resolution is out of range: %v", ErrInvalidArgument, q.Resolution)
+	}
+
+	if q.Sum != "" && !layer.HasColumn(q.Sum) {
+		return nil, fmt.Errorf("%w: layer %v has no column: %v", ErrInvalidArgument, layer.Name, q.Sum)
+	}
+
+	table, err := repository.QuoteIdent(layer.Table)
+	if err != nil {
+		return nil, err
+	}
+	geom, err := repository.QuoteIdent(layer.GeometryColumn())
+	if err != nil {
+		return nil, err
+	}
+	geom = "t." + geom
+
+	where, args := bboxSQL(geom, q.BBox)
+	for k, v := range q.Filter {
+		if !layer.HasColumn(k) {
+			return nil, fmt.Errorf("%w: layer %v has no column: %v", ErrInvalidArgument, layer.Name, k)
+		}
+		column, err := repository.QuoteIdent(k)
+		if err != nil {
+			return nil, err
+		}
+		where += fmt.Sprintf(" AND t.%s::text = ?", column)
+		args = append(args, v)
+	}
+
+	value := "NULL::float8"
+	if q.Sum != "" {
+		column, err := repository.QuoteIdent(q.Sum)
+		if err != nil {
+			return nil, err
+		}
+		value = fmt.Sprintf("t.%s::float8", column)
+	}
+
+	// point of polygons and lines is on surface, one row over limit tells it is exceeded
+	maxRows := x.appConfig.Hex.MaxRows
+	query := fmt.Sprintf(`SELECT ST_X(p) AS lng, ST_Y(p) AS lat, value FROM (SELECT ST_PointOnSurface(%s) AS p, %s AS value FROM %s AS t WHERE %s LIMIT %d) AS s`,
+		geom, value, table, where, maxRows+1)
+
+	rows, err := x.repository.Raw(query, args...).Rows()
+	if err != nil {
+		return nil, fmt.Errorf("error on layer %v hex aggregate: %v", layer.Name, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	bins := map[hexgrid.Cell]*HexBin{}
+	maxCells := x.MaxCells()
+
+	for n := 0; rows.Next(); n++ {
+
+		if n >= maxRows {
+			return nil, fmt.Errorf("%w: more than %v points, use smaller bbox or filter", ErrInvalidArgument, maxRows)
+		}
+
+		var lng, lat float64
+		var v sql.NullFloat64
+		if err := rows.Scan(&lng, &lat, &v); err != nil {
+			return nil, fmt.Errorf("error on layer %v hex aggregate: %v", layer.Name, err)
+		}
+
+		c := hexgrid.FromLngLat(lng, lat, q.Resolution)
+
+		bin := bins[c]
+		if bin == nil {
+			if len(bins) >= maxCells {
+				return nil, fmt.Errorf("%w: more than %v cells, use coarser resolution or smaller bbox", ErrInvalidArgument, maxCells)
+			}
+			bin = &HexBin{Cell: c}
+			bins[c] = bin
+		}
+
+		bin.Count++
+		bin.Sum += v.Float64
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error on layer %v hex aggregate: %v", layer.Name, err)
+	}
+
+	res := make([]HexBin, 0, len(bins))
+	for _, v := range bins {
+		res = append(res, *v)
+	}
+
+	slices.SortFunc(res, func(a, b HexBin) int {
+		return strings.Compare(a.Cell.String(), b.Cell.String())
+	})
+
+	return res, nil
+}
+
+func NewHex(appConfig *config.AppConfig, repository repository.AppRepository) HexService {
+
+	return &defaultHexSrv{
+		appConfig:  appConfig,
+		repository: repository,
+	}
+}
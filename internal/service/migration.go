@@ -1,9 +1,74 @@
 // Package service app services
 package service
 
+import (
+	"go-gis/internal/migrate"
+	"go-gis/internal/repository"
+	xlog "go-gis/internal/util/utillog"
+)
+
+// NewMigrator migrator of embedded schema migrations
+func NewMigrator(repository repository.AppRepository) (*migrate.Migrator, error) {
+
+	migrations, err := migrate.Embedded(repository.Dialect())
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.New(repository.Driver(), migrations), nil
+}
+
+// MigrateUp ensure schema and postgis extension, then apply pending migrations,
+// the path of start and migrate subcommand
+func MigrateUp(repository repository.AppRepository) ([]migrate.Migration, error) {
+
+	if err := repository.EnsureSchema(); err != nil {
+		return nil, err
+	}
+
+	if err := repository.EnsurePostGIS(); err != nil {
+		return nil, err
+	}
+
+	migrator, err := NewMigrator(repository)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := migrator.Up()
+	for _, v := range applied {
+		xlog.Info("migration applied: %v_%v %v", v.Version, v.Name, repository.Schema())
+	}
+
+	return applied, err
+}
+
 func mustCreateRepository(appService AppService) {
 
-	_ = appService.Repository()
+	repository := appService.Repository()
+
+	if _, err := MigrateUp(repository); err != nil {
+		panic(err)
+	}
+
+	// layer tables are loaded by import, indexes are created once tables exist
+	for _, layer := range appService.Config().Layers {
+		exists, err := repository.HasTable(layer.Table)
+		if err != nil {
+			panic(err)
+		}
+		if exists {
+			if err := repository.CreateSpatialIndex(layer.Table, layer.GeometryColumn()); err != nil {
+				panic(err)
+			}
+		}
+	}
+
+	if x := appService.Postcode(); x != nil {
+		if err := x.Migrate(); err != nil {
+			panic(err)
+		}
+	}
 
 	mustInitRepositoryMasterData(appService)
 }
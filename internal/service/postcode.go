@@ -0,0 +1,323 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-gis/internal/config"
+	"go-gis/internal/geo/geonames"
+	"go-gis/internal/repository"
+	"io"
+	"strings"
+)
+
+// PostcodeReader stream of postal codes, io.EOF at end,
+// *geonames.RowError is rejected and reading continues
+type PostcodeReader interface {
+	Next() (*geonames.PostalCode, error)
+}
+
+// PostcodePlace place of postcode with admin areas
+type PostcodePlace struct {
+	Name   string `json:"name"`
+	Admin1 string `json:"admin1,omitempty"`
+	Admin2 string `json:"admin2,omitempty"`
+	Admin3 string `json:"admin3,omitempty"`
+}
+
+// Postcode centroid of postcode places
+type Postcode struct {
+	CountryCode string          `json:"country_code"`
+	PostalCode  string          `json:"postal_code"`
+	Lat         float64         `json:"lat"`
+	Lng         float64         `json:"lng"`
+	Distance    float64         `json:"distance,omitempty"` // meters, nearest only
+	Places      []PostcodePlace `json:"places"`
+}
+
+type PostcodeService interface {
+	// Import insert postal codes, rows of imported countries are replaced
+	Import(r PostcodeReader, opts ImportOptions) (ImportResult, error)
+	// Lookup centroid and places, nil if not found
+	Lookup(country string, postcode string) (*Postcode, error)
+	// Nearest postcode to point within max distance, country is optional, nil if not found
+	Nearest(lat, lng float64, country string) (*Postcode, error)
+	// Migrate create table and indexes
+	Migrate() error
+}
+
+type defaultPostcodeSrv struct {
+	appConfig  *config.AppConfig
+	repository repository.AppRepository
+}
+
+func (x *defaultPostcodeSrv) table() (string, error) {
+	return repository.QuoteIdent(x.appConfig.Postcodes.Table)
+}
+
+// indexName name of index without schema
+func (x *defaultPostcodeSrv) indexName(suffix string) (string, error) {
+
+	name := x.appConfig.Postcodes.Table
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+
+	return repository.QuoteIdent(name + "_" + suffix)
+}
+
+func (x *defaultPostcodeSrv) Migrate() error {
+
+	table, err := x.table()
+	if err != nil {
+		return err
+	}
+	keyIndex, err := x.indexName("key_idx")
+	if err != nil {
+		return err
+	}
+	geomIndex, err := x.indexName("geom_idx")
+	if err != nil {
+		return err
+	}
+
+	queries := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id bigserial PRIMARY KEY,
+	country_code varchar(2) NOT NULL,
+	postal_code text NOT NULL,
+	postal_key text NOT NULL,
+	place_name text NOT NULL DEFAULT '',
+	admin_name1 text NOT NULL DEFAULT '',
+	admin_code1 text NOT NULL DEFAULT '',
+	admin_name2 text NOT NULL DEFAULT '',
+	admin_code2 text NOT NULL DEFAULT '',
+	admin_name3 text NOT NULL DEFAULT '',
+	admin_code3 text NOT NULL DEFAULT '',
+	accuracy smallint NOT NULL DEFAULT 0,
+	geom geometry(Point, 4326) NOT NULL
+)`, table),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s (country_code, postal_key)`, keyIndex, table),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s USING GIST (geom)`, geomIndex, table),
+	}
+
+	for _, q := range queries {
+		if err := x.repository.Exec(q).Error; err != nil {
+			return fmt.Errorf("error on postcodes migration: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// Import in one transaction: replaced countries are deleted and filled together or not at all,
+// broken batches roll back to savepoint and are retried row by row
+func (x *defaultPostcodeSrv) Import(r PostcodeReader, opts ImportOptions) (res ImportResult, err error) {
+
+	// reader is consumed, transaction can not be retried
+	err = x.repository.TransactionWith(repository.TxOptions{Retries: -1}, func(tx repository.AppRepository) error {
+		return x.importRows(tx, r, opts, &res)
+	})
+	if err != nil {
+		res.Imported = 0 // rolled back
+	}
+
+	return res, err
+}
+
+// importRows rows of reader into postcodes table of tx
+func (x *defaultPostcodeSrv) importRows(tx repository.AppRepository, r PostcodeReader, opts ImportOptions, res *ImportResult) error {
+
+	table, err := x.table()
+	if err != nil {
+		return err
+	}
+
+	reject := func(index int, id any, reason string) {
+		res.Rejected++
+		if opts.OnReject != nil {
+			opts.OnReject(ImportReject{Index: index, ID: id, Reason: reason})
+		}
+	}
+
+	type row struct {
+		index int
+		item  *geonames.PostalCode
+	}
+
+	batch := newBatchInsert(opts.BatchSize, postcodeParams, func(rows []row) error {
+		items := make([]*geonames.PostalCode, len(rows))
+		for i, v := range rows {
+			items[i] = v.item
+		}
+		return x.insertPostcodes(tx, table, items)
+	}, func(v row, err error) {
+		reject(v.index, v.item.PostalCode, err.Error())
+	})
+
+	flush := func(n int, err error) error {
+		res.Imported += n
+		if opts.OnProgress != nil && n > 0 {
+			opts.OnProgress(*res)
+		}
+		if err != nil {
+			return fmt.Errorf("error on postcodes import: %w", err)
+		}
+		return nil
+	}
+
+	countries := map[string]bool{}
+
+	for index := 0; ; index++ {
+
+		item, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+
+		rowErr := &geonames.RowError{}
+		if errors.As(err, &rowErr) {
+			res.Total++
+			reject(index, nil, rowErr.Error())
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		res.Total++
+
+		if !countries[item.CountryCode] {
+			if err := flush(batch.flush()); err != nil {
+				return err
+			}
+			query := fmt.Sprintf("DELETE FROM %s WHERE country_code = ?", table)
+			if err := tx.Exec(query, item.CountryCode).Error; err != nil {
+				return fmt.Errorf("error on postcodes of %v: %w", item.CountryCode, err)
+			}
+			countries[item.CountryCode] = true
+		}
+
+		if err := flush(batch.add(row{index: index, item: item})); err != nil {
+			return err
+		}
+	}
+
+	return flush(batch.flush())
+}
+
+// postcodeParams bind parameters of inserted row
+const postcodeParams = 13
+
+// insertPostcodes multi-row insert in savepoint of tx
+func (x *defaultPostcodeSrv) insertPostcodes(tx repository.AppRepository, table string, items []*geonames.PostalCode) error {
+
+	values := make([]string, 0, len(items))
+	args := make([]any, 0, len(items)*postcodeParams)
+
+	for _, v := range items {
+		values = append(values, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ST_SetSRID(ST_MakePoint(?, ?), 4326))")
+		args = append(args, v.CountryCode, v.PostalCode, geonames.NormalizePostalCode(v.PostalCode),
+			v.PlaceName, v.AdminName1, v.AdminCode1, v.AdminName2, v.AdminCode2, v.AdminName3, v.AdminCode3,
+			v.Accuracy, v.Lng, v.Lat)
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (country_code, postal_code, postal_key, place_name,
+	admin_name1, admin_code1, admin_name2, admin_code2, admin_name3, admin_code3, accuracy, geom) VALUES %s`,
+		table, strings.Join(values, ", "))
+
+	return tx.Transaction(func(sp repository.AppRepository) error {
+		return sp.Exec(query, args...).Error
+	})
+}
+
+func (x *defaultPostcodeSrv) Lookup(country string, postcode string) (*Postcode, error) {
+
+	country = strings.ToUpper(strings.TrimSpace(country))
+	key := geonames.NormalizePostalCode(postcode)
+
+	if len(country) != 2 || key == "" {
+		return nil, fmt.Errorf("%w: country and postcode are required", ErrInvalidArgument)
+	}
+
+	table, err := x.table()
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`SELECT min(postal_code), ST_Y(ST_Centroid(ST_Collect(geom))), ST_X(ST_Centroid(ST_Collect(geom))),
+	json_agg(json_build_object('name', place_name, 'admin1', admin_name1, 'admin2', admin_name2, 'admin3', admin_name3) ORDER BY place_name)::text
+FROM %s WHERE country_code = ? AND postal_key = ? HAVING count(*) > 0`, table)
+
+	res := &Postcode{CountryCode: country}
+	var places string
+
+	err = x.repository.Raw(query, country, key).Row().Scan(&res.PostalCode, &res.Lat, &res.Lng, &places)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error on postcode %v %v: %v", country, key, err)
+	}
+
+	if err := json.Unmarshal([]byte(places), &res.Places); err != nil {
+		return nil, fmt.Errorf("error on postcode %v %v places: %v", country, key, err)
+	}
+
+	return res, nil
+}
+
+func (x *defaultPostcodeSrv) Nearest(lat, lng float64, country string) (*Postcode, error) {
+
+	country = strings.ToUpper(strings.TrimSpace(country))
+
+	table, err := x.table()
+	if err != nil {
+		return nil, err
+	}
+
+	where := ""
+	args := []any{lng, lat}
+	if country != "" {
+		where = "WHERE t.country_code = ?"
+		args = append(args, country)
+	}
+
+	query := fmt.Sprintf(`WITH p AS (SELECT ST_SetSRID(ST_MakePoint(?, ?), 4326) AS g)
+SELECT t.country_code, t.postal_code, ST_Distance(t.geom::geography, p.g::geography)
+FROM %s AS t, p %s ORDER BY t.geom <-> p.g LIMIT 1`, table, where)
+
+	var code, postcode string
+	var distance float64
+
+	err = x.repository.Raw(query, args...).Row().Scan(&code, &postcode, &distance)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error on nearest postcode: %v", err)
+	}
+
+	if maxDistance := x.appConfig.Postcodes.MaxDistance; maxDistance > 0 && distance > maxDistance {
+		return nil, nil
+	}
+
+	res, err := x.Lookup(code, postcode)
+	if err != nil || res == nil {
+		return res, err
+	}
+
+	res.Distance = distance
+
+	return res, nil
+}
+
+func NewPostcode(appConfig *config.AppConfig, repository repository.AppRepository) PostcodeService {
+
+	return &defaultPostcodeSrv{
+		appConfig:  appConfig,
+		repository: repository,
+	}
+}
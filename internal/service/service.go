@@ -1,17 +1,25 @@
 package service
 
 import (
+	"cmp"
+	"context"
 	"encoding/base64"
+	"errors"
 	"go-gis/internal/config"
 	"go-gis/internal/i18n"
 	"go-gis/internal/repository"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	xlog "go-gis/internal/util/utillog"
 	"net/http"
 )
 
+// ErrInvalidArgument wrapped by services on bad client input
+var ErrInvalidArgument = errors.New("invalid argument")
+
 // AppService all services ep
 type AppService interface {
 	Config() *config.AppConfig
@@ -23,14 +31,58 @@ type AppService interface {
 	Repository() repository.AppRepository
 
 	Geocode() GeocodeService
+	Address() AddressService
+	Postcode() PostcodeService // nil if disabled
+	Country() CountryService
+
+	Feature() FeatureService
+	Tile() TileService
+	Cluster() ClusterService // nil if no layers are clustered
+	Hex() HexService
+	TileProxy() TileProxyService // nil if disabled
+	StaticMap() StaticMapService // nil if tile proxy disabled
+
+	Transform() TransformService
+	Geometry() GeometryService
+
+	// Tenant services on repository of tenant schema, ErrUnknownTenant if not in config
+	Tenant(name string) (AppService, error)
+	// WithContext services of request, queries are cancelled with ctx
+	WithContext(ctx context.Context) AppService
+
+	// Ready database is connected and migrated, false in degraded start until connected
+	Ready() bool
 }
 type defaultAppService struct {
-	geocode GeocodeService
+	geocode  GeocodeService
+	address  AddressService
+	postcode PostcodeService
+	country  CountryService
+
+	feature FeatureService
+	tile    TileService
+	cluster ClusterService
+	hex     HexService
+
+	tileProxy TileProxyService
+	staticMap StaticMapService
+
+	transform TransformService
+	geometry  GeometryService
 
 	configSource *config.AppConfigSource
 	repository   repository.AppRepository
 
 	lang i18n.AppLang
+
+	skipMigration bool // DB.Migration is ignored
+	allowDegraded bool // DB.Degraded start of server
+
+	ready atomic.Bool // of root services
+
+	parent  *defaultAppService // of tenant services
+	mu      sync.Mutex
+	tenants map[string]*defaultAppService
 }
 
 func (x *defaultAppService) mustConfig() {
@@ -53,13 +105,104 @@ func (x *defaultAppService) mustBuild() {
 
 	x.lang = i18n.MustNewAppLang(appConfig)
 
-	x.repository = repository.MustNewRepository(appConfig) // , appLogger)
+	degraded := appConfig.DB.Degraded && x.allowDegraded
 
-	x.geocode = NewGeocode(appConfig)
+	if degraded {
+		rep, err := repository.OpenRepository(appConfig)
+		if err != nil {
+			panic(err)
+		}
+		x.repository = rep
+	} else {
+		x.repository = repository.MustNewRepository(appConfig) // , appLogger)
+	}
+
+	x.address = MustNewAddress(appConfig)
+	x.country = MustNewCountry(appConfig)
+
+	if appConfig.TileProxy.Enabled {
+		x.tileProxy = MustNewTileProxy(appConfig)
+		x.staticMap = NewStaticMap(appConfig, x.tileProxy)
+	}
+
+	x.transform = MustNewTransform(appConfig)
+
+	x.buildRepositoryServices()
+	x.cluster = NewCluster(appConfig, x.repository)
+
+	if degraded {
+		go x.connectDegraded()
+		return
+	}
+
+	x.mustMigrate()
+	x.ready.Store(true)
+}
 
-	if appConfig.DB.Migration {
+// mustMigrate schema migrations of default and tenant schemas if enabled
+func (x *defaultAppService) mustMigrate() {
+
+	appConfig := x.Config()
+
+	if appConfig.DB.Migration && !x.skipMigration {
 		mustCreateRepository(x) //
+
+		for _, name := range TenantNames(appConfig) {
+			tenant, err := x.Tenant(name)
+			if err != nil {
+				panic(err)
+			}
+			mustCreateRepository(tenant)
+		}
+	}
+}
+
+// connectDegraded wait for database and migrate, services are ready after,
+// on migration error services stay not ready
+func (x *defaultAppService) connectDegraded() {
+
+	xlog.Warn("db degraded mode: waiting for database")
+
+	if err := repository.WaitConnected(context.Background(), x.repository, 0); err != nil {
+		if !errors.Is(err, repository.ErrClosed) {
+			xlog.Error("%v", err)
+		}
+		return
 	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			xlog.Error("error on migration, db stays not ready: %v", r)
+		}
+	}()
+
+	x.mustMigrate()
+	x.ready.Store(true)
+
+	xlog.Info("db is connected, services are ready")
+}
+
+// Ready database is connected and migrated
+func (x *defaultAppService) Ready() bool {
+	return cmp.Or(x.parent, x).ready.Load()
+}
+
+// buildRepositoryServices stateless services of x.repository, rebuilt per tenant and request
+func (x *defaultAppService) buildRepositoryServices() {
+
+	appConfig := x.Config()
+
+	if appConfig.Postcodes.Enabled {
+		x.postcode = NewPostcode(appConfig, x.repository)
+	}
+
+	x.geocode = NewGeocode(appConfig, x.address, x.country, x.postcode)
+
+	x.feature = NewFeature(appConfig, x.repository)
+	x.tile = NewTile(appConfig, x.repository)
+	x.hex = NewHex(appConfig, x.repository)
+
+	x.geometry = NewGeometry(appConfig, x.repository)
 }
 
 func mustConfigRuntime(appConfig *config.AppConfig) {
@@ -91,6 +234,18 @@ func mustConfigRuntime(appConfig *config.AppConfig) {
 	}
 }
 
+// requestHeaders headers of outgoing requests
+func requestHeaders(appConfig *config.AppConfig) map[string]string {
+
+	res := map[string]string{}
+
+	if ua := appConfig.HTTPTransport.UserAgent; ua != "" {
+		res["User-Agent"] = ua
+	}
+
+	return res
+}
+
 // MustNewAppServiceProd prod
 func MustNewAppServiceProd() AppService {
 
@@ -103,6 +258,30 @@ func MustNewAppServiceProd() AppService {
 
 }
 
+// MustNewAppServiceServer prod, starts without database if DB.Degraded
+func MustNewAppServiceServer() AppService {
+
+	appService := &defaultAppService{allowDegraded: true}
+
+	appService.mustConfig()
+	appService.mustBuild()
+
+	return appService
+
+}
+
+// MustNewAppServiceWithoutMigration prod without schema migration on start
+func MustNewAppServiceWithoutMigration() AppService {
+
+	appService := &defaultAppService{skipMigration: true}
+
+	appService.mustConfig()
+	appService.mustBuild()
+
+	return appService
+
+}
+
 // MustNewAppServiceTesting testing
 func MustNewAppServiceTesting() AppService {
 
@@ -119,6 +298,21 @@ func (x *defaultAppService) HasLang(code string) bool           { return x.lang.
 func (x *defaultAppService) Repository() repository.AppRepository { return x.repository }
 
 func (x *defaultAppService) Geocode() GeocodeService { return x.geocode }
+func (x *defaultAppService) Address() AddressService { return x.address }
+
+func (x *defaultAppService) Postcode() PostcodeService { return x.postcode }
+func (x *defaultAppService) Country() CountryService   { return x.country }
+
+func (x *defaultAppService) Feature() FeatureService { return x.feature }
+func (x *defaultAppService) Tile() TileService       { return x.tile }
+func (x *defaultAppService) Cluster() ClusterService { return x.cluster }
+func (x *defaultAppService) Hex() HexService         { return x.hex }
+
+func (x *defaultAppService) TileProxy() TileProxyService { return x.tileProxy }
+func (x *defaultAppService) StaticMap() StaticMapService { return x.staticMap }
+
+func (x *defaultAppService) Transform() TransformService { return x.transform }
+func (x *defaultAppService) Geometry() GeometryService   { return x.geometry }
 
 func BasicAuth(username, password string) string {
 	// Combine username and password in the format "username:password"
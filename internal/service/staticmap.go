@@ -0,0 +1,333 @@
+package service
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"go-gis/internal/config"
+	"go-gis/internal/geo/geojson"
+	"go-gis/internal/geo/render"
+	"go-gis/internal/geo/tile"
+	xlog "go-gis/internal/util/utillog"
+	"image"
+	"image/color"
+	"image/jpeg" // also registers tile decoder
+	"image/png"
+	"math"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// static map overlay kinds
+const (
+	OverlayMarker  = "marker"
+	OverlayPath    = "path"
+	OverlayPolygon = "polygon"
+)
+
+// static map image formats
+const (
+	FormatPNG  = "png"
+	FormatJPEG = "jpeg"
+)
+
+// StaticMapStyle overlay style, sizes in px before scale
+type StaticMapStyle struct {
+	Color  color.RGBA
+	Fill   color.RGBA // polygon only
+	Weight float64    // line width or marker size
+}
+
+// DefaultStaticMapStyle default style of overlay kind
+func DefaultStaticMapStyle(kind string) StaticMapStyle {
+
+	switch kind {
+	case OverlayMarker:
+		return StaticMapStyle{Color: color.RGBA{220, 38, 38, 255}, Weight: 16}
+	case OverlayPolygon:
+		return StaticMapStyle{Color: color.RGBA{37, 99, 235, 255}, Fill: color.RGBA{9, 24, 58, 64}, Weight: 2}
+	}
+
+	return StaticMapStyle{Color: color.RGBA{37, 99, 235, 255}, Weight: 3}
+}
+
+type StaticMapOverlay struct {
+	Kind   string
+	Style  StaticMapStyle
+	Points []geojson.Position
+}
+
+type StaticMapRequest struct {
+	Center   *geojson.Position // nil = auto-fit overlays
+	Zoom     int               // < 0 = auto-fit
+	Width    int
+	Height   int
+	Scale    int
+	Format   string // png jpeg
+	Source   string // tile proxy source
+	Overlays []StaticMapOverlay
+}
+
+type StaticMapService interface {
+	Render(req StaticMapRequest) (data []byte, contentType string, err error)
+}
+
+type defaultStaticMapSrv struct {
+	appConfig *config.AppConfig
+	tileProxy TileProxyService
+}
+
+func (x *defaultStaticMapSrv) validate(req *StaticMapRequest) error {
+
+	cfg := &x.appConfig.StaticMap
+
+	if req.Width <= 0 || req.Height <= 0 || req.Width > cfg.MaxWidth || req.Height > cfg.MaxHeight {
+		return fmt.Errorf("%w: size must be up to %vx%v", ErrInvalidArgument, cfg.MaxWidth, cfg.MaxHeight)
+	}
+
+	if req.Scale < 1 || req.Scale > cfg.MaxScale {
+		return fmt.Errorf("%w: scale must be 1..%v", ErrInvalidArgument, cfg.MaxScale)
+	}
+
+	if req.Zoom > tile.MaxZoom {
+		return fmt.Errorf("%w: zoom must be up to %v", ErrInvalidArgument, tile.MaxZoom)
+	}
+
+	if req.Format != FormatPNG && req.Format != FormatJPEG {
+		return fmt.Errorf("%w: format must be png or jpeg", ErrInvalidArgument)
+	}
+
+	if !x.tileProxy.HasSource(req.Source) {
+		return fmt.Errorf("%w: unknown tile source: %v", ErrInvalidArgument, req.Source)
+	}
+
+	for _, o := range req.Overlays {
+		for _, p := range o.Points {
+			if p[0] < -180 || p[0] > 180 || p[1] < -90 || p[1] > 90 {
+				return fmt.Errorf("%w: position out of range: %v", ErrInvalidArgument, p)
+			}
+		}
+	}
+
+	if req.Center == nil || req.Zoom < 0 {
+		if x.bound(req.Overlays) == nil {
+			return fmt.Errorf("%w: center and zoom or overlays are required", ErrInvalidArgument)
+		}
+	}
+
+	return nil
+}
+
+func (x *defaultStaticMapSrv) Render(req StaticMapRequest) ([]byte, string, error) {
+
+	req.Source = cmp.Or(req.Source, x.appConfig.StaticMap.Source)
+	req.Format = cmp.Or(req.Format, FormatPNG)
+	req.Scale = max(req.Scale, 1)
+
+	if err := x.validate(&req); err != nil {
+		return nil, "", err
+	}
+
+	if req.Zoom < 0 {
+		req.Zoom = x.fitZoom(&req)
+	}
+
+	if req.Center == nil {
+		req.Center = x.fitCenter(&req)
+	}
+
+	tileSize := 256 * req.Scale
+	width, height := req.Width*req.Scale, req.Height*req.Scale
+
+	cx, cy := tile.LngLatToPixel(req.Center[0], req.Center[1], req.Zoom, float64(tileSize))
+	left, top := cx-float64(width)/2, cy-float64(height)/2
+
+	canvas := render.NewCanvas(width, height, color.RGBA{229, 227, 223, 255})
+
+	x.drawTiles(canvas, &req, left, top)
+
+	project := func(points []geojson.Position) []render.Point {
+		res := make([]render.Point, 0, len(points))
+		for _, p := range points {
+			px, py := tile.LngLatToPixel(p[0], p[1], req.Zoom, float64(tileSize))
+			res = append(res, render.Point{X: px - left, Y: py - top})
+		}
+		return res
+	}
+
+	scale := float64(req.Scale)
+
+	for _, kind := range []string{OverlayPolygon, OverlayPath, OverlayMarker} { // z-order
+		for _, o := range req.Overlays {
+			if o.Kind != kind || len(o.Points) == 0 {
+				continue
+			}
+
+			points := project(o.Points)
+
+			switch kind {
+			case OverlayPolygon:
+				fill := canvas.NewShape()
+				fill.Polygon([][]render.Point{points})
+				canvas.Paint(fill, o.Style.Fill)
+
+				if o.Style.Weight > 0 {
+					stroke := canvas.NewShape()
+					stroke.Polyline(append(points, points[0]), o.Style.Weight*scale)
+					canvas.Paint(stroke, o.Style.Color)
+				}
+			case OverlayPath:
+				stroke := canvas.NewShape()
+				stroke.Polyline(points, o.Style.Weight*scale)
+				canvas.Paint(stroke, o.Style.Color)
+			case OverlayMarker:
+				size := o.Style.Weight * scale
+				for _, p := range points {
+					outline := canvas.NewShape()
+					outline.Marker(p, size+2*scale)
+					canvas.Paint(outline, color.RGBA{255, 255, 255, 255})
+
+					pin := canvas.NewShape()
+					pin.Marker(render.Point{X: p.X, Y: p.Y - scale}, size)
+					canvas.Paint(pin, o.Style.Color)
+				}
+			}
+		}
+	}
+
+	buf := &bytes.Buffer{}
+
+	if req.Format == FormatJPEG {
+		err := jpeg.Encode(buf, canvas.Image, &jpeg.Options{Quality: 90})
+		return buf.Bytes(), "image/jpeg", err
+	}
+
+	err := png.Encode(buf, canvas.Image)
+	return buf.Bytes(), "image/png", err
+}
+
+// staticMapFetches concurrent tile fetches of one static map
+const staticMapFetches = 6
+
+// drawTiles draw tiles covering canvas, missing tiles keep background.
+// Scale 2 and more fetch tiles of finer zoom, each of them covers 256 px or less of canvas
+// and upstream 256 px tiles are not upscaled.
+func (x *defaultStaticMapSrv) drawTiles(canvas *render.Canvas, req *StaticMapRequest, left, top float64) {
+
+	zoom := min(req.Zoom+int(math.Log2(float64(req.Scale))), tile.MaxZoom)
+	size := float64(256*req.Scale) / float64(int(1)<<(zoom-req.Zoom)) // px of tile on canvas
+
+	bounds := canvas.Image.Bounds()
+	n := 1 << zoom
+
+	tx0, ty0 := int(math.Floor(left/size)), int(math.Floor(top/size))
+	tx1 := int(math.Floor((left + float64(bounds.Dx()-1)) / size))
+	ty1 := int(math.Floor((top + float64(bounds.Dy()-1)) / size))
+
+	type tileImage struct {
+		dst image.Rectangle
+		img image.Image
+	}
+
+	mu := sync.Mutex{}
+	g := errgroup.Group{}
+	g.SetLimit(staticMapFetches)
+	images := []tileImage{}
+
+	for ty := max(ty0, 0); ty <= min(ty1, n-1); ty++ {
+		for tx := tx0; tx <= tx1; tx++ {
+
+			t := tile.Tile{Z: zoom, X: ((tx % n) + n) % n, Y: ty} // wrap around antimeridian
+			dst := image.Rect(
+				int(math.Round(float64(tx)*size-left)), int(math.Round(float64(ty)*size-top)),
+				int(math.Round(float64(tx+1)*size-left)), int(math.Round(float64(ty+1)*size-top)))
+
+			g.Go(func() error {
+				res, err := x.tileProxy.RasterTile(req.Source, t)
+				if err != nil {
+					xlog.Warn("static map tile error: %v", err)
+					return nil
+				}
+
+				img, _, err := image.Decode(bytes.NewReader(res.Data))
+				if err != nil {
+					xlog.Warn("static map tile %v decode error: %v", t, err)
+					return nil
+				}
+
+				mu.Lock()
+				images = append(images, tileImage{dst: dst, img: img})
+				mu.Unlock()
+
+				return nil
+			})
+		}
+	}
+
+	_ = g.Wait() // missing tiles are logged
+
+	for _, v := range images {
+		canvas.DrawImage(v.img, v.dst)
+	}
+}
+
+// bound of overlays, nil if no points
+func (x *defaultStaticMapSrv) bound(overlays []StaticMapOverlay) *geojson.BBox {
+
+	var res *geojson.BBox
+
+	for _, o := range overlays {
+		for _, p := range o.Points {
+			if res == nil {
+				res = &geojson.BBox{p[0], p[1], p[0], p[1]}
+			}
+			res.Extend(p)
+		}
+	}
+
+	return res
+}
+
+// fitZoom max zoom where overlays fit into image with padding
+func (x *defaultStaticMapSrv) fitZoom(req *StaticMapRequest) int {
+
+	cfg := &x.appConfig.StaticMap
+	b := x.bound(req.Overlays)
+
+	width := float64(max(req.Width-2*cfg.Padding, 1))
+	height := float64(max(req.Height-2*cfg.Padding, 1))
+
+	for z := cfg.MaxZoom; z > 0; z-- {
+		x0, y0 := tile.LngLatToPixel(b[0], b[3], z, 256)
+		x1, y1 := tile.LngLatToPixel(b[2], b[1], z, 256)
+
+		if x1-x0 <= width && y1-y0 <= height {
+			return z
+		}
+	}
+
+	return 0
+}
+
+// fitCenter center of overlays bound in mercator
+func (x *defaultStaticMapSrv) fitCenter(req *StaticMapRequest) *geojson.Position {
+
+	b := x.bound(req.Overlays)
+
+	x0, y0 := tile.LngLatToPixel(b[0], b[3], req.Zoom, 256)
+	x1, y1 := tile.LngLatToPixel(b[2], b[1], req.Zoom, 256)
+
+	lng, lat := tile.PixelToLngLat((x0+x1)/2, (y0+y1)/2, req.Zoom, 256)
+
+	return &geojson.Position{lng, lat}
+}
+
+func NewStaticMap(appConfig *config.AppConfig, tileProxy TileProxyService) StaticMapService {
+
+	return &defaultStaticMapSrv{
+		appConfig: appConfig,
+		tileProxy: tileProxy,
+	}
+
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"go-gis/internal/config"
+	"go-gis/internal/repository"
+	"slices"
+	"sort"
+)
+
+// ErrUnknownTenant tenant is not in config or tenants are disabled
+var ErrUnknownTenant = errors.New("unknown tenant")
+
+// TenantNames configured tenants ordered by name, empty if disabled
+func TenantNames(appConfig *config.AppConfig) []string {
+
+	if !appConfig.Tenants.Enabled {
+		return nil
+	}
+
+	res := []string{}
+	for k := range appConfig.Tenants.Schemas {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+// Tenant services on repository of tenant schema, built once per tenant.
+// Services without repository are shared with default services
+func (x *defaultAppService) Tenant(name string) (AppService, error) {
+
+	if x.parent != nil {
+		return x.parent.Tenant(name)
+	}
+
+	appConfig := x.Config()
+
+	if !slices.Contains(TenantNames(appConfig), name) {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTenant, name)
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if v, ok := x.tenants[name]; ok {
+		return v, nil
+	}
+
+	rep, err := x.repository.ForSchema(appConfig.Tenants.Schemas[name])
+	if err != nil {
+		return nil, err
+	}
+
+	res := x.derive(rep)
+	res.cluster = NewCluster(appConfig, rep)
+
+	if x.tenants == nil {
+		x.tenants = map[string]*defaultAppService{}
+	}
+	x.tenants[name] = res
+
+	return res, nil
+}
+
+// WithContext services on repository of ctx, cluster index is shared and loads in own context
+func (x *defaultAppService) WithContext(ctx context.Context) AppService {
+
+	res := x.derive(x.repository.WithContext(ctx))
+	res.cluster = x.cluster
+
+	return res
+}
+
+// derive services on repository, services without repository are shared
+func (x *defaultAppService) derive(rep repository.AppRepository) *defaultAppService {
+
+	res := &defaultAppService{
+		address:      x.address,
+		country:      x.country,
+		tileProxy:    x.tileProxy,
+		staticMap:    x.staticMap,
+		transform:    x.transform,
+		configSource: x.configSource,
+		repository:   rep,
+		lang:         x.lang,
+		parent:       cmp.Or(x.parent, x),
+	}
+	res.buildRepositoryServices()
+
+	return res
+}
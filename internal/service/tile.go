@@ -0,0 +1,118 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"go-gis/internal/config"
+	"go-gis/internal/geo/tile"
+	"go-gis/internal/repository"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	tileSizeMetric = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gis_vector_tile_size_bytes",
+		Help:    "Size of generated vector tiles.",
+		Buckets: prometheus.ExponentialBuckets(256, 4, 8), // 256B..4MB
+	}, []string{"layer"})
+
+	tileDurationMetric = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gis_vector_tile_duration_seconds",
+		Help:    "Latency of vector tile generation.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"layer"})
+)
+
+type TileService interface {
+	// VectorTile MVT tile of layer, empty if no features in tile
+	VectorTile(layer string, t tile.Tile) ([]byte, error)
+}
+
+type defaultTileSrv struct {
+	appConfig  *config.AppConfig
+	repository repository.AppRepository
+}
+
+func (x *defaultTileSrv) VectorTile(layerName string, t tile.Tile) ([]byte, error) {
+
+	layer := x.appConfig.Layer(layerName)
+	if layer == nil {
+		return nil, fmt.Errorf("%w: unknown layer: %v", ErrInvalidArgument, layerName)
+	}
+
+	if !t.Valid() || !layer.HasZoom(t.Z) {
+		return nil, fmt.Errorf("%w: tile %v is out of layer %v zoom range", ErrInvalidArgument, t, layerName)
+	}
+
+	start := time.Now()
+
+	cfg := &x.appConfig.Tiles
+	table, err := repository.QuoteIdent(layer.Table)
+	if err != nil {
+		return nil, err
+	}
+	geom, err := repository.QuoteIdent(layer.GeometryColumn())
+	if err != nil {
+		return nil, err
+	}
+	geom = "t." + geom
+
+	names := []string{layer.IDColumn()}
+	for _, c := range layer.VectorTileColumns() {
+		if c != layer.IDColumn() {
+			names = append(names, c)
+		}
+	}
+
+	columns := make([]string, 0, len(names))
+	for _, c := range names {
+		column, err := repository.QuoteIdent(c)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, "t."+column)
+	}
+
+	margin := float64(cfg.Buffer) / float64(cfg.Extent)
+
+	query := fmt.Sprintf(`WITH bounds AS (
+	SELECT ST_TileEnvelope(?, ?, ?) AS merc, ST_Transform(ST_TileEnvelope(?, ?, ?, margin => ?), 4326) AS wgs
+), mvt AS (
+	SELECT ST_AsMVTGeom(ST_Transform(%[1]s, 3857), bounds.merc, ?, ?, true) AS mvt_geom, %[2]s
+	FROM %[3]s AS t, bounds
+	WHERE ST_Intersects(%[1]s, bounds.wgs)
+)
+SELECT ST_AsMVT(mvt.*, ?, ?, 'mvt_geom') FROM mvt`,
+		geom, strings.Join(columns, ", "), table)
+
+	args := []any{
+		t.Z, t.X, t.Y,
+		t.Z, t.X, t.Y, margin,
+		cfg.Extent, cfg.Buffer,
+		layer.Name, cfg.Extent,
+	}
+
+	var data []byte
+	err = x.repository.Raw(query, args...).Row().Scan(&data)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("error on layer %v tile %v: %v", layer.Name, t, err)
+	}
+
+	tileDurationMetric.WithLabelValues(layer.Name).Observe(time.Since(start).Seconds())
+	tileSizeMetric.WithLabelValues(layer.Name).Observe(float64(len(data)))
+
+	return data, nil
+}
+
+func NewTile(appConfig *config.AppConfig, repository repository.AppRepository) TileService {
+
+	return &defaultTileSrv{
+		appConfig:  appConfig,
+		repository: repository,
+	}
+
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"cmp"
+	"fmt"
+	"go-gis/internal/config"
+	"go-gis/internal/config/consts"
+	"go-gis/internal/geo/tile"
+	"go-gis/internal/util/utilcache"
+	"go-gis/internal/util/utilhttp"
+	xlog "go-gis/internal/util/utillog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// cache states of raster tile
+const (
+	TileCacheHit   = "HIT"
+	TileCacheMiss  = "MISS"
+	TileCacheStale = "STALE"
+)
+
+// RasterTile tile image from upstream or cache
+type RasterTile struct {
+	Data        []byte
+	ContentType string
+	Cache       string // HIT MISS STALE
+}
+
+type TileProxyService interface {
+	HasSource(name string) bool
+	RasterTile(source string, t tile.Tile) (*RasterTile, error)
+}
+
+type defaultTileProxySrv struct {
+	appConfig *config.AppConfig
+	cache     *utilcache.DiskCache
+
+	group singleflight.Group
+}
+
+func (x *defaultTileProxySrv) HasSource(name string) bool {
+
+	_, ok := x.appConfig.TileProxy.Sources[name]
+	return ok
+}
+
+func (x *defaultTileProxySrv) RasterTile(source string, t tile.Tile) (*RasterTile, error) {
+
+	cfg := &x.appConfig.TileProxy
+
+	if !x.HasSource(source) {
+		return nil, fmt.Errorf("%w: unknown tile source: %v", ErrInvalidArgument, source)
+	}
+
+	if !t.Valid() {
+		return nil, fmt.Errorf("%w: invalid tile: %v", ErrInvalidArgument, t)
+	}
+
+	key := source + "/" + t.String()
+
+	data, modTime, ok := x.cache.Get(key)
+	if ok {
+		age := time.Since(modTime)
+		ttl := time.Duration(cfg.TTL) * time.Second
+		staleTTL := time.Duration(cfg.StaleTTL) * time.Second
+
+		if age < ttl {
+			return newRasterTile(data, TileCacheHit), nil
+		}
+
+		if age < ttl+staleTTL {
+			go func() {
+				_, _ = x.fetch(source, t, key) // revalidate, errors are logged
+			}()
+			return newRasterTile(data, TileCacheStale), nil
+		}
+	}
+
+	fresh, err := x.fetch(source, t, key)
+	if err != nil {
+		if ok {
+			// upstream is unreachable, serve from cache regardless of age
+			return newRasterTile(data, TileCacheStale), nil
+		}
+		return nil, err
+	}
+
+	return newRasterTile(fresh, TileCacheMiss), nil
+}
+
+func newRasterTile(data []byte, cache string) *RasterTile {
+
+	return &RasterTile{
+		Data:        data,
+		ContentType: http.DetectContentType(data),
+		Cache:       cache,
+	}
+}
+
+// fetch download tile and put to cache, concurrent calls for same key share one request
+func (x *defaultTileProxySrv) fetch(source string, t tile.Tile, key string) ([]byte, error) {
+
+	res, err, _ := x.group.Do(key, func() (any, error) {
+
+		cfg := &x.appConfig.TileProxy
+
+		data, err := utilhttp.GetBytesWithTimeout(x.tileURL(source, t), nil,
+			requestHeaders(x.appConfig), time.Duration(cfg.Timeout)*time.Second)
+		if err != nil {
+			xlog.Warn("tile proxy upstream error: [source: %v] [tile: %v] %v", source, t, err)
+			return nil, fmt.Errorf("error on tile upstream %v: %v", source, err)
+		}
+
+		if !strings.HasPrefix(http.DetectContentType(data), "image/") {
+			return nil, fmt.Errorf("error on tile upstream %v: response is not an image", source)
+		}
+
+		if err := x.cache.Put(key, data); err != nil {
+			xlog.Error("tile proxy cache error: %v", err)
+		}
+
+		return data, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return res.([]byte), nil
+}
+
+func (x *defaultTileProxySrv) tileURL(source string, t tile.Tile) string {
+
+	cfg := &x.appConfig.TileProxy
+
+	res := cfg.Sources[source]
+
+	res = strings.ReplaceAll(res, "{z}", strconv.Itoa(t.Z))
+	res = strings.ReplaceAll(res, "{x}", strconv.Itoa(t.X))
+	res = strings.ReplaceAll(res, "{y}", strconv.Itoa(t.Y))
+
+	if len(cfg.Subdomains) > 0 {
+		res = strings.ReplaceAll(res, "{s}", cfg.Subdomains[(t.X+t.Y)%len(cfg.Subdomains)])
+	}
+
+	return res
+}
+
+func MustNewTileProxy(appConfig *config.AppConfig) TileProxyService {
+
+	cfg := &appConfig.TileProxy
+
+	dir := cmp.Or(cfg.CacheDir, filepath.Join(os.TempDir(), consts.AppName, "tiles"))
+
+	cache, err := utilcache.NewDiskCache(dir, int64(cfg.CacheMaxSize)*1024*1024)
+	if err != nil {
+		panic(err)
+	}
+
+	xlog.Info("tile proxy cache: %v", dir)
+
+	return &defaultTileProxySrv{
+		appConfig: appConfig,
+		cache:     cache,
+	}
+
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"fmt"
+	"go-gis/internal/config"
+	"go-gis/internal/geo/geojson"
+	"go-gis/internal/geo/proj"
+	"strings"
+)
+
+// TransformService coordinate transformation between EPSG codes
+type TransformService interface {
+	// TransformPosition x,y in axis order lng,lat or easting,northing
+	TransformPosition(from, to int, p geojson.Position) (geojson.Position, error)
+	// Transform positions of geometry in place
+	Transform(from, to int, g *geojson.Geometry) error
+	// CRS lookup by code
+	CRS(code int) (*proj.CRS, error)
+}
+
+type defaultTransformSrv struct {
+	appConfig *config.AppConfig
+	registry  *proj.Registry
+}
+
+func (x *defaultTransformSrv) CRS(code int) (*proj.CRS, error) {
+
+	res, err := x.registry.Lookup(code)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	}
+
+	return res, nil
+}
+
+func (x *defaultTransformSrv) TransformPosition(from, to int, p geojson.Position) (geojson.Position, error) {
+
+	g := geojson.NewPoint(p[0], p[1])
+	if err := x.Transform(from, to, g); err != nil {
+		return geojson.Position{}, err
+	}
+
+	return g.Point, nil
+}
+
+func (x *defaultTransformSrv) Transform(from, to int, g *geojson.Geometry) error {
+
+	src, err := x.CRS(from)
+	if err != nil {
+		return err
+	}
+	dst, err := x.CRS(to)
+	if err != nil {
+		return err
+	}
+
+	g.Positions(func(p *geojson.Position) {
+		if err != nil {
+			return
+		}
+		var cx, cy float64
+		if cx, cy, err = proj.Transform(src, dst, p[0], p[1]); err == nil {
+			*p = geojson.Position{cx, cy}
+		}
+	})
+
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	}
+
+	return nil
+}
+
+// newCRS system of config parameters
+func newCRS(def config.AppConfigProjection) (*proj.CRS, error) {
+
+	ellName := def.Ellipsoid
+	if ellName == "" {
+		ellName = "WGS84"
+	}
+
+	ell, err := proj.EllipsoidByName(ellName)
+	if err != nil {
+		return nil, err
+	}
+
+	toWGS84, err := proj.NewHelmert(def.ToWGS84)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &proj.CRS{Code: def.Code, Name: def.Name, Ellipsoid: ell, ToWGS84: toWGS84}
+
+	k0 := def.K0
+	if k0 == 0 {
+		k0 = 1
+	}
+
+	switch strings.ToLower(def.Type) {
+	case "longlat":
+	case "tmerc":
+		res.Projection = proj.NewTransverseMercator(ell, def.Lat0, def.Lon0, k0, def.X0, def.Y0)
+	case "lcc":
+		if res.Projection, err = proj.NewLambertConformalConic(ell, def.Lat0, def.Lon0, def.Lat1, def.Lat2, k0, def.X0, def.Y0); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("error unknown projection type: %q", def.Type)
+	}
+
+	return res, nil
+}
+
+// NewTransform registry of built-in and config systems
+func NewTransform(appConfig *config.AppConfig) (TransformService, error) {
+
+	registry := proj.NewRegistry()
+
+	for _, def := range appConfig.Projections {
+		crs, err := newCRS(def)
+		if err != nil {
+			return nil, fmt.Errorf("error on projection %v: %v", def.Code, err)
+		}
+		registry.Add(crs)
+	}
+
+	return &defaultTransformSrv{appConfig: appConfig, registry: registry}, nil
+}
+
+// MustNewTransform panic on invalid projections in config
+func MustNewTransform(appConfig *config.AppConfig) TransformService {
+
+	res, err := NewTransform(appConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	return res
+}
@@ -0,0 +1,181 @@
+// Package utilcache disk cache
+package utilcache
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DiskCache files cache with size limit, file mod time is the time of Put.
+// When total size exceeds the limit, oldest files are removed
+type DiskCache struct {
+	dir     string
+	maxSize int64 // bytes, 0 = no limit
+
+	mu   sync.Mutex
+	size int64
+}
+
+// NewDiskCache create dir if not exists and count current size
+func NewDiskCache(dir string, maxSize int64) (*DiskCache, error) {
+
+	dir = filepath.Clean(dir)
+
+	err := os.MkdirAll(dir, 0o750)
+	if err != nil {
+		return nil, fmt.Errorf("error on cache dir %v: %v", dir, err)
+	}
+
+	res := &DiskCache{dir: dir, maxSize: maxSize}
+
+	err = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if info, err := d.Info(); err == nil {
+			res.size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error on cache dir %v: %v", dir, err)
+	}
+
+	return res, nil
+}
+
+// path key is relative slash separated path like "osm/1/2/3"
+func (x *DiskCache) path(key string) (string, error) {
+
+	key = filepath.Clean(filepath.FromSlash(key))
+	if key == "." || filepath.IsAbs(key) || strings.HasPrefix(key, "..") {
+		return "", fmt.Errorf("error invalid cache key: %v", key)
+	}
+
+	return filepath.Join(x.dir, key), nil
+}
+
+// Get data and time of Put, ok=false if not exists
+func (x *DiskCache) Get(key string) (data []byte, modTime time.Time, ok bool) {
+
+	p, err := x.path(key)
+	if err != nil {
+		return nil, modTime, false
+	}
+
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, modTime, false
+	}
+
+	data, err = os.ReadFile(p) //nolint:gosec // path is under cache dir
+	if err != nil {
+		return nil, modTime, false
+	}
+
+	return data, info.ModTime(), true
+}
+
+// Put write data atomically and evict old files if size limit exceeded
+func (x *DiskCache) Put(key string, data []byte) error {
+
+	p, err := x.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(p), 0o750)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	var prevSize int64
+	if info, err := os.Stat(p); err == nil {
+		prevSize = info.Size()
+	}
+
+	err = os.Rename(tmp.Name(), p)
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.size += int64(len(data)) - prevSize
+
+	if x.maxSize > 0 && x.size > x.maxSize {
+		x.evict()
+	}
+
+	return nil
+}
+
+// Size total size of cached files
+func (x *DiskCache) Size() int64 {
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	return x.size
+}
+
+type cacheFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// evict remove oldest files down to 90% of max size, under lock
+func (x *DiskCache) evict() {
+
+	files := []cacheFile{}
+	var total int64
+
+	_ = filepath.WalkDir(x.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil // skip unreadable
+		}
+		if info, err := d.Info(); err == nil {
+			files = append(files, cacheFile{path: p, size: info.Size(), modTime: info.ModTime()})
+			total += info.Size()
+		}
+		return nil
+	})
+
+	slices.SortFunc(files, func(a, b cacheFile) int { return a.modTime.Compare(b.modTime) })
+
+	target := x.maxSize * 9 / 10
+
+	for _, f := range files {
+		if total <= target {
+			break
+		}
+		if os.Remove(f.path) == nil {
+			total -= f.size
+		}
+	}
+
+	x.size = total
+}
@@ -0,0 +1,47 @@
+package utilcache
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Test put, get and eviction of oldest files
+func TestDiskCache(t *testing.T) {
+
+	dir := t.TempDir()
+
+	cache, err := NewDiskCache(dir, 250)
+	if err != nil {
+		t.Fatalf("NewDiskCache error: %v", err)
+	}
+
+	data := bytes.Repeat([]byte{1}, 100)
+
+	for i, key := range []string{"osm/1/0/0", "osm/1/0/1", "osm/1/1/0"} {
+		if err := cache.Put(key, data); err != nil {
+			t.Fatalf("Put error: %v", err)
+		}
+		// distinct mod times
+		mt := time.Now().Add(time.Duration(i-10) * time.Minute)
+		_ = os.Chtimes(filepath.Join(dir, filepath.FromSlash(key)), mt, mt)
+	}
+
+	if _, _, ok := cache.Get("osm/1/1/0"); !ok {
+		t.Error("Expected newest entry to exist")
+	}
+
+	if cache.Size() > 250 {
+		t.Errorf("Expected size <= 250, got %v", cache.Size())
+	}
+
+	if _, _, ok := cache.Get("osm/1/0/0"); ok {
+		t.Error("Expected oldest entry to be evicted")
+	}
+
+	if err := cache.Put("../escape", data); err == nil {
+		t.Error("Expected error for key outside of cache dir")
+	}
+}
@@ -60,7 +60,7 @@ func fromFile(cfgPtr any, dir string, file string) error {
 	data, err := os.ReadFile(fullPath)
 
 	if err != nil {
-		return fmt.Errorf("error with file %v: %v", fullPath, err)
+		return fmt.Errorf("error with file %v: %w", fullPath, err)
 	}
 
 	xlog.Info("loading config from file: %v", fullPath)
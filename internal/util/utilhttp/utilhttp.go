@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 )
 
 // URLEncode encodes a string for safe inclusion in a URL query.
@@ -165,6 +166,14 @@ func PostFormURL(baseURL string, queryParams map[string]string,
 func GetBytes(baseURL string, queryParams map[string]string,
 	headers map[string]string,
 ) ([]byte, error) {
+
+	return GetBytesWithTimeout(baseURL, queryParams, headers, 0)
+}
+
+// GetBytesWithTimeout GetBytes with client timeout, 0 = no timeout
+func GetBytesWithTimeout(baseURL string, queryParams map[string]string,
+	headers map[string]string, timeout time.Duration,
+) ([]byte, error) {
 	// The URL to send the POST request to
 	url, err := JoinURL(baseURL, queryParams)
 
@@ -184,7 +193,7 @@ func GetBytes(baseURL string, queryParams map[string]string,
 		}
 	}
 
-	client := &http.Client{}
+	client := &http.Client{Timeout: timeout}
 	resp, err := client.Do(req)
 
 	if err != nil {
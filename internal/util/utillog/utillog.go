@@ -3,6 +3,7 @@ package utillog
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"log/slog"
 	"os"
@@ -27,6 +28,11 @@ var DefaultLogger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
 
 // var DefaultLogger = log.New(os.Stdout, "", log.LUTC)
 
+// SetOutput replace DefaultLogger with JSON logger to w
+func SetOutput(w io.Writer) {
+	DefaultLogger = slog.New(slog.NewJSONHandler(w, nil))
+}
+
 func Info(format string, v ...any) {
 	msg := fmt.Sprintf(format, v...)
 	DefaultLogger.Info(msg)
@@ -0,0 +1,57 @@
+package e2e
+
+import (
+	xcmd "go-gis/internal/cmd"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestDegradedStart server without database: ready probe and database routes are 503
+func TestDegradedStart(t *testing.T) {
+
+	t.Setenv("APP_ENV", "testing")
+	t.Setenv("APP_DB_DEGRADED", "1")
+	t.Setenv("APP_DB_HOST", "127.0.0.1")
+	t.Setenv("APP_DB_PORT", "1") // nothing listens
+	t.Setenv("APP_DB_DSN", "")
+
+	cmd := xcmd.Command{}
+
+	go cmd.Exec()
+
+	time.Sleep(3 * time.Second)
+
+	urls := []struct {
+		title  string
+		url    string
+		status int
+	}{
+		{title: "test live", url: "http://127.0.0.1:31180/-/probe/live", status: http.StatusOK},
+		{title: "test ready", url: "http://127.0.0.1:31180/-/probe/ready", status: http.StatusServiceUnavailable},
+		{title: "test features", url: "http://127.0.0.1:31180/gis/api/features?layer=zones&bbox=-1,50,1,52", status: http.StatusServiceUnavailable},
+	}
+
+	for _, itm := range urls {
+
+		t.Run(itm.title, func(t *testing.T) {
+
+			resp, err := http.Get(itm.url)
+			if err != nil {
+				t.Fatalf("Error : %v", err)
+			}
+			_ = resp.Body.Close()
+
+			if resp.StatusCode != itm.status {
+				t.Errorf("Error on %v: status %v, want %v", itm.url, resp.StatusCode, itm.status)
+			}
+
+		})
+
+	}
+
+	cmd.Stop()
+
+	time.Sleep(1 * time.Second)
+
+}